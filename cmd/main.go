@@ -2,14 +2,19 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"faceit-backend-test/internal/auth"
 	"faceit-backend-test/internal/config"
 	"faceit-backend-test/internal/health"
+	"faceit-backend-test/internal/idempotency"
+	"faceit-backend-test/internal/mail"
 	"faceit-backend-test/internal/notify"
 	"faceit-backend-test/internal/pubsub"
 	"faceit-backend-test/internal/router"
 	"faceit-backend-test/internal/sub"
 	"faceit-backend-test/internal/user"
 	"fmt"
+	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
 	"github.com/kelseyhightower/envconfig"
 	"github.com/sirupsen/logrus"
@@ -33,6 +38,10 @@ var (
 // @title Faceit Backend Test
 // @version 0.1
 
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+
 func main() {
 	s := &Service{}
 	defer s.Shutdown()
@@ -41,10 +50,12 @@ func main() {
 	initLogger()
 
 	db := initDb()
-	routes := initRoutes(db)
+	tokens := initTokenManager()
+	routes, revocation := initRoutes(db, tokens)
 
 	go func() {
-		if err := initHTTPHandler(s, routes...); err != nil {
+		authMiddleware := auth.NewMiddleware(tokens, auth.WithAdmins(cfg.Auth.Admins...), auth.WithRevocation(revocation))
+		if err := initHTTPHandler(s, authMiddleware, routes...); err != nil {
 			logger.WithFields(logrus.Fields{
 				"transport": "http",
 				"error":     err.Error(),
@@ -53,7 +64,7 @@ func main() {
 		}
 	}()
 
-	signalHandler := make(chan os.Signal)
+	signalHandler := make(chan os.Signal, 1)
 	signal.Notify(signalHandler, syscall.SIGTERM, syscall.SIGINT, syscall.SIGUSR1, syscall.SIGUSR2)
 
 	receivedSignal := <-signalHandler
@@ -107,6 +118,14 @@ func initConfig() {
 	if err != nil {
 		stdLog.Fatalf(err.Error())
 	}
+
+	// the purgers cannot tick on a non positive interval, the one of the users is only started when they are retained.
+	if cfg.User.DeletedRetention > 0 && cfg.User.PurgeInterval <= 0 {
+		stdLog.Fatalf("USER_PURGE_INTERVAL must be positive when USER_DELETED_RETENTION is set")
+	}
+	if cfg.Idempotency.PurgeInterval <= 0 {
+		stdLog.Fatalf("IDEMPOTENCY_PURGE_INTERVAL must be positive")
+	}
 }
 
 func initLogger() {
@@ -127,8 +146,8 @@ func initLogger() {
 	}).Info("logger initialized")
 }
 
-func initHTTPHandler(s *Service, routes ...router.Controller) error {
-	httpRouter := router.NewHTTPRouter(routes...)
+func initHTTPHandler(s *Service, authMiddleware gin.HandlerFunc, routes ...router.Controller) error {
+	httpRouter := router.NewHTTPRouter(authMiddleware, routes...)
 
 	s.server = &http.Server{
 		Addr:    cfg.Server.HttpAddress,
@@ -142,33 +161,117 @@ func initHTTPHandler(s *Service, routes ...router.Controller) error {
 	return s.server.ListenAndServe()
 }
 
-func initRoutes(db *sqlx.DB) []router.Controller {
+func initTokenManager() auth.TokenManager {
+	signingKey, err := auth.NewSigningKey(cfg.Auth.SigningAlgorithm, cfg.Auth.Secret, cfg.Auth.PrivateKeyFile)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"algorithm": cfg.Auth.SigningAlgorithm,
+			"error":     err.Error(),
+		}).Fatal("cannot create the access token signing key")
+	}
+
+	return auth.NewTokenManager(
+		auth.WithSigningKey(signingKey),
+		auth.WithIssuer(cfg.Service.Name),
+		auth.WithTokenTTL(time.Duration(cfg.Auth.TokenTtl)*time.Second),
+	)
+}
+
+// initMailSender returns the sender of the mails, SMTP is used in the background so that the requests do not wait for the server.
+func initMailSender() mail.Sender {
+	switch cfg.Mail.Sender {
+	case mail.SenderSMTP:
+		sender := mail.NewAsyncSender(mail.NewSMTPSender(
+			mail.WithAddress(cfg.Mail.SmtpHost, cfg.Mail.SmtpPort),
+			mail.WithCredentials(cfg.Mail.SmtpUsername, cfg.Mail.SmtpPassword),
+			mail.WithFrom(cfg.Mail.From),
+		), cfg.Mail.QueueSize, logger)
+		sender.Start(context.Background())
+
+		return sender
+	case mail.SenderFile:
+		return mail.NewFileSender(cfg.Mail.File, cfg.Mail.From)
+	case mail.SenderLog:
+		return mail.NewLogSender(logger)
+	}
+
+	logger.WithField("sender", cfg.Mail.Sender).Fatal("unknown mail sender")
+	return nil
+}
+
+// initVerificationSecret returns the secret the email verification tokens are signed with. A random one is generated
+// when it is not configured, the tokens are not valid after a restart then.
+func initVerificationSecret() []byte {
+	if cfg.User.VerificationSecret != "" {
+		return []byte(cfg.User.VerificationSecret)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		logger.WithField("error", err.Error()).Fatal("cannot generate the email verification secret")
+	}
+	logger.Warn("USER_VERIFICATION_SECRET is not set, the email verification tokens are signed with a random secret")
+
+	return secret
+}
+
+func initRoutes(db *sqlx.DB, tokens auth.TokenManager) ([]router.Controller, auth.Revocation) {
 	broker := pubsub.NewBroker()
 
 	healthCheck := health.NewController(
 		health.WithDb(db),
 	)
 
+	notificationManager := notify.NewNotificationManager(
+		[]string{user.UserCreatedTopic, user.UserChangeTopic, user.UserDeletedTopic, user.UserEmailVerifiedTopic, user.UserErasedTopic},
+		notify.WithBroker(broker),
+		notify.WithLogger(logger),
+		notify.WithDeliveryLogSize(cfg.Notification.DeliveryLogSize),
+	)
+	notificationManager.Start()
+
 	userRepository := user.NewRepository(user.WithDb(db))
-	userService := user.NewServiceLoggingMiddleware(logger)(user.NewService(
+	idempotencyKeys := idempotency.NewRepository(idempotency.WithDb(db))
+	idempotency.NewPurger(idempotencyKeys, time.Duration(cfg.Idempotency.PurgeInterval)*time.Second, logger).Start(context.Background())
+
+	userBaseService := user.NewService(
 		user.WithRepository(userRepository),
 		user.WithBroker(broker),
-	))
+		user.WithPasswordHasher(user.NewBcryptHasher(cfg.Password.BcryptCost)),
+		user.WithImportBatchSize(cfg.User.ImportBatchSize),
+		user.WithMailSender(initMailSender()),
+		user.WithVerification(initVerificationSecret(), time.Duration(cfg.User.VerificationTtl)*time.Second, cfg.User.VerificationUrl),
+		user.WithRequireVerifiedEmail(cfg.User.RequireVerifiedEmail),
+		user.WithPasswordReset(time.Duration(cfg.User.PasswordResetTtl)*time.Second, cfg.User.PasswordResetUrl),
+		user.WithDeliveryLog(notificationManager),
+	)
+	userService := user.NewServiceLoggingMiddleware(logger)(userBaseService)
 	users := user.NewController(
 		user.WithService(userService),
+		user.WithIdentityMiddleware(auth.NewIdentityMiddleware(tokens, auth.WithAdmins(cfg.Auth.Admins...), auth.WithRevocation(userBaseService))),
+		user.WithIdempotencyMiddleware(idempotency.NewMiddleware(idempotencyKeys, time.Duration(cfg.Idempotency.Ttl)*time.Second, logger)),
+		user.WithMaxImportSize(cfg.User.ImportMaxSize),
 	)
 
-	notificationManager := notify.NewNotificationManager(
-		[]string{user.UserChangeTopic},
-		notify.WithBroker(broker),
-		notify.WithLogger(logger),
-	)
-	notificationManager.Start()
+	if cfg.User.DeletedRetention > 0 {
+		user.NewPurger(
+			user.WithPurgerRepository(userRepository),
+			user.WithPurgerLogger(logger),
+			user.WithRetention(time.Duration(cfg.User.DeletedRetention)*time.Second),
+			user.WithPurgeInterval(time.Duration(cfg.User.PurgeInterval)*time.Second),
+		).Start(context.Background())
+	}
+
+	authService := auth.NewServiceLoggingMiddleware(logger)(auth.NewService(
+		auth.WithAuthenticator(userBaseService),
+		auth.WithTokenManager(tokens),
+	))
+	authentication := auth.NewController(auth.WithService(authService))
 
 	subscribeService := sub.NewServiceLoggingMiddleware(logger)(sub.NewService(sub.WithNotificationManager(notificationManager)))
 	subscribe := sub.NewController(sub.WithService(subscribeService))
 
-	return []router.Controller{healthCheck, users, subscribe}
+	return []router.Controller{healthCheck, authentication, users, subscribe}, userBaseService
 }
 
 func initDb() *sqlx.DB {
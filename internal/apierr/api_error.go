@@ -7,12 +7,18 @@ type ApiError struct {
 	Code       string      `json:"code"`
 	Message    string      `json:"message"`
 	Data       interface{} `json:"data"`
+	// Cause is the internal error behind the api error, it is logged but not returned to the clients.
+	Cause error `json:"-"`
 }
 
 func (err ApiError) Error() string {
 	return err.Message
 }
 
+func (err ApiError) Unwrap() error {
+	return err.Cause
+}
+
 func InternalServerError() ApiError {
 	return ApiError{
 		StatusCode: http.StatusInternalServerError,
@@ -39,3 +45,30 @@ func BadRequest(message string) ApiError {
 		Data:       nil,
 	}
 }
+
+func Unauthorized(message string) ApiError {
+	return ApiError{
+		StatusCode: http.StatusUnauthorized,
+		Code:       "0003",
+		Message:    message,
+		Data:       nil,
+	}
+}
+
+func NotFound(message string) ApiError {
+	return ApiError{
+		StatusCode: http.StatusNotFound,
+		Code:       "0004",
+		Message:    message,
+		Data:       nil,
+	}
+}
+
+func Forbidden(message string) ApiError {
+	return ApiError{
+		StatusCode: http.StatusForbidden,
+		Code:       "0005",
+		Message:    message,
+		Data:       nil,
+	}
+}
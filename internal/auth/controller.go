@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"faceit-backend-test/internal/apierr"
+	"faceit-backend-test/internal/router"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+const route = "/auth"
+
+type Service interface {
+	Login(ctx context.Context, request LoginRequest) (LoginResponse, error)
+}
+
+// controller authentication of the users is handled in this controller
+// @tag.name AuthController
+type controller struct {
+	service Service
+}
+
+var _ router.Controller = (*controller)(nil)
+
+type ControllerOpts func(*controller)
+
+func NewController(opts ...ControllerOpts) *controller {
+	c := &controller{}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func WithService(service Service) ControllerOpts {
+	return func(c *controller) {
+		c.service = service
+	}
+}
+
+// Register registers the endpoints to the given router group
+func (c *controller) Register(r *gin.RouterGroup) {
+	r.POST(route+"/login", c.Login)
+}
+
+// Login godoc
+// @Summary authenticates a user by nickname or email and password, returns a signed access token.
+// @tags AuthController
+// @Accept json
+// @Produce json
+// @Param LoginRequest body LoginRequest true "user credentials"
+// @Success 200 {object} LoginResponse
+// @Failure 400 {object} apierr.ApiError
+// @Failure 401 {object} apierr.ApiError
+// @Failure 500 {object} apierr.ApiError
+// @Router /v1/auth/login [post]
+func (c *controller) Login(ctx *gin.Context) {
+	var req LoginRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		c.decodeError(ctx, apierr.BadRequest(err.Error()))
+		return
+	}
+
+	resp, err := c.service.Login(ctx.Request.Context(), req)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (c *controller) decodeError(ctx *gin.Context, err error) {
+	apiErr, ok := err.(apierr.ApiError)
+	if !ok {
+		apiErr = apierr.InternalServerError()
+	}
+
+	ctx.JSON(apiErr.StatusCode, apiErr)
+}
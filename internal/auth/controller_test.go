@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"faceit-backend-test/internal/apierr"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type mockService struct {
+	loginMock func(ctx context.Context, request LoginRequest) (LoginResponse, error)
+}
+
+func (m *mockService) Login(ctx context.Context, request LoginRequest) (LoginResponse, error) {
+	return m.loginMock(ctx, request)
+}
+
+func TestController_Register(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller := NewController()
+
+	t.Run("", func(t *testing.T) {
+		controller.Register(&router.RouterGroup)
+
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/auth/login", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.NotEqual(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestController_Login(t *testing.T) {
+	mockService := &mockService{}
+	gin.SetMode(gin.TestMode)
+	controller := NewController(WithService(mockService))
+	router := gin.Default()
+	router.POST("/auth/login", controller.Login)
+
+	t.Run("success", func(t *testing.T) {
+		req := LoginRequest{
+			Login:    "bobby.firmino",
+			Password: "liverpool321",
+		}
+		expected := LoginResponse{
+			AccessToken: "token",
+			TokenType:   tokenTypeBearer,
+			ExpiresIn:   900,
+		}
+
+		mockService.loginMock = func(ctx context.Context, request LoginRequest) (LoginResponse, error) {
+			assert.EqualValues(t, req, request)
+			return expected, nil
+		}
+
+		reqBody, err := json.Marshal(req)
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/auth/login", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(expected)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+	})
+
+	t.Run("should return bad request when a required field is missing", func(t *testing.T) {
+		req := LoginRequest{
+			Login: "bobby.firmino",
+		}
+		expected := apierr.BadRequest("invalid request body")
+
+		reqBody, err := json.Marshal(req)
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/auth/login", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		var actualResp apierr.ApiError
+		err = json.Unmarshal(rr.Body.Bytes(), &actualResp)
+
+		assert.Equal(t, expected.StatusCode, rr.Code)
+		assert.Equal(t, expected.Code, actualResp.Code)
+	})
+
+	t.Run("should return unauthorized when credentials are invalid", func(t *testing.T) {
+		req := LoginRequest{
+			Login:    "bobby.firmino",
+			Password: "wrong",
+		}
+		expected := apierr.Unauthorized("invalid login or password")
+
+		mockService.loginMock = func(ctx context.Context, request LoginRequest) (LoginResponse, error) {
+			return LoginResponse{}, expected
+		}
+
+		reqBody, err := json.Marshal(req)
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/auth/login", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		var actualResp apierr.ApiError
+		err = json.Unmarshal(rr.Body.Bytes(), &actualResp)
+
+		assert.Equal(t, expected.StatusCode, rr.Code)
+		assert.Equal(t, expected.Code, actualResp.Code)
+		assert.Equal(t, expected.Message, actualResp.Message)
+	})
+}
@@ -0,0 +1,18 @@
+package auth
+
+import (
+	"faceit-backend-test/internal/apierr"
+	"net/http"
+)
+
+// TokenError error response model for the failures while issuing access tokens,
+// the error is kept as the cause so that it is logged but not returned to the clients.
+func TokenError(err error) apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusInternalServerError,
+		Code:       "3000",
+		Message:    "the access token cannot be issued at the moment",
+		Data:       nil,
+		Cause:      err,
+	}
+}
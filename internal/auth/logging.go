@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+type ServiceLoggingMiddleware func(Service) Service
+
+type serviceLoggingMiddleware struct {
+	logger *logrus.Logger
+	next   Service
+}
+
+var _ Service = (*serviceLoggingMiddleware)(nil)
+
+func NewServiceLoggingMiddleware(logger *logrus.Logger) ServiceLoggingMiddleware {
+	return func(s Service) Service {
+		return &serviceLoggingMiddleware{
+			logger: logger,
+			next:   s,
+		}
+	}
+}
+
+func (s *serviceLoggingMiddleware) Login(ctx context.Context, request LoginRequest) (LoginResponse, error) {
+	s.logger.WithFields(logrus.Fields{
+		"service":  "AuthService",
+		"endpoint": "Login",
+		"request":  request.redacted(),
+	}).Debug("received request")
+	var resp LoginResponse
+	var err error
+	defer func(start time.Time) {
+		logger := s.logger.WithFields(logrus.Fields{
+			"service":  "AuthService",
+			"endpoint": "Login",
+			"took":     time.Since(start).String(),
+		})
+		if err != nil {
+			fields := logrus.Fields{"error": err}
+			if cause := errors.Unwrap(err); cause != nil {
+				fields["cause"] = cause
+			}
+			logger.WithFields(fields).Errorln("an error occurred")
+			return
+		}
+
+		// the response is not logged since it contains the access token.
+		logger.Debug("access token issued")
+	}(time.Now())
+	resp, err = s.next.Login(ctx, request)
+	return resp, err
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"faceit-backend-test/internal/apierr"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestServiceLoggingMiddleware_Login(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		req := LoginRequest{
+			Login:    "bobby.firmino",
+			Password: "liverpool321",
+		}
+		expected := LoginResponse{
+			AccessToken: "token",
+			TokenType:   tokenTypeBearer,
+			ExpiresIn:   900,
+		}
+
+		mockService := &mockService{}
+		mockService.loginMock = func(ctx context.Context, request LoginRequest) (LoginResponse, error) {
+			assert.EqualValues(t, req, request)
+			return expected, nil
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(mockService)
+
+		resp, err := loggingMiddleware.Login(context.Background(), req)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, resp)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		req := LoginRequest{
+			Login:    "bobby.firmino",
+			Password: "liverpool321",
+		}
+		expectedErr := apierr.Unauthorized("invalid login or password")
+
+		mockService := &mockService{}
+		mockService.loginMock = func(ctx context.Context, request LoginRequest) (LoginResponse, error) {
+			return LoginResponse{}, expectedErr
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(mockService)
+
+		_, err := loggingMiddleware.Login(context.Background(), req)
+		assert.Error(t, err)
+		assert.EqualValues(t, expectedErr, err)
+	})
+}
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"context"
+	"faceit-backend-test/internal/apierr"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"time"
+)
+
+const (
+	authorizationHeader = "Authorization"
+	bearerScheme        = "Bearer"
+	subjectContextKey   = "auth.subject"
+	adminContextKey     = "auth.admin"
+)
+
+// subjectKey is the key of the subject in the request contexts, which are passed on to the services.
+type subjectKey struct{}
+
+// Revocation tells whether the tokens of a subject issued at a time are revoked,
+// e.g. since the subject is deleted or its password is reset afterwards.
+type Revocation interface {
+	Revoked(ctx context.Context, subject string, issuedAt time.Time) (bool, error)
+}
+
+// middleware is the configuration of the authentication middlewares.
+type middleware struct {
+	admins     map[string]bool
+	revocation Revocation
+}
+
+type MiddlewareOpts func(*middleware)
+
+// WithAdmins sets the subjects that are authorized as admins, they are allowed to manage the other users.
+func WithAdmins(subjects ...string) MiddlewareOpts {
+	return func(m *middleware) {
+		for _, subject := range subjects {
+			m.admins[subject] = true
+		}
+	}
+}
+
+// WithRevocation sets the check of the revoked tokens, the tokens are valid until they expire without it.
+func WithRevocation(revocation Revocation) MiddlewareOpts {
+	return func(m *middleware) {
+		m.revocation = revocation
+	}
+}
+
+// NewMiddleware creates a gin middleware that rejects the requests
+// without a valid bearer access token and stores the token subject in the context.
+func NewMiddleware(tokens TokenManager, opts ...MiddlewareOpts) gin.HandlerFunc {
+	m := &middleware{admins: map[string]bool{}}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return func(ctx *gin.Context) {
+		scheme, token, found := strings.Cut(ctx.GetHeader(authorizationHeader), " ")
+		if !found || !strings.EqualFold(scheme, bearerScheme) || token == "" {
+			abortUnauthorized(ctx, "missing bearer token")
+			return
+		}
+
+		claims, err := tokens.Verify(token)
+		if err != nil {
+			abortUnauthorized(ctx, "invalid bearer token")
+			return
+		}
+
+		if m.revocation != nil {
+			// the tokens without an issue time are treated as issued at the zero time, so any password reset revokes them.
+			var issuedAt time.Time
+			if claims.IssuedAt != nil {
+				issuedAt = claims.IssuedAt.Time
+			}
+
+			revoked, err := m.revocation.Revoked(ctx.Request.Context(), claims.Subject, issuedAt)
+			if err != nil {
+				apiErr := apierr.InternalServerError()
+				ctx.AbortWithStatusJSON(apiErr.StatusCode, apiErr)
+				return
+			}
+			if revoked {
+				abortUnauthorized(ctx, "revoked bearer token")
+				return
+			}
+		}
+
+		ctx.Set(subjectContextKey, claims.Subject)
+		ctx.Set(adminContextKey, m.admins[claims.Subject])
+		ctx.Request = ctx.Request.WithContext(NewContext(ctx.Request.Context(), claims.Subject))
+		ctx.Next()
+	}
+}
+
+// NewIdentityMiddleware creates a gin middleware that stores the subject of the bearer access token in the context
+// when the request has one, the requests without a token are not rejected so that it can be used on the public routes.
+func NewIdentityMiddleware(tokens TokenManager, opts ...MiddlewareOpts) gin.HandlerFunc {
+	authenticate := NewMiddleware(tokens, opts...)
+
+	return func(ctx *gin.Context) {
+		if ctx.GetHeader(authorizationHeader) == "" {
+			ctx.Next()
+			return
+		}
+
+		authenticate(ctx)
+	}
+}
+
+// Subject returns the subject of the access token the request is authenticated with.
+func Subject(ctx *gin.Context) (string, bool) {
+	subject := ctx.GetString(subjectContextKey)
+	return subject, subject != ""
+}
+
+// IsAdmin reports whether the subject of the access token the request is authenticated with is an admin.
+func IsAdmin(ctx *gin.Context) bool {
+	return ctx.GetBool(adminContextKey)
+}
+
+// NewContext returns a copy of the context carrying the subject of the access token.
+func NewContext(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, subjectKey{}, subject)
+}
+
+// SubjectFromContext returns the subject of the access token the request of the context is authenticated with.
+func SubjectFromContext(ctx context.Context) (string, bool) {
+	subject, _ := ctx.Value(subjectKey{}).(string)
+	return subject, subject != ""
+}
+
+func abortUnauthorized(ctx *gin.Context, message string) {
+	apiErr := apierr.Unauthorized(message)
+
+	ctx.Header("WWW-Authenticate", bearerScheme)
+	ctx.AbortWithStatusJSON(apiErr.StatusCode, apiErr)
+}
@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNewMiddleware(t *testing.T) {
+	key, err := NewSigningKey(AlgorithmHS256, "secret", "")
+	assert.NoError(t, err)
+	tokens := NewTokenManager(WithSigningKey(key), WithTokenTTL(time.Minute))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/protected", NewMiddleware(tokens), func(ctx *gin.Context) {
+		subject, ok := Subject(ctx)
+		assert.True(t, ok)
+
+		contextSubject, ok := SubjectFromContext(ctx.Request.Context())
+		assert.True(t, ok)
+		assert.Equal(t, subject, contextSubject, "should pass the subject on to the services")
+
+		ctx.String(http.StatusOK, subject)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		subject := uuid.New().String()
+		token, err := tokens.Issue(subject)
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodGet, "/protected", nil)
+		assert.NoError(t, err)
+		request.Header.Set("Authorization", "Bearer "+token.Value)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, subject, rr.Body.String())
+	})
+
+	t.Run("should return unauthorized when the header is missing", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodGet, "/protected", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, bearerScheme, rr.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("should return unauthorized when the token is invalid", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodGet, "/protected", nil)
+		assert.NoError(t, err)
+		request.Header.Set("Authorization", "Bearer invalid")
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("should authorize the admins", func(t *testing.T) {
+		admin := uuid.New().String()
+
+		router := gin.Default()
+		router.GET("/protected", NewMiddleware(tokens, WithAdmins(admin)), func(ctx *gin.Context) {
+			ctx.String(http.StatusOK, strconv.FormatBool(IsAdmin(ctx)))
+		})
+
+		for subject, expected := range map[string]string{admin: "true", uuid.New().String(): "false"} {
+			token, err := tokens.Issue(subject)
+			assert.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodGet, "/protected", nil)
+			assert.NoError(t, err)
+			request.Header.Set("Authorization", "Bearer "+token.Value)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, expected, rr.Body.String())
+		}
+	})
+
+	t.Run("should return unauthorized when the token is revoked", func(t *testing.T) {
+		revoked := uuid.New().String()
+
+		router := gin.Default()
+		router.GET("/protected", NewMiddleware(tokens, WithRevocation(mockRevocation(func(subject string, issuedAt time.Time) (bool, error) {
+			assert.WithinDuration(t, time.Now(), issuedAt, time.Minute, "should check the tokens by their issue time")
+			return subject == revoked, nil
+		}))), func(ctx *gin.Context) {
+			ctx.Status(http.StatusOK)
+		})
+
+		for subject, expected := range map[string]int{revoked: http.StatusUnauthorized, uuid.New().String(): http.StatusOK} {
+			token, err := tokens.Issue(subject)
+			assert.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodGet, "/protected", nil)
+			assert.NoError(t, err)
+			request.Header.Set("Authorization", "Bearer "+token.Value)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, expected, rr.Code)
+		}
+	})
+}
+
+type mockRevocation func(subject string, issuedAt time.Time) (bool, error)
+
+func (m mockRevocation) Revoked(ctx context.Context, subject string, issuedAt time.Time) (bool, error) {
+	return m(subject, issuedAt)
+}
+
+func TestNewIdentityMiddleware(t *testing.T) {
+	key, err := NewSigningKey(AlgorithmHS256, "secret", "")
+	assert.NoError(t, err)
+	tokens := NewTokenManager(WithSigningKey(key), WithTokenTTL(time.Minute))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/public", NewIdentityMiddleware(tokens), func(ctx *gin.Context) {
+		subject, _ := Subject(ctx)
+		ctx.String(http.StatusOK, subject)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		subject := uuid.New().String()
+		token, err := tokens.Issue(subject)
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodGet, "/public", nil)
+		assert.NoError(t, err)
+		request.Header.Set("Authorization", "Bearer "+token.Value)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, subject, rr.Body.String())
+	})
+
+	t.Run("should not reject the requests without a token", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodGet, "/public", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Body.String())
+	})
+
+	t.Run("should return unauthorized when the token is invalid", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodGet, "/public", nil)
+		assert.NoError(t, err)
+		request.Header.Set("Authorization", "Bearer invalid")
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
@@ -0,0 +1,14 @@
+package auth
+
+// LoginRequest login endpoint request model contains the user credentials
+// @Description login endpoint request model, login can be either the nickname or the email of the user
+type LoginRequest struct {
+	Login    string `json:"login" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// redacted returns a copy of the request without the password so that it can be logged.
+func (r LoginRequest) redacted() LoginRequest {
+	r.Password = ""
+	return r
+}
@@ -0,0 +1,9 @@
+package auth
+
+// LoginResponse login endpoint response model containing the issued access token
+// @Description login endpoint response model containing the issued access token
+type LoginResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
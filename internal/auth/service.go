@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+const tokenTypeBearer = "Bearer"
+
+// Authenticator verifies the credentials of a user and returns the id of the user.
+type Authenticator interface {
+	Authenticate(ctx context.Context, login string, password string) (string, error)
+}
+
+type service struct {
+	authenticator Authenticator
+	tokens        TokenManager
+}
+
+var _ Service = (*service)(nil)
+
+type ServiceOpts func(*service)
+
+func NewService(opts ...ServiceOpts) *service {
+	s := &service{}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func WithAuthenticator(authenticator Authenticator) ServiceOpts {
+	return func(s *service) {
+		s.authenticator = authenticator
+	}
+}
+
+func WithTokenManager(tokens TokenManager) ServiceOpts {
+	return func(s *service) {
+		s.tokens = tokens
+	}
+}
+
+func (s *service) Login(ctx context.Context, request LoginRequest) (LoginResponse, error) {
+	userId, err := s.authenticator.Authenticate(ctx, request.Login, request.Password)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	token, err := s.tokens.Issue(userId)
+	if err != nil {
+		return LoginResponse{}, TokenError(err)
+	}
+
+	return LoginResponse{
+		AccessToken: token.Value,
+		TokenType:   tokenTypeBearer,
+		ExpiresIn:   int64(time.Until(token.ExpiresAt).Seconds()),
+	}, nil
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"faceit-backend-test/internal/apierr"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type mockAuthenticator struct {
+	authenticateMock func(ctx context.Context, login string, password string) (string, error)
+}
+
+func (m *mockAuthenticator) Authenticate(ctx context.Context, login string, password string) (string, error) {
+	return m.authenticateMock(ctx, login, password)
+}
+
+type mockTokenManager struct {
+	issueMock  func(subject string) (Token, error)
+	verifyMock func(token string) (jwt.RegisteredClaims, error)
+}
+
+func (m *mockTokenManager) Issue(subject string) (Token, error) {
+	return m.issueMock(subject)
+}
+
+func (m *mockTokenManager) Verify(token string) (jwt.RegisteredClaims, error) {
+	return m.verifyMock(token)
+}
+
+func TestService_Login(t *testing.T) {
+	req := LoginRequest{
+		Login:    "bobby.firmino",
+		Password: "liverpool321",
+	}
+	userId := uuid.New().String()
+
+	t.Run("success", func(t *testing.T) {
+		authenticator := &mockAuthenticator{}
+		authenticator.authenticateMock = func(ctx context.Context, login string, password string) (string, error) {
+			assert.Equal(t, req.Login, login)
+			assert.Equal(t, req.Password, password)
+
+			return userId, nil
+		}
+
+		tokens := &mockTokenManager{}
+		tokens.issueMock = func(subject string) (Token, error) {
+			assert.Equal(t, userId, subject)
+
+			return Token{Value: "token", ExpiresAt: time.Now().Add(time.Hour)}, nil
+		}
+
+		service := NewService(WithAuthenticator(authenticator), WithTokenManager(tokens))
+		actual, err := service.Login(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, "token", actual.AccessToken)
+		assert.Equal(t, tokenTypeBearer, actual.TokenType)
+		assert.InDelta(t, time.Hour.Seconds(), actual.ExpiresIn, 1)
+	})
+
+	t.Run("authentication error", func(t *testing.T) {
+		expectedErr := apierr.Unauthorized("invalid login or password")
+
+		authenticator := &mockAuthenticator{}
+		authenticator.authenticateMock = func(ctx context.Context, login string, password string) (string, error) {
+			return "", expectedErr
+		}
+
+		service := NewService(WithAuthenticator(authenticator))
+		_, err := service.Login(context.Background(), req)
+		assert.Error(t, err)
+		assert.EqualValues(t, expectedErr, err)
+	})
+
+	t.Run("token error", func(t *testing.T) {
+		authenticator := &mockAuthenticator{}
+		authenticator.authenticateMock = func(ctx context.Context, login string, password string) (string, error) {
+			return userId, nil
+		}
+
+		tokens := &mockTokenManager{}
+		tokens.issueMock = func(subject string) (Token, error) {
+			return Token{}, fmt.Errorf("mock error")
+		}
+
+		service := NewService(WithAuthenticator(authenticator), WithTokenManager(tokens))
+		_, err := service.Login(context.Background(), req)
+		assert.Error(t, err)
+		assert.IsType(t, apierr.ApiError{}, err)
+
+		apiErr := err.(apierr.ApiError)
+		assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+		assert.NotContains(t, apiErr.Message, "mock error", "should not return the internal error to the client")
+		assert.EqualError(t, errors.Unwrap(err), "mock error")
+	})
+}
@@ -0,0 +1,176 @@
+// Package auth contains the authentication of the users and the access token operations.
+package auth
+
+import (
+	"crypto"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// SigningKey is the key pair used for signing and verifying the access tokens.
+// for HS256 both keys are the same shared secret.
+type SigningKey struct {
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// NewSigningKey creates the signing key of the given algorithm,
+// HS256 uses the secret while RS256 and EdDSA use the PEM encoded private key file.
+func NewSigningKey(algorithm string, secret string, privateKeyFile string) (SigningKey, error) {
+	switch strings.ToUpper(algorithm) {
+	case AlgorithmHS256:
+		if secret == "" {
+			return SigningKey{}, fmt.Errorf("secret is required for %s", AlgorithmHS256)
+		}
+
+		return SigningKey{
+			method:    jwt.SigningMethodHS256,
+			signKey:   []byte(secret),
+			verifyKey: []byte(secret),
+		}, nil
+	case AlgorithmRS256:
+		buf, err := os.ReadFile(privateKeyFile)
+		if err != nil {
+			return SigningKey{}, err
+		}
+
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(buf)
+		if err != nil {
+			return SigningKey{}, err
+		}
+
+		return SigningKey{
+			method:    jwt.SigningMethodRS256,
+			signKey:   key,
+			verifyKey: key.Public(),
+		}, nil
+	case strings.ToUpper(AlgorithmEdDSA):
+		buf, err := os.ReadFile(privateKeyFile)
+		if err != nil {
+			return SigningKey{}, err
+		}
+
+		key, err := jwt.ParseEdPrivateKeyFromPEM(buf)
+		if err != nil {
+			return SigningKey{}, err
+		}
+
+		return SigningKey{
+			method:    jwt.SigningMethodEdDSA,
+			signKey:   key,
+			verifyKey: key.(crypto.Signer).Public(),
+		}, nil
+	}
+
+	return SigningKey{}, fmt.Errorf("unknown signing algorithm: %s", algorithm)
+}
+
+// Token is a signed access token issued for a subject.
+type Token struct {
+	Value     string
+	ExpiresAt time.Time
+}
+
+type TokenManager interface {
+	Issue(subject string) (Token, error)
+	Verify(token string) (jwt.RegisteredClaims, error)
+}
+
+// tokenManager issues and verifies the JWT access tokens.
+type tokenManager struct {
+	key    SigningKey
+	issuer string
+	ttl    time.Duration
+}
+
+var _ TokenManager = (*tokenManager)(nil)
+
+type TokenManagerOpts func(*tokenManager)
+
+func NewTokenManager(opts ...TokenManagerOpts) *tokenManager {
+	t := &tokenManager{}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+func WithSigningKey(key SigningKey) TokenManagerOpts {
+	return func(t *tokenManager) {
+		t.key = key
+	}
+}
+
+func WithIssuer(issuer string) TokenManagerOpts {
+	return func(t *tokenManager) {
+		t.issuer = issuer
+	}
+}
+
+func WithTokenTTL(ttl time.Duration) TokenManagerOpts {
+	return func(t *tokenManager) {
+		t.ttl = ttl
+	}
+}
+
+// Issue creates a signed access token for the subject which expires after the ttl.
+func (t *tokenManager) Issue(subject string) (Token, error) {
+	now := time.Now()
+	expiresAt := now.Add(t.ttl)
+
+	claims := jwt.RegisteredClaims{
+		ID:        uuid.New().String(),
+		Issuer:    t.issuer,
+		Subject:   subject,
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+
+	value, err := jwt.NewWithClaims(t.key.method, claims).SignedString(t.key.signKey)
+	if err != nil {
+		return Token{}, err
+	}
+
+	return Token{
+		Value:     value,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Verify checks the signature, the algorithm, the issuer and the expiration of the token
+// and returns its claims.
+func (t *tokenManager) Verify(token string) (jwt.RegisteredClaims, error) {
+	var claims jwt.RegisteredClaims
+
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		// the algorithm is pinned, otherwise a token signed with another algorithm might be accepted.
+		if token.Method.Alg() != t.key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Method.Alg())
+		}
+
+		return t.key.verifyKey, nil
+	})
+	if err != nil {
+		return jwt.RegisteredClaims{}, err
+	}
+
+	if t.issuer != "" && !claims.VerifyIssuer(t.issuer, true) {
+		return jwt.RegisteredClaims{}, fmt.Errorf("invalid issuer: %v", claims.Issuer)
+	}
+
+	return claims, nil
+}
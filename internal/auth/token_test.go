@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writePrivateKey(t *testing.T, key interface{}) string {
+	buf, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "private.pem")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: buf}), 0600)
+	assert.NoError(t, err)
+
+	return path
+}
+
+func TestNewSigningKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	rsaKeyFile := writePrivateKey(t, rsaKey)
+	edKeyFile := writePrivateKey(t, edKey)
+
+	tests := []struct {
+		name      string
+		algorithm string
+		secret    string
+		keyFile   string
+	}{
+		{name: "HS256", algorithm: AlgorithmHS256, secret: "secret"},
+		{name: "RS256", algorithm: AlgorithmRS256, keyFile: rsaKeyFile},
+		{name: "EdDSA", algorithm: AlgorithmEdDSA, keyFile: edKeyFile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := NewSigningKey(tt.algorithm, tt.secret, tt.keyFile)
+			assert.NoError(t, err)
+
+			tokens := NewTokenManager(WithSigningKey(key), WithIssuer("test"), WithTokenTTL(time.Minute))
+			subject := uuid.New().String()
+
+			token, err := tokens.Issue(subject)
+			assert.NoError(t, err)
+
+			claims, err := tokens.Verify(token.Value)
+			assert.NoError(t, err)
+			assert.Equal(t, subject, claims.Subject)
+			assert.Equal(t, "test", claims.Issuer)
+		})
+	}
+
+	t.Run("should return error when secret is missing", func(t *testing.T) {
+		_, err := NewSigningKey(AlgorithmHS256, "", "")
+		assert.Error(t, err)
+	})
+
+	t.Run("should return error when the key file does not match the algorithm", func(t *testing.T) {
+		_, err := NewSigningKey(AlgorithmRS256, "", edKeyFile)
+		assert.Error(t, err)
+	})
+
+	t.Run("should return error when algorithm is unknown", func(t *testing.T) {
+		_, err := NewSigningKey("none", "secret", "")
+		assert.Error(t, err)
+	})
+}
+
+func TestTokenManager_Verify(t *testing.T) {
+	key, err := NewSigningKey(AlgorithmHS256, "secret", "")
+	assert.NoError(t, err)
+
+	t.Run("should return error when token is expired", func(t *testing.T) {
+		tokens := NewTokenManager(WithSigningKey(key), WithTokenTTL(-time.Minute))
+
+		token, err := tokens.Issue(uuid.New().String())
+		assert.NoError(t, err)
+
+		_, err = tokens.Verify(token.Value)
+		assert.Error(t, err)
+	})
+
+	t.Run("should return error when token is signed with another key", func(t *testing.T) {
+		otherKey, err := NewSigningKey(AlgorithmHS256, "other secret", "")
+		assert.NoError(t, err)
+
+		token, err := NewTokenManager(WithSigningKey(otherKey), WithTokenTTL(time.Minute)).Issue(uuid.New().String())
+		assert.NoError(t, err)
+
+		_, err = NewTokenManager(WithSigningKey(key), WithTokenTTL(time.Minute)).Verify(token.Value)
+		assert.Error(t, err)
+	})
+
+	t.Run("should return error when issuer does not match", func(t *testing.T) {
+		token, err := NewTokenManager(WithSigningKey(key), WithIssuer("other"), WithTokenTTL(time.Minute)).Issue(uuid.New().String())
+		assert.NoError(t, err)
+
+		_, err = NewTokenManager(WithSigningKey(key), WithIssuer("test"), WithTokenTTL(time.Minute)).Verify(token.Value)
+		assert.Error(t, err)
+	})
+}
@@ -1,10 +1,16 @@
 package config
 
 type Config struct {
-	Service  ServiceConfig
-	Server   ServerConfig
-	Log      LogConfig
-	Postgres PostgresConfig
+	Service      ServiceConfig
+	Server       ServerConfig
+	Log          LogConfig
+	Postgres     PostgresConfig
+	Password     PasswordConfig
+	Auth         AuthConfig
+	User         UserConfig
+	Mail         MailConfig
+	Notification NotificationConfig
+	Idempotency  IdempotencyConfig
 }
 
 type ServiceConfig struct {
@@ -29,3 +35,49 @@ type PostgresConfig struct {
 	MaxIdleConnections int    `split_words:"true" default:"5"`
 	MaxOpenConnections int    `split_words:"true" default:"10"`
 }
+
+type PasswordConfig struct {
+	BcryptCost int `split_words:"true" default:"12"`
+}
+
+type AuthConfig struct {
+	SigningAlgorithm string   `split_words:"true" default:"HS256"`
+	Secret           string   `split_words:"true"`
+	PrivateKeyFile   string   `split_words:"true"`
+	TokenTtl         int      `split_words:"true" default:"900"`
+	Admins           []string `split_words:"true"`
+}
+
+type UserConfig struct {
+	DeletedRetention int   `split_words:"true" default:"2592000"`
+	PurgeInterval    int   `split_words:"true" default:"3600"`
+	ImportBatchSize  int   `split_words:"true" default:"1000"`
+	ImportMaxSize    int64 `split_words:"true" default:"104857600"`
+
+	VerificationSecret   string `split_words:"true"`
+	VerificationTtl      int    `split_words:"true" default:"86400"`
+	VerificationUrl      string `split_words:"true" default:"http://localhost/verify-email"`
+	RequireVerifiedEmail bool   `split_words:"true" default:"false"`
+	PasswordResetTtl     int    `split_words:"true" default:"3600"`
+	PasswordResetUrl     string `split_words:"true" default:"http://localhost/reset-password"`
+}
+
+type MailConfig struct {
+	Sender       string `split_words:"true" default:"log"`
+	From         string `split_words:"true" default:"no-reply@localhost"`
+	SmtpHost     string `split_words:"true"`
+	SmtpPort     int    `split_words:"true" default:"587"`
+	SmtpUsername string `split_words:"true"`
+	SmtpPassword string `split_words:"true"`
+	File         string `split_words:"true" default:"mails.txt"`
+	QueueSize    int    `split_words:"true" default:"100"`
+}
+
+type NotificationConfig struct {
+	DeliveryLogSize int `split_words:"true" default:"10000"`
+}
+
+type IdempotencyConfig struct {
+	Ttl           int `split_words:"true" default:"86400"`
+	PurgeInterval int `split_words:"true" default:"3600"`
+}
@@ -0,0 +1,34 @@
+package idempotency
+
+import (
+	"faceit-backend-test/internal/apierr"
+	"fmt"
+	"net/http"
+)
+
+func keyReusedError(key string) apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusUnprocessableEntity,
+		Code:       "4000",
+		Message:    fmt.Sprintf("the idempotency key %s is already used for another request", key),
+		Data:       nil,
+	}
+}
+
+func requestInProgressError(key string) apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusConflict,
+		Code:       "4001",
+		Message:    fmt.Sprintf("the request with the idempotency key %s is still in progress", key),
+		Data:       nil,
+	}
+}
+
+func requestTooLargeError() apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Code:       "4002",
+		Message:    fmt.Sprintf("the requests with an idempotency key cannot be larger than %d bytes", maxBodySize),
+		Data:       nil,
+	}
+}
@@ -0,0 +1,195 @@
+// Package idempotency makes the retries of the mutating requests safe. The first response to a request made with
+// an idempotency key is stored, and the retries made with the same key are answered with it instead of being served.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"faceit-backend-test/internal/apierr"
+	"faceit-backend-test/internal/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Header is the header the idempotency key is read from.
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is set on the responses that are replayed from a previous request.
+const ReplayedHeader = "Idempotent-Replayed"
+
+const (
+	// maxKeyLength limits the length of the keys given by the clients.
+	maxKeyLength = 255
+	// maxBodySize limits the size of the requests made with a key, since they are read in memory to be compared.
+	maxBodySize = 1 << 20
+)
+
+// storedHeaders are the response headers that are replayed with the stored responses.
+var storedHeaders = []string{"Content-Type", "Content-Disposition", "ETag", "Location"}
+
+// NewMiddleware creates a gin middleware that stores the first response to the requests made with an idempotency key
+// for the ttl and replays it on the retries. The keys are scoped to the subject of the access token, a key cannot be
+// reused for a request with another method, path or body. The requests without a key are served as usual.
+func NewMiddleware(store Store, ttl time.Duration, logger *logrus.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(Header)
+		if key == "" {
+			ctx.Next()
+			return
+		}
+
+		if !valid(key) {
+			abort(ctx, apierr.BadRequest("the idempotency key must be at most 255 printable ASCII characters"))
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxBodySize+1))
+		if err != nil {
+			abort(ctx, apierr.BadRequest(err.Error()))
+			return
+		}
+		if len(body) > maxBodySize {
+			abort(ctx, requestTooLargeError())
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		client, _ := auth.SubjectFromContext(ctx.Request.Context())
+		record := Record{Client: client, Key: key, Fingerprint: fingerprint(ctx.Request, body)}
+
+		stored, claimed, err := store.Claim(ctx.Request.Context(), record, ttl)
+		if err != nil {
+			logger.WithFields(logrus.Fields{
+				"client": client,
+				"key":    key,
+				"error":  err.Error(),
+			}).Error("cannot claim the idempotency key")
+			abort(ctx, apierr.InternalServerError())
+			return
+		}
+
+		if !claimed {
+			replay(ctx, stored, record)
+			return
+		}
+
+		// the record is completed even if the request is cancelled, otherwise the key would be stuck in progress.
+		storeCtx := context.Background()
+		recorder := &responseRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = recorder
+
+		defer func() {
+			if r := recover(); r != nil {
+				release(storeCtx, store, record, logger)
+				panic(r)
+			}
+		}()
+
+		ctx.Next()
+
+		// the server errors are not stored, so that the requests failed by them can be retried with the same key.
+		if recorder.Status() >= http.StatusInternalServerError {
+			release(storeCtx, store, record, logger)
+			return
+		}
+
+		record.StatusCode = recorder.Status()
+		record.Header = ResponseHeader{}
+		for _, name := range storedHeaders {
+			if value := recorder.Header().Get(name); value != "" {
+				http.Header(record.Header).Set(name, value)
+			}
+		}
+		record.Body = recorder.body.Bytes()
+
+		if err := store.Complete(storeCtx, record); err != nil {
+			logger.WithFields(logrus.Fields{
+				"client": client,
+				"key":    key,
+				"error":  err.Error(),
+			}).Error("cannot store the response of the idempotency key")
+		}
+	}
+}
+
+// replay writes the stored response, unless the key is used for another request or the request is still in progress.
+func replay(ctx *gin.Context, stored Record, record Record) {
+	if stored.Fingerprint != record.Fingerprint {
+		abort(ctx, keyReusedError(record.Key))
+		return
+	}
+
+	if !stored.Completed() {
+		abort(ctx, requestInProgressError(record.Key))
+		return
+	}
+
+	for name, values := range stored.Header {
+		for _, value := range values {
+			ctx.Writer.Header().Add(name, value)
+		}
+	}
+	ctx.Header(ReplayedHeader, "true")
+	ctx.Status(stored.StatusCode)
+	_, _ = ctx.Writer.Write(stored.Body)
+	ctx.Abort()
+}
+
+func release(ctx context.Context, store Store, record Record, logger *logrus.Logger) {
+	if err := store.Release(ctx, record.Client, record.Key); err != nil {
+		logger.WithFields(logrus.Fields{
+			"client": record.Client,
+			"key":    record.Key,
+			"error":  err.Error(),
+		}).Error("cannot release the idempotency key")
+	}
+}
+
+// valid reports whether the key is not longer than maxKeyLength and only contains printable ASCII characters.
+func valid(key string) bool {
+	if len(key) > maxKeyLength {
+		return false
+	}
+
+	for _, c := range key {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fingerprint identifies the request made with a key by its method, path, query and body.
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func abort(ctx *gin.Context, apiErr apierr.ApiError) {
+	ctx.AbortWithStatusJSON(apiErr.StatusCode, apiErr)
+}
+
+// responseRecorder keeps a copy of the response body written to the client.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,247 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"faceit-backend-test/internal/apierr"
+	"faceit-backend-test/internal/auth"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockStore struct {
+	claimMock    func(ctx context.Context, record Record, ttl time.Duration) (Record, bool, error)
+	completeMock func(ctx context.Context, record Record) error
+	releaseMock  func(ctx context.Context, client string, key string) error
+	purgeMock    func(ctx context.Context) (int64, error)
+}
+
+func (m *mockStore) Claim(ctx context.Context, record Record, ttl time.Duration) (Record, bool, error) {
+	return m.claimMock(ctx, record, ttl)
+}
+
+func (m *mockStore) Complete(ctx context.Context, record Record) error {
+	return m.completeMock(ctx, record)
+}
+
+func (m *mockStore) Release(ctx context.Context, client string, key string) error {
+	return m.releaseMock(ctx, client, key)
+}
+
+func (m *mockStore) PurgeExpired(ctx context.Context) (int64, error) {
+	return m.purgeMock(ctx)
+}
+
+func TestNewMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	served := 0
+	status := http.StatusCreated
+
+	store := &mockStore{}
+	router := gin.Default()
+	router.POST("/users", func(ctx *gin.Context) {
+		ctx.Request = ctx.Request.WithContext(auth.NewContext(ctx.Request.Context(), "admin"))
+	}, NewMiddleware(store, time.Hour, logrus.New()), func(ctx *gin.Context) {
+		served++
+
+		body, err := io.ReadAll(ctx.Request.Body)
+		assert.NoError(t, err)
+
+		ctx.Header("ETag", `"1"`)
+		ctx.Data(status, "application/json", body)
+	})
+
+	newRequest := func(key string, body string) *http.Request {
+		request, err := http.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+		assert.NoError(t, err)
+		if key != "" {
+			request.Header.Set(Header, key)
+		}
+
+		return request
+	}
+
+	t.Run("should serve the requests without a key as usual", func(t *testing.T) {
+		served = 0
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, newRequest("", `{"nickname":"bobby"}`))
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, 1, served)
+	})
+
+	t.Run("should store the first response of a key", func(t *testing.T) {
+		served = 0
+
+		var completed Record
+		store.claimMock = func(ctx context.Context, record Record, ttl time.Duration) (Record, bool, error) {
+			assert.Equal(t, "admin", record.Client, "should scope the key to the subject of the access token")
+			assert.Equal(t, "key-1", record.Key)
+			assert.Len(t, record.Fingerprint, 64)
+			assert.Equal(t, time.Hour, ttl)
+
+			return record, true, nil
+		}
+		store.completeMock = func(ctx context.Context, record Record) error {
+			completed = record
+			return nil
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, newRequest("key-1", `{"nickname":"bobby"}`))
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, `{"nickname":"bobby"}`, rr.Body.String(), "should pass the body on to the handler")
+		assert.Empty(t, rr.Header().Get(ReplayedHeader))
+		assert.Equal(t, 1, served)
+
+		assert.Equal(t, http.StatusCreated, completed.StatusCode)
+		assert.Equal(t, `{"nickname":"bobby"}`, string(completed.Body))
+		assert.Equal(t, `"1"`, http.Header(completed.Header).Get("ETag"))
+		assert.Equal(t, "application/json", http.Header(completed.Header).Get("Content-Type"))
+	})
+
+	t.Run("should replay the stored response to the retries", func(t *testing.T) {
+		served = 0
+
+		store.claimMock = func(ctx context.Context, record Record, ttl time.Duration) (Record, bool, error) {
+			stored := record
+			stored.StatusCode = http.StatusCreated
+			stored.Header = ResponseHeader{"Content-Type": {"application/json"}, "Etag": {`"1"`}}
+			stored.Body = []byte(`{"id":"1"}`)
+
+			return stored, false, nil
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, newRequest("key-1", `{"nickname":"bobby"}`))
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, `{"id":"1"}`, rr.Body.String())
+		assert.Equal(t, `"1"`, rr.Header().Get("ETag"))
+		assert.Equal(t, "true", rr.Header().Get(ReplayedHeader))
+		assert.Equal(t, 0, served, "should not serve the retries")
+	})
+
+	t.Run("should return unprocessable entity when the key is reused for another request", func(t *testing.T) {
+		served = 0
+
+		store.claimMock = func(ctx context.Context, record Record, ttl time.Duration) (Record, bool, error) {
+			stored := record
+			stored.Fingerprint = "another"
+			stored.StatusCode = http.StatusCreated
+
+			return stored, false, nil
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, newRequest("key-1", `{"nickname":"another"}`))
+
+		assertApiError(t, rr, keyReusedError("key-1"))
+		assert.Equal(t, 0, served)
+	})
+
+	t.Run("should return conflict when the first request is in progress", func(t *testing.T) {
+		served = 0
+
+		store.claimMock = func(ctx context.Context, record Record, ttl time.Duration) (Record, bool, error) {
+			return record, false, nil
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, newRequest("key-1", `{"nickname":"bobby"}`))
+
+		assertApiError(t, rr, requestInProgressError("key-1"))
+		assert.Equal(t, 0, served)
+	})
+
+	t.Run("should release the key when the request fails with a server error", func(t *testing.T) {
+		status = http.StatusInternalServerError
+		defer func() { status = http.StatusCreated }()
+
+		released := ""
+		store.claimMock = func(ctx context.Context, record Record, ttl time.Duration) (Record, bool, error) {
+			return record, true, nil
+		}
+		store.completeMock = func(ctx context.Context, record Record) error {
+			assert.Fail(t, "should not store the server errors")
+			return nil
+		}
+		store.releaseMock = func(ctx context.Context, client string, key string) error {
+			released = key
+			return nil
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, newRequest("key-1", `{"nickname":"bobby"}`))
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Equal(t, "key-1", released)
+	})
+
+	t.Run("should return internal server error when the key cannot be claimed", func(t *testing.T) {
+		served = 0
+
+		store.claimMock = func(ctx context.Context, record Record, ttl time.Duration) (Record, bool, error) {
+			return Record{}, false, fmt.Errorf("mock error")
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, newRequest("key-1", `{"nickname":"bobby"}`))
+
+		assertApiError(t, rr, apierr.InternalServerError())
+		assert.Equal(t, 0, served)
+	})
+
+	t.Run("should return bad request when the key is invalid", func(t *testing.T) {
+		for _, key := range []string{"key\x01", strings.Repeat("k", maxKeyLength+1)} {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, newRequest(key, `{}`))
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, key)
+		}
+	})
+
+	t.Run("should return request entity too large when the request is too large to be compared", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, newRequest("key-1", strings.Repeat("a", maxBodySize+1)))
+
+		assertApiError(t, rr, requestTooLargeError())
+	})
+}
+
+func TestFingerprint(t *testing.T) {
+	newRequest := func(method string, target string) *http.Request {
+		request, err := http.NewRequest(method, target, nil)
+		assert.NoError(t, err)
+		return request
+	}
+
+	body := []byte(`{"nickname":"bobby"}`)
+	expected := fingerprint(newRequest(http.MethodPatch, "/users/1?fields=id"), body)
+
+	assert.Equal(t, expected, fingerprint(newRequest(http.MethodPatch, "/users/1?fields=id"), []byte(`{"nickname":"bobby"}`)))
+	assert.NotEqual(t, expected, fingerprint(newRequest(http.MethodPatch, "/users/1?fields=id"), []byte(`{}`)))
+	assert.NotEqual(t, expected, fingerprint(newRequest(http.MethodPatch, "/users/2?fields=id"), body))
+	assert.NotEqual(t, expected, fingerprint(newRequest(http.MethodDelete, "/users/1?fields=id"), body))
+	assert.NotEqual(t, expected, fingerprint(newRequest(http.MethodPatch, "/users/1"), body))
+}
+
+func assertApiError(t *testing.T, rr *httptest.ResponseRecorder, expected apierr.ApiError) {
+	assert.Equal(t, expected.StatusCode, rr.Code)
+
+	var actual apierr.ApiError
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &actual))
+	assert.Equal(t, expected.Code, actual.Code)
+	assert.Equal(t, expected.Message, actual.Message)
+}
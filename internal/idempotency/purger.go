@@ -0,0 +1,45 @@
+package idempotency
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// purger deletes the records of the expired keys regularly, the expired keys are reusable even before they are purged.
+type purger struct {
+	store    Store
+	interval time.Duration
+	logger   *logrus.Logger
+}
+
+func NewPurger(store Store, interval time.Duration, logger *logrus.Logger) *purger {
+	return &purger{store: store, interval: interval, logger: logger}
+}
+
+// Start runs the purge async on every interval until the context is done.
+func (p *purger) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.purge(ctx)
+			}
+		}
+	}()
+}
+
+func (p *purger) purge(ctx context.Context) {
+	purged, err := p.store.PurgeExpired(ctx)
+	if err != nil {
+		p.logger.WithField("error", err.Error()).Error("cannot purge the expired idempotency keys")
+		return
+	}
+
+	p.logger.WithField("purged", purged).Debug("purged the expired idempotency keys")
+}
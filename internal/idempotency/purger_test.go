@@ -0,0 +1,33 @@
+package idempotency
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"testing"
+	"time"
+)
+
+func TestPurger_Start(t *testing.T) {
+	purged := make(chan struct{}, 1)
+
+	store := &mockStore{}
+	store.purgeMock = func(ctx context.Context) (int64, error) {
+		select {
+		case purged <- struct{}{}:
+		default:
+		}
+
+		return 1, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	NewPurger(store, time.Millisecond, logrus.New()).Start(ctx)
+
+	select {
+	case <-purged:
+	case <-time.After(time.Second):
+		t.Fatal("should purge the expired keys on every interval")
+	}
+}
@@ -0,0 +1,169 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+	"time"
+)
+
+// claimKeyQuery stores a new record of the key, the record of an expired key is replaced so that the key can be reused.
+// Nothing is returned when the key is already used by the client.
+const claimKeyQuery = `INSERT INTO idempotency_keys (client, key, fingerprint, expires_at)
+						VALUES (:client, :key, :fingerprint, current_timestamp + make_interval(secs => :ttl))
+						ON CONFLICT (client, key) DO UPDATE SET fingerprint=EXCLUDED.fingerprint, status_code=NULL,
+						header=NULL, body=NULL, created_at=current_timestamp, expires_at=EXCLUDED.expires_at
+						WHERE idempotency_keys.expires_at <= current_timestamp
+						RETURNING client;`
+const selectKeyQuery = `SELECT client, key, fingerprint, coalesce(status_code, 0) AS status_code, header, body, expires_at
+						FROM idempotency_keys WHERE client=:client AND key=:key;`
+const completeKeyQuery = `UPDATE idempotency_keys SET status_code=:status_code, header=:header, body=:body
+						WHERE client=:client AND key=:key;`
+const releaseKeyQuery = `DELETE FROM idempotency_keys WHERE client=:client AND key=:key AND status_code IS NULL;`
+const purgeExpiredKeysQuery = `DELETE FROM idempotency_keys WHERE expires_at <= current_timestamp;`
+
+// Record is the first request made with an idempotency key by a client and the response to it,
+// the status code is 0 until the response is stored.
+type Record struct {
+	Client      string         `db:"client"`
+	Key         string         `db:"key"`
+	Fingerprint string         `db:"fingerprint"`
+	StatusCode  int            `db:"status_code"`
+	Header      ResponseHeader `db:"header"`
+	Body        []byte         `db:"body"`
+	ExpiresAt   time.Time      `db:"expires_at"`
+}
+
+// Completed reports whether the response to the request is stored.
+func (r Record) Completed() bool {
+	return r.StatusCode != 0
+}
+
+// ResponseHeader is the header of a stored response, it is stored as jsonb.
+type ResponseHeader http.Header
+
+func (h ResponseHeader) Value() (driver.Value, error) {
+	b, err := json.Marshal(h)
+	return string(b), err
+}
+
+func (h *ResponseHeader) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, h)
+	case string:
+		return json.Unmarshal([]byte(v), h)
+	}
+
+	return fmt.Errorf("cannot scan %T into %T", src, h)
+}
+
+// Store stores the records of the idempotency keys.
+type Store interface {
+	// Claim stores the record of the key unless the client has already used it, the stored record is returned then.
+	Claim(ctx context.Context, record Record, ttl time.Duration) (Record, bool, error)
+	// Complete stores the response to the request of the record.
+	Complete(ctx context.Context, record Record) error
+	// Release removes the record of a request that is not completed, so that the key can be used again.
+	Release(ctx context.Context, client string, key string) error
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+type repository struct {
+	db *sqlx.DB
+}
+
+var _ Store = (*repository)(nil)
+
+type RepositoryOpts func(*repository)
+
+func NewRepository(opts ...RepositoryOpts) *repository {
+	r := &repository{}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+func WithDb(db *sqlx.DB) RepositoryOpts {
+	return func(r *repository) {
+		r.db = db
+	}
+}
+
+func (r *repository) Claim(ctx context.Context, record Record, ttl time.Duration) (Record, bool, error) {
+	stmt, err := r.db.PrepareNamedContext(ctx, claimKeyQuery)
+	if err != nil {
+		return Record{}, false, err
+	}
+	defer stmt.Close()
+
+	var client string
+	err = stmt.QueryRowxContext(ctx, map[string]interface{}{
+		"client":      record.Client,
+		"key":         record.Key,
+		"fingerprint": record.Fingerprint,
+		"ttl":         ttl.Seconds(),
+	}).Scan(&client)
+	if err == nil {
+		return record, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Record{}, false, err
+	}
+
+	stored, err := r.get(ctx, record.Client, record.Key)
+	return stored, false, err
+}
+
+func (r *repository) get(ctx context.Context, client string, key string) (Record, error) {
+	stmt, err := r.db.PrepareNamedContext(ctx, selectKeyQuery)
+	if err != nil {
+		return Record{}, err
+	}
+	defer stmt.Close()
+
+	var record Record
+	err = stmt.QueryRowxContext(ctx, map[string]interface{}{"client": client, "key": key}).StructScan(&record)
+	return record, err
+}
+
+func (r *repository) Complete(ctx context.Context, record Record) error {
+	stmt, err := r.db.PrepareNamedContext(ctx, completeKeyQuery)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, record)
+	return err
+}
+
+func (r *repository) Release(ctx context.Context, client string, key string) error {
+	stmt, err := r.db.PrepareNamedContext(ctx, releaseKeyQuery)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, map[string]interface{}{"client": client, "key": key})
+	return err
+}
+
+func (r *repository) PurgeExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, purgeExpiredKeysQuery)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
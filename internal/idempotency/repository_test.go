@@ -0,0 +1,143 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var recordColumns = []string{"client", "key", "fingerprint", "status_code", "header", "body", "expires_at"}
+
+func NewMock() (*sqlx.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		logrus.WithField("error", err).Fatalf("unexpected error while opening a stub database")
+	}
+
+	sqlxDb := sqlx.NewDb(db, "sqlmock")
+	return sqlxDb, mock
+}
+
+func TestRepository_Claim(t *testing.T) {
+	record := Record{Client: "admin", Key: "key-1", Fingerprint: "fingerprint"}
+
+	t.Run("should claim a new key", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(WithDb(db))
+
+		prep := mock.ExpectPrepare("INSERT INTO idempotency_keys")
+		prep.ExpectQuery().
+			WithArgs(record.Client, record.Key, record.Fingerprint, time.Hour.Seconds()).
+			WillReturnRows(sqlmock.NewRows([]string{"client"}).AddRow(record.Client))
+
+		actual, claimed, err := repo.Claim(context.Background(), record, time.Hour)
+		assert.NoError(t, err)
+		assert.True(t, claimed)
+		assert.Equal(t, record, actual)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return the stored record when the key is already used", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(WithDb(db))
+
+		expiresAt := time.Now().Add(time.Hour)
+
+		prep := mock.ExpectPrepare("INSERT INTO idempotency_keys")
+		prep.ExpectQuery().
+			WithArgs(record.Client, record.Key, record.Fingerprint, time.Hour.Seconds()).
+			WillReturnError(sql.ErrNoRows)
+
+		prep = mock.ExpectPrepare("SELECT client, key, fingerprint")
+		prep.ExpectQuery().
+			WithArgs(record.Client, record.Key).
+			WillReturnRows(sqlmock.NewRows(recordColumns).
+				AddRow(record.Client, record.Key, record.Fingerprint, http.StatusCreated,
+					[]byte(`{"Etag":["\"1\""]}`), []byte(`{"id":"1"}`), expiresAt))
+
+		actual, claimed, err := repo.Claim(context.Background(), record, time.Hour)
+		assert.NoError(t, err)
+		assert.False(t, claimed)
+		assert.Equal(t, Record{
+			Client:      record.Client,
+			Key:         record.Key,
+			Fingerprint: record.Fingerprint,
+			StatusCode:  http.StatusCreated,
+			Header:      ResponseHeader{"Etag": {`"1"`}},
+			Body:        []byte(`{"id":"1"}`),
+			ExpiresAt:   expiresAt,
+		}, actual)
+		assert.True(t, actual.Completed())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return the record of a request in progress", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(WithDb(db))
+
+		prep := mock.ExpectPrepare("INSERT INTO idempotency_keys")
+		prep.ExpectQuery().WillReturnError(sql.ErrNoRows)
+
+		prep = mock.ExpectPrepare("SELECT client, key, fingerprint")
+		prep.ExpectQuery().
+			WillReturnRows(sqlmock.NewRows(recordColumns).
+				AddRow(record.Client, record.Key, record.Fingerprint, 0, nil, nil, time.Now()))
+
+		actual, claimed, err := repo.Claim(context.Background(), record, time.Hour)
+		assert.NoError(t, err)
+		assert.False(t, claimed)
+		assert.False(t, actual.Completed())
+	})
+}
+
+func TestRepository_Complete(t *testing.T) {
+	db, mock := NewMock()
+	repo := NewRepository(WithDb(db))
+
+	record := Record{
+		Client:     "admin",
+		Key:        "key-1",
+		StatusCode: http.StatusCreated,
+		Header:     ResponseHeader{"Etag": {`"1"`}},
+		Body:       []byte(`{"id":"1"}`),
+	}
+
+	prep := mock.ExpectPrepare("UPDATE idempotency_keys SET status_code=\\?, header=\\?, body=\\?")
+	prep.ExpectExec().
+		WithArgs(http.StatusCreated, `{"Etag":["\"1\""]}`, record.Body, record.Client, record.Key).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.Complete(context.Background(), record))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_Release(t *testing.T) {
+	db, mock := NewMock()
+	repo := NewRepository(WithDb(db))
+
+	prep := mock.ExpectPrepare("DELETE FROM idempotency_keys WHERE client=\\? AND key=\\? AND status_code IS NULL")
+	prep.ExpectExec().
+		WithArgs("admin", "key-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.Release(context.Background(), "admin", "key-1"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_PurgeExpired(t *testing.T) {
+	db, mock := NewMock()
+	repo := NewRepository(WithDb(db))
+
+	mock.ExpectExec("DELETE FROM idempotency_keys WHERE expires_at <= current_timestamp").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	purged, err := repo.PurgeExpired(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), purged)
+}
@@ -0,0 +1,58 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrQueueFull is returned by the async sender when too many mails are waiting to be sent.
+var ErrQueueFull = errors.New("mail queue is full")
+
+// asyncSender queues the mails and sends them by the next sender in the background, so that the requests
+// don't wait for the mail server. The failures are logged since there is no one to return them to.
+type asyncSender struct {
+	next   Sender
+	queue  chan Message
+	logger *logrus.Logger
+}
+
+var _ Sender = (*asyncSender)(nil)
+
+func NewAsyncSender(next Sender, queueSize int, logger *logrus.Logger) *asyncSender {
+	return &asyncSender{
+		next:   next,
+		queue:  make(chan Message, queueSize),
+		logger: logger,
+	}
+}
+
+// Start sends the queued mails until the context is done.
+func (s *asyncSender) Start(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message := <-s.queue:
+				err := s.next.Send(ctx, message)
+				if err != nil {
+					s.logger.WithFields(logrus.Fields{
+						"to":      message.To,
+						"subject": message.Subject,
+						"error":   err.Error(),
+					}).Error("cannot send the mail")
+				}
+			}
+		}
+	}()
+}
+
+func (s *asyncSender) Send(ctx context.Context, message Message) error {
+	select {
+	case s.queue <- message:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
@@ -0,0 +1,11 @@
+package mail
+
+import "context"
+
+type MockSender struct {
+	SendMock func(context.Context, Message) error
+}
+
+func (m *MockSender) Send(ctx context.Context, message Message) error {
+	return m.SendMock(ctx, message)
+}
@@ -0,0 +1,87 @@
+package mail
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var message = Message{
+	To:      "robertofirmino@lfc.co.uk",
+	Subject: "Verify your email",
+	Body:    "first line\nsecond line",
+}
+
+func TestFormat(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		date := time.Date(2022, 9, 5, 16, 34, 57, 0, time.UTC)
+
+		actual := string(format("no-reply@faceit.com", message, date))
+
+		assert.Equal(t, "From: no-reply@faceit.com\r\n"+
+			"To: robertofirmino@lfc.co.uk\r\n"+
+			"Subject: Verify your email\r\n"+
+			"Date: Mon, 05 Sep 2022 16:34:57 +0000\r\n"+
+			"MIME-Version: 1.0\r\n"+
+			"Content-Type: text/plain; charset=UTF-8\r\n"+
+			"\r\n"+
+			"first line\r\nsecond line\r\n", actual)
+	})
+}
+
+func TestFileSender_Send(t *testing.T) {
+	t.Run("should append the mails to the file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "mails.txt")
+		sender := NewFileSender(path, "no-reply@faceit.com")
+
+		assert.NoError(t, sender.Send(context.Background(), message))
+		assert.NoError(t, sender.Send(context.Background(), message))
+
+		buf, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, strings.Count(string(buf), "To: robertofirmino@lfc.co.uk\r\n"))
+	})
+}
+
+type senderFunc func(ctx context.Context, message Message) error
+
+func (f senderFunc) Send(ctx context.Context, message Message) error {
+	return f(ctx, message)
+}
+
+func TestAsyncSender_Send(t *testing.T) {
+	t.Run("should send the mails in the background", func(t *testing.T) {
+		sent := make(chan Message, 1)
+		sender := NewAsyncSender(senderFunc(func(ctx context.Context, message Message) error {
+			sent <- message
+			return nil
+		}), 1, logrus.New())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sender.Start(ctx)
+
+		assert.NoError(t, sender.Send(context.Background(), message))
+
+		select {
+		case actual := <-sent:
+			assert.Equal(t, message, actual)
+		case <-time.After(time.Second):
+			assert.Fail(t, "the mail is not sent")
+		}
+	})
+
+	t.Run("should return an error when the queue is full", func(t *testing.T) {
+		sender := NewAsyncSender(senderFunc(func(ctx context.Context, message Message) error {
+			return nil
+		}), 1, logrus.New())
+
+		assert.NoError(t, sender.Send(context.Background(), message))
+		assert.ErrorIs(t, sender.Send(context.Background(), message), ErrQueueFull)
+	})
+}
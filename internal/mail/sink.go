@@ -0,0 +1,61 @@
+package mail
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"os"
+	"sync"
+	"time"
+)
+
+// fileSender appends the mails to a file instead of sending them, so that they can be read in the local runs.
+type fileSender struct {
+	mu   sync.Mutex
+	path string
+	from string
+}
+
+var _ Sender = (*fileSender)(nil)
+
+func NewFileSender(path string, from string) *fileSender {
+	return &fileSender{path: path, from: from}
+}
+
+func (s *fileSender) Send(ctx context.Context, message Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(append(format(s.from, message, time.Now()), "\r\n"...))
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// logSender logs the mails instead of sending them, it is the default of the local runs.
+type logSender struct {
+	logger *logrus.Logger
+}
+
+var _ Sender = (*logSender)(nil)
+
+func NewLogSender(logger *logrus.Logger) *logSender {
+	return &logSender{logger: logger}
+}
+
+func (s *logSender) Send(ctx context.Context, message Message) error {
+	s.logger.WithFields(logrus.Fields{
+		"to":      message.To,
+		"subject": message.Subject,
+		"body":    message.Body,
+	}).Info("mail is not sent, it is logged")
+
+	return nil
+}
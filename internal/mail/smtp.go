@@ -0,0 +1,66 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// smtpSender sends the mails by an SMTP server, the credentials are sent by PLAIN auth,
+// which net/smtp only allows over TLS or to localhost.
+type smtpSender struct {
+	address  string
+	username string
+	password string
+	from     string
+}
+
+var _ Sender = (*smtpSender)(nil)
+
+type SMTPSenderOpts func(*smtpSender)
+
+func NewSMTPSender(opts ...SMTPSenderOpts) *smtpSender {
+	s := &smtpSender{}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func WithAddress(host string, port int) SMTPSenderOpts {
+	return func(s *smtpSender) {
+		s.address = net.JoinHostPort(host, fmt.Sprint(port))
+	}
+}
+
+func WithCredentials(username string, password string) SMTPSenderOpts {
+	return func(s *smtpSender) {
+		s.username = username
+		s.password = password
+	}
+}
+
+func WithFrom(from string) SMTPSenderOpts {
+	return func(s *smtpSender) {
+		s.from = from
+	}
+}
+
+// Send delivers the message to the SMTP server, the context is not used since net/smtp doesn't support it.
+func (s *smtpSender) Send(ctx context.Context, message Message) error {
+	var auth smtp.Auth
+	if s.username != "" {
+		host, _, err := net.SplitHostPort(s.address)
+		if err != nil {
+			return err
+		}
+
+		auth = smtp.PlainAuth("", s.username, s.password, host)
+	}
+
+	return smtp.SendMail(s.address, auth, s.from, []string{message.To}, format(s.from, message, time.Now()))
+}
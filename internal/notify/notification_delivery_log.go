@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultDeliveryLogSize = 10000
+
+// Subject is implemented by the notification data about a subject, such as a user,
+// so that the deliveries of the data about the subject can be found.
+type Subject interface {
+	NotificationSubject() string
+}
+
+// Delivery is a notification sent to a subscriber, whether it is accepted by the subscriber or not.
+// The data of the notification is not kept, only the subject it is about.
+type Delivery struct {
+	MessageId      string    `json:"message_id"`
+	SubscriptionId string    `json:"subscription_id"`
+	Topic          string    `json:"topic"`
+	CallbackUrl    string    `json:"callback_url"`
+	Delivered      bool      `json:"delivered"`
+	SentAt         time.Time `json:"sent_at"`
+	subject        string
+}
+
+// deliveryLog keeps the latest deliveries about the subjects in memory,
+// the oldest ones are dropped once the log is full.
+type deliveryLog struct {
+	deliveries []Delivery
+	size       int
+	mtx        sync.RWMutex
+}
+
+func newDeliveryLog(size int) *deliveryLog {
+	return &deliveryLog{size: size}
+}
+
+// record appends the delivery when the data sent is about a subject.
+func (l *deliveryLog) record(delivery Delivery, data interface{}) {
+	subject, ok := data.(Subject)
+	if !ok || l.size <= 0 {
+		return
+	}
+	delivery.subject = subject.NotificationSubject()
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if len(l.deliveries) >= l.size {
+		l.deliveries = l.deliveries[len(l.deliveries)-l.size+1:]
+	}
+	l.deliveries = append(l.deliveries, delivery)
+}
+
+// bySubject returns the deliveries about the subject, the oldest first.
+func (l *deliveryLog) bySubject(subject string) []Delivery {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+
+	deliveries := []Delivery{}
+	for _, delivery := range l.deliveries {
+		if delivery.subject == subject {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	return deliveries
+}
+
+// forget removes the deliveries about the subject.
+func (l *deliveryLog) forget(subject string) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	kept := make([]Delivery, 0, len(l.deliveries))
+	for _, delivery := range l.deliveries {
+		if delivery.subject != subject {
+			kept = append(kept, delivery)
+		}
+	}
+	l.deliveries = kept
+}
@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type mockSubject struct {
+	Id string `json:"id"`
+}
+
+func (s mockSubject) NotificationSubject() string {
+	return s.Id
+}
+
+func TestDeliveryLog(t *testing.T) {
+	t.Run("should only record the deliveries about a subject", func(t *testing.T) {
+		log := newDeliveryLog(10)
+		log.record(Delivery{MessageId: "1"}, mockSubject{Id: "a"})
+		log.record(Delivery{MessageId: "2"}, 5)
+		log.record(Delivery{MessageId: "3"}, mockSubject{Id: "b"})
+		log.record(Delivery{MessageId: "4"}, mockSubject{Id: "a"})
+
+		deliveries := log.bySubject("a")
+		assert.Len(t, deliveries, 2)
+		assert.Equal(t, "1", deliveries[0].MessageId)
+		assert.Equal(t, "4", deliveries[1].MessageId)
+	})
+
+	t.Run("should drop the oldest deliveries when it is full", func(t *testing.T) {
+		log := newDeliveryLog(2)
+		log.record(Delivery{MessageId: "1"}, mockSubject{Id: "a"})
+		log.record(Delivery{MessageId: "2"}, mockSubject{Id: "a"})
+		log.record(Delivery{MessageId: "3"}, mockSubject{Id: "a"})
+
+		deliveries := log.bySubject("a")
+		assert.Len(t, deliveries, 2)
+		assert.Equal(t, "2", deliveries[0].MessageId)
+	})
+
+	t.Run("should forget the deliveries about the subject", func(t *testing.T) {
+		log := newDeliveryLog(10)
+		log.record(Delivery{MessageId: "1"}, mockSubject{Id: "a"})
+		log.record(Delivery{MessageId: "2"}, mockSubject{Id: "b"})
+
+		log.forget("a")
+		assert.Empty(t, log.bySubject("a"))
+		assert.Len(t, log.bySubject("b"), 1)
+	})
+
+	t.Run("should not record anything when the size is 0", func(t *testing.T) {
+		log := newDeliveryLog(0)
+		log.record(Delivery{MessageId: "1"}, mockSubject{Id: "a"})
+
+		assert.Empty(t, log.bySubject("a"))
+	})
+}
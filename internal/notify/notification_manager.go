@@ -19,6 +19,8 @@ type notificationManager struct {
 	broker                      pubsub.Broker
 	pendingVerification         chan NotificationWebhookClient
 	notificationWebhookHandlers map[string]*notificationWebhookHandler
+	deliveries                  *deliveryLog
+	deliveryLogSize             int
 	logger                      *logrus.Logger
 }
 
@@ -30,16 +32,18 @@ func NewNotificationManager(topics []string, opts ...NotificationManagerOpts) *n
 	n := &notificationManager{
 		notificationWebhookHandlers: map[string]*notificationWebhookHandler{},
 		pendingVerification:         make(chan NotificationWebhookClient, 100),
+		deliveryLogSize:             defaultDeliveryLogSize,
 	}
 
 	for _, opt := range opts {
 		opt(n)
 	}
 
+	n.deliveries = newDeliveryLog(n.deliveryLogSize)
 	for _, topic := range topics {
 		subscriber := pubsub.NewSubscriber()
 		n.broker.Subscribe(subscriber, topic)
-		n.notificationWebhookHandlers[topic] = newNotificationWebhookHandler(n.logger, subscriber)
+		n.notificationWebhookHandlers[topic] = newNotificationWebhookHandler(n.logger, subscriber, n.deliveries)
 	}
 
 	return n
@@ -57,6 +61,13 @@ func WithBroker(broker pubsub.Broker) NotificationManagerOpts {
 	}
 }
 
+// WithDeliveryLogSize sets how many of the latest deliveries about the subjects are kept, they are not kept when it is 0.
+func WithDeliveryLogSize(size int) NotificationManagerOpts {
+	return func(manager *notificationManager) {
+		manager.deliveryLogSize = size
+	}
+}
+
 // Start starts all the notification operators async
 func (n *notificationManager) Start() {
 	go n.handlePendingVerifications()
@@ -119,3 +130,13 @@ func (n *notificationManager) handlePendingVerifications() {
 		topic.addToSubscribers(notifierClient)
 	}
 }
+
+// Deliveries returns the deliveries about the subject which are still kept, the oldest first.
+func (n *notificationManager) Deliveries(subject string) []Delivery {
+	return n.deliveries.bySubject(subject)
+}
+
+// ForgetDeliveries removes the deliveries about the subject, so that its data is not kept anymore.
+func (n *notificationManager) ForgetDeliveries(subject string) {
+	n.deliveries.forget(subject)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"sync"
+	"time"
 )
 
 type NotificationWebhookClient interface {
@@ -21,14 +22,16 @@ type notificationWebhookHandler struct {
 	logger              *logrus.Logger
 	eventSubscriber     pubsub.Subscriber
 	notificationClients []NotificationWebhookClient
+	deliveries          *deliveryLog
 	mtx                 sync.RWMutex
 }
 
-func newNotificationWebhookHandler(logger *logrus.Logger, subscriber pubsub.Subscriber) *notificationWebhookHandler {
+func newNotificationWebhookHandler(logger *logrus.Logger, subscriber pubsub.Subscriber, deliveries *deliveryLog) *notificationWebhookHandler {
 	return &notificationWebhookHandler{
 		logger:              logger,
 		eventSubscriber:     subscriber,
 		notificationClients: make([]NotificationWebhookClient, 0),
+		deliveries:          deliveries,
 	}
 }
 
@@ -52,6 +55,14 @@ func (t *notificationWebhookHandler) notify() {
 		t.mtx.RLock()
 		for _, nc := range t.notificationClients {
 			err := nc.Notify(payload)
+			t.deliveries.record(Delivery{
+				MessageId:      payload.messageId,
+				SubscriptionId: nc.Subscriber().Id,
+				Topic:          msg.Topic(),
+				CallbackUrl:    nc.Subscriber().CallbackUrl,
+				Delivered:      err == nil,
+				SentAt:         time.Now(),
+			}, payload.Data)
 			if err != nil {
 				t.logger.WithFields(logrus.Fields{
 					"subId":     nc.Subscriber().Id,
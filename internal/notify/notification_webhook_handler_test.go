@@ -3,6 +3,7 @@ package notify
 import (
 	"faceit-backend-test/internal/pubsub"
 	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
 	"sync"
 	"testing"
 )
@@ -12,7 +13,7 @@ func TestNotificationWebhookHandler_notify(t *testing.T) {
 	logger := logrus.New()
 	subscriber := pubsub.NewSubscriber()
 
-	notifyHandler := newNotificationWebhookHandler(logger, subscriber)
+	notifyHandler := newNotificationWebhookHandler(logger, subscriber, newDeliveryLog(10))
 	t.Run("success", func(t *testing.T) {
 		var wg sync.WaitGroup
 		wg.Add(1)
@@ -28,7 +29,13 @@ func TestNotificationWebhookHandler_notify(t *testing.T) {
 		notifyHandler.addToSubscribers(mockClient)
 
 		subscriber.Signal(pubsub.NewMessage(5, "test"))
+		subscriber.Signal(pubsub.NewMessage(mockSubject{Id: "a"}, "test"))
 		subscriber.Destruct()
 		wg.Wait()
+
+		deliveries := notifyHandler.deliveries.bySubject("a")
+		assert.Len(t, deliveries, 1, "should record the deliveries about a subject")
+		assert.Equal(t, "test", deliveries[0].Topic)
+		assert.True(t, deliveries[0].Delivered)
 	})
 }
@@ -0,0 +1,58 @@
+// Package requestid identifies the requests, so that the logs and the records written while serving a request
+// can be correlated with it.
+package requestid
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Header is the header the request id is read from and written to.
+const Header = "X-Request-Id"
+
+// maxLength limits the length of the request ids given by the clients.
+const maxLength = 100
+
+type requestIdKey struct{}
+
+// NewMiddleware creates a gin middleware that stores the id of the request in the request context and returns it
+// in the response header. The id given by the client is kept, otherwise a new one is generated.
+func NewMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(Header)
+		if !valid(id) {
+			id = uuid.New().String()
+		}
+
+		ctx.Request = ctx.Request.WithContext(NewContext(ctx.Request.Context(), id))
+		ctx.Header(Header, id)
+		ctx.Next()
+	}
+}
+
+// valid reports whether the request id is not empty and only contains printable ASCII characters.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewContext returns a copy of the context carrying the request id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, id)
+}
+
+// FromContext returns the id of the request of the context, it is empty when the context has none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
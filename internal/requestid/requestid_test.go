@@ -0,0 +1,45 @@
+package requestid
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/", NewMiddleware(), func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, FromContext(ctx.Request.Context()))
+	})
+
+	t.Run("should keep the request id of the client", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodGet, "/", nil)
+		assert.NoError(t, err)
+		request.Header.Set(Header, "request-1")
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, "request-1", rr.Body.String())
+		assert.Equal(t, "request-1", rr.Header().Get(Header))
+	})
+
+	t.Run("should generate a request id when the client does not give a valid one", func(t *testing.T) {
+		for _, id := range []string{"", "request 1", strings.Repeat("r", maxLength+1)} {
+			request, err := http.NewRequest(http.MethodGet, "/", nil)
+			assert.NoError(t, err)
+			request.Header.Set(Header, id)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+
+			assert.NotEmpty(t, rr.Body.String(), id)
+			assert.NotEqual(t, id, rr.Body.String())
+			assert.Equal(t, rr.Body.String(), rr.Header().Get(Header))
+		}
+	})
+}
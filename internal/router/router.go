@@ -2,6 +2,7 @@ package router
 
 import (
 	_ "faceit-backend-test/docs"
+	"faceit-backend-test/internal/requestid"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -12,20 +13,32 @@ type Controller interface {
 	Register(group *gin.RouterGroup)
 }
 
+// AuthenticatedController is an interface for controllers having routes that require authentication,
+// these routes are registered to a group that the authentication middleware is applied.
+type AuthenticatedController interface {
+	RegisterAuthenticated(group *gin.RouterGroup)
+}
+
 // NewHTTPRouter creates a new router and registers all the routes
-func NewHTTPRouter(routes ...Controller) *gin.Engine {
+func NewHTTPRouter(authMiddleware gin.HandlerFunc, routes ...Controller) *gin.Engine {
 	router := gin.Default()
+	router.Use(requestid.NewMiddleware())
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	createApiV1(router, routes...)
+	createApiV1(router, authMiddleware, routes...)
 	return router
 }
 
-func createApiV1(router *gin.Engine, routes ...Controller) *gin.RouterGroup {
+func createApiV1(router *gin.Engine, authMiddleware gin.HandlerFunc, routes ...Controller) *gin.RouterGroup {
 	v1 := router.Group("v1")
+	authenticated := v1.Group("", authMiddleware)
 
 	for _, route := range routes {
 		route.Register(v1)
+
+		if authenticatedRoute, ok := route.(AuthenticatedController); ok {
+			authenticatedRoute.RegisterAuthenticated(authenticated)
+		}
 	}
 
 	return v1
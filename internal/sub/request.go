@@ -2,8 +2,9 @@ package sub
 
 // SubscribeRequest subscribe endpoint request model contains the subscription parameters
 // @Description subscribe endpoint request model contains the subscription parameters
+// The types are the topics the users are published to, the updates are published to user.update.
 type SubscribeRequest struct {
-	Type     string `json:"type" binding:"required"`
+	Type     string `json:"type" binding:"required" enums:"user.created,user.update,user.deleted,user.email_verified,user.erased"`
 	Callback string `json:"callback" binding:"required"`
 	Secret   string `json:"secret"`
 }
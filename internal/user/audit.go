@@ -0,0 +1,246 @@
+package user
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"faceit-backend-test/internal/auth"
+	"faceit-backend-test/internal/requestid"
+	"fmt"
+	"time"
+)
+
+const (
+	AuditActionCreated       = "created"
+	AuditActionUpdated       = "updated"
+	AuditActionDeleted       = "deleted"
+	AuditActionRestored      = "restored"
+	AuditActionEmailVerified = "email_verified"
+	AuditActionPasswordReset = "password_reset"
+	AuditActionErased        = "erased"
+)
+
+// AuditEntity is an entry of the change history of a user, the entries are only appended.
+// The snapshot is the state of the user after the change, so the user can be reconstructed as of any time
+// after its first recorded change.
+type AuditEntity struct {
+	Id        int64         `db:"id"`
+	UserId    string        `db:"user_id"`
+	Action    string        `db:"action"`
+	Actor     string        `db:"actor"`
+	RequestId string        `db:"request_id"`
+	Changes   AuditChanges  `db:"changes"`
+	Snapshot  AuditSnapshot `db:"snapshot"`
+	CreatedAt time.Time     `db:"created_at"`
+}
+
+// FieldChange is the value of a field before and after a change, the values of the password are never recorded.
+// @Description value of a field before and after a change
+type FieldChange struct {
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+// AuditChanges are the changed fields of a user by their json names, it is stored as jsonb.
+type AuditChanges map[string]FieldChange
+
+func (c AuditChanges) Value() (driver.Value, error) {
+	b, err := json.Marshal(c)
+	// the value is returned as a string, since COPY would encode the bytes as bytea.
+	return string(b), err
+}
+
+func (c *AuditChanges) Scan(src interface{}) error {
+	return scanJSON(src, c)
+}
+
+// AuditSnapshot is the state of a user after a change without its password, it is stored as jsonb.
+type AuditSnapshot struct {
+	User
+}
+
+func (s AuditSnapshot) Value() (driver.Value, error) {
+	b, err := json.Marshal(s)
+	return string(b), err
+}
+
+func (s *AuditSnapshot) Scan(src interface{}) error {
+	return scanJSON(src, s)
+}
+
+func scanJSON(src interface{}, dest interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	}
+
+	return fmt.Errorf("cannot scan %T as json", src)
+}
+
+// newAuditEntity returns the audit entry of the change, the actor and the request are taken from the context.
+func newAuditEntity(ctx context.Context, action string, user Entity, changes AuditChanges) AuditEntity {
+	actor, _ := auth.SubjectFromContext(ctx)
+
+	return AuditEntity{
+		UserId:    user.Id,
+		Action:    action,
+		Actor:     actor,
+		RequestId: requestid.FromContext(ctx),
+		Changes:   changes,
+		Snapshot: AuditSnapshot{User{
+			Id:              user.Id,
+			FirstName:       user.FirstName,
+			LastName:        user.LastName,
+			Nickname:        user.Nickname,
+			Email:           user.Email,
+			Country:         user.Country,
+			Version:         user.Version,
+			CreatedAt:       user.CreatedAt,
+			UpdatedAt:       user.UpdatedAt,
+			DeletedAt:       user.DeletedAt,
+			EmailVerifiedAt: user.EmailVerifiedAt,
+		}},
+	}
+}
+
+// detachedContext returns a new context carrying the actor and the request id of the context,
+// which is used by the changes outliving the request.
+func detachedContext(ctx context.Context) context.Context {
+	detached := requestid.NewContext(context.Background(), requestid.FromContext(ctx))
+	if actor, ok := auth.SubjectFromContext(ctx); ok {
+		detached = auth.NewContext(detached, actor)
+	}
+
+	return detached
+}
+
+// createdChanges returns the fields of a created user as changes without their old values.
+func createdChanges(user Entity) AuditChanges {
+	changes := auditChanges(Entity{}, user)
+	for field, change := range changes {
+		changes[field] = FieldChange{New: change.New}
+	}
+
+	return changes
+}
+
+// auditChanges returns the fields of the user having different values, like changedFields does,
+// and the password when its hash is changed.
+func auditChanges(previous Entity, current Entity) AuditChanges {
+	changes := AuditChanges{}
+
+	if previous.FirstName != current.FirstName {
+		changes["first_name"] = FieldChange{Old: previous.FirstName, New: current.FirstName}
+	}
+	if previous.LastName != current.LastName {
+		changes["last_name"] = FieldChange{Old: previous.LastName, New: current.LastName}
+	}
+	if previous.Nickname != current.Nickname {
+		changes["nickname"] = FieldChange{Old: previous.Nickname, New: current.Nickname}
+	}
+	if previous.Email != current.Email {
+		changes["email"] = FieldChange{Old: previous.Email, New: current.Email}
+	}
+	if previous.Country != current.Country {
+		changes["country"] = FieldChange{Old: previous.Country, New: current.Country}
+	}
+	if previous.Password != current.Password {
+		changes["password"] = FieldChange{}
+	}
+	if (previous.DeletedAt == nil) != (current.DeletedAt == nil) {
+		changes["deleted_at"] = FieldChange{Old: timeValue(previous.DeletedAt), New: timeValue(current.DeletedAt)}
+	}
+	if (previous.EmailVerifiedAt == nil) != (current.EmailVerifiedAt == nil) {
+		changes["email_verified_at"] = FieldChange{Old: timeValue(previous.EmailVerifiedAt), New: timeValue(current.EmailVerifiedAt)}
+	}
+
+	return changes
+}
+
+// timeValue returns the time of the nullable field as a value, so that a null time is omitted from the changes.
+func timeValue(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+
+	return *t
+}
+
+// audited applies the change by the repository of a transaction and appends its audit entry in the same transaction,
+// so that a change is never applied without being recorded. The previous state is not used by the creations.
+func (s *service) audited(ctx context.Context, action string, change func(repo Repository) (EntityChange, error)) (EntityChange, error) {
+	var result EntityChange
+
+	err := s.repo.Transaction(ctx, func(repo Repository) error {
+		var err error
+		result, err = change(repo)
+		if err != nil {
+			return err
+		}
+
+		var changes AuditChanges
+		switch action {
+		case AuditActionCreated:
+			changes = createdChanges(result.Entity)
+		case AuditActionPasswordReset:
+			// the previous state is not returned by the reset, only the password is changed anyway.
+			changes = AuditChanges{"password": FieldChange{}}
+		case AuditActionErased:
+			// the erased values are the personal data, so only the erased fields are recorded.
+			changes = auditChanges(result.Previous, result.Entity)
+			for field := range changes {
+				changes[field] = FieldChange{}
+			}
+		default:
+			changes = auditChanges(result.Previous, result.Entity)
+		}
+
+		return repo.AppendAudit(ctx, newAuditEntity(ctx, action, result.Entity, changes))
+	})
+
+	return result, err
+}
+
+// GetHistory returns the changes of the user, the latest first. The history is kept after the user is deleted.
+func (s *service) GetHistory(ctx context.Context, request GetUserHistoryRequest) (UserHistoryResponse, error) {
+	entities, err := s.repo.GetHistory(ctx, request.Id, request.PerPage, (request.Page-1)*request.PerPage)
+	if err != nil {
+		return UserHistoryResponse{}, repositoryError(err)
+	}
+
+	entries := make([]AuditEntry, len(entities))
+	for i, entity := range entities {
+		entries[i] = newAuditEntry(entity)
+	}
+
+	return UserHistoryResponse{Entries: entries, Page: request.Page, PerPage: request.PerPage}, nil
+}
+
+func newAuditEntry(entity AuditEntity) AuditEntry {
+	return AuditEntry{
+		Id:        entity.Id,
+		UserId:    entity.UserId,
+		Action:    entity.Action,
+		Actor:     entity.Actor,
+		RequestId: entity.RequestId,
+		Changes:   entity.Changes,
+		Version:   entity.Snapshot.Version,
+		CreatedAt: entity.CreatedAt,
+	}
+}
+
+// GetSnapshot reconstructs the user as of the time from the latest change recorded until then.
+func (s *service) GetSnapshot(ctx context.Context, request GetUserSnapshotRequest) (UserSnapshotResponse, error) {
+	entity, err := s.repo.GetSnapshot(ctx, request.Id, request.At)
+	if errors.Is(err, ErrNotFound) {
+		return UserSnapshotResponse{}, userSnapshotNotFoundError(request.Id)
+	}
+	if err != nil {
+		return UserSnapshotResponse{}, repositoryError(err)
+	}
+
+	return UserSnapshotResponse{User: entity.Snapshot.User, ChangedAt: entity.CreatedAt}, nil
+}
@@ -0,0 +1,206 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"faceit-backend-test/internal/apierr"
+	"faceit-backend-test/internal/auth"
+	"faceit-backend-test/internal/pubsub"
+	"faceit-backend-test/internal/requestid"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAuditChanges(t *testing.T) {
+	t.Run("should record the changed fields", func(t *testing.T) {
+		updated := e
+		updated.Country = "NL"
+		updated.Password = "another hash"
+
+		changes := auditChanges(e, updated)
+		assert.Equal(t, AuditChanges{
+			"country":  {Old: e.Country, New: "NL"},
+			"password": {},
+		}, changes, "should not record the values of the password")
+	})
+
+	t.Run("should record the fields of the created users without their old values", func(t *testing.T) {
+		changes := createdChanges(e)
+		assert.Equal(t, FieldChange{New: e.Nickname}, changes["nickname"])
+		assert.Equal(t, FieldChange{}, changes["password"])
+		assert.NotContains(t, changes, "deleted_at")
+	})
+
+	t.Run("should be stored as json", func(t *testing.T) {
+		changes := AuditChanges{"country": {Old: e.Country, New: "NL"}, "password": {}}
+
+		value, err := changes.Value()
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"country":{"old":"GB","new":"NL"},"password":{}}`, value.(string))
+
+		var scanned AuditChanges
+		assert.NoError(t, scanned.Scan([]byte(value.(string))))
+		assert.Equal(t, changes, scanned)
+	})
+}
+
+func TestService_Audited(t *testing.T) {
+	deleted := e
+	deletedAt := time.Now()
+	deleted.DeletedAt = &deletedAt
+
+	ctx := auth.NewContext(requestid.NewContext(context.Background(), "request"), "admin")
+
+	t.Run("should append the change in the same transaction", func(t *testing.T) {
+		var audited []AuditEntity
+
+		mockRepo := &mockRepository{}
+		mockRepo.transactionMock = func(ctx context.Context, fn func(Repository) error) error {
+			err := fn(mockRepo)
+			assert.Len(t, audited, 1, "should append the entry before the commit")
+			return err
+		}
+		mockRepo.restoreMock = func(ctx context.Context, id string) (EntityChange, error) {
+			return EntityChange{Entity: e, Previous: deleted}, nil
+		}
+		mockRepo.appendAuditMock = func(ctx context.Context, entities ...AuditEntity) error {
+			audited = append(audited, entities...)
+			return nil
+		}
+
+		mockBroker := &pubsub.MockBroker{}
+		mockBroker.PublishMock = func(s string, i interface{}) {}
+
+		service := NewService(WithRepository(mockRepo), WithBroker(mockBroker))
+
+		_, err := service.Restore(ctx, RestoreUserRequest{Id: e.Id})
+		assert.NoError(t, err)
+		assert.Len(t, audited, 1)
+		assert.Equal(t, e.Id, audited[0].UserId)
+		assert.Equal(t, AuditActionRestored, audited[0].Action)
+		assert.Equal(t, "admin", audited[0].Actor)
+		assert.Equal(t, "request", audited[0].RequestId)
+		assert.Equal(t, AuditChanges{"deleted_at": {Old: deletedAt}}, audited[0].Changes)
+		assert.Equal(t, e.Version, audited[0].Snapshot.Version)
+		assert.Nil(t, audited[0].Snapshot.DeletedAt)
+
+		b, err := json.Marshal(audited[0].Snapshot)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(b), e.Password, "should not record the password")
+	})
+
+	t.Run("should not apply the change when it cannot be recorded", func(t *testing.T) {
+		expectedErr := fmt.Errorf("mock error")
+
+		mockRepo := &mockRepository{}
+		mockRepo.restoreMock = func(ctx context.Context, id string) (EntityChange, error) {
+			return EntityChange{Entity: e, Previous: deleted}, nil
+		}
+		mockRepo.appendAuditMock = func(ctx context.Context, entities ...AuditEntity) error {
+			return expectedErr
+		}
+
+		mockBroker := &pubsub.MockBroker{}
+		mockBroker.PublishMock = func(s string, i interface{}) {
+			assert.Fail(t, "should not publish the unrecorded changes")
+		}
+
+		service := NewService(WithRepository(mockRepo), WithBroker(mockBroker))
+
+		_, err := service.Restore(ctx, RestoreUserRequest{Id: e.Id})
+		assert.ErrorIs(t, err, expectedErr)
+	})
+}
+
+func TestService_GetHistory(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		entity := AuditEntity{
+			Id:        7,
+			UserId:    e.Id,
+			Action:    AuditActionUpdated,
+			Actor:     "admin",
+			RequestId: "request",
+			Changes:   AuditChanges{"country": {Old: "NL", New: e.Country}},
+			Snapshot:  AuditSnapshot{User{Id: e.Id, Version: 3}},
+			CreatedAt: time.Now(),
+		}
+
+		mockRepo := &mockRepository{}
+		mockRepo.historyMock = func(ctx context.Context, userId string, limit int, offset int) ([]AuditEntity, error) {
+			assert.Equal(t, e.Id, userId)
+			assert.Equal(t, 10, limit)
+			assert.Equal(t, 10, offset)
+
+			return []AuditEntity{entity}, nil
+		}
+
+		service := NewService(WithRepository(mockRepo))
+
+		actual, err := service.GetHistory(context.Background(), GetUserHistoryRequest{Id: e.Id, Page: 2, PerPage: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, UserHistoryResponse{
+			Entries: []AuditEntry{{
+				Id:        entity.Id,
+				UserId:    e.Id,
+				Action:    AuditActionUpdated,
+				Actor:     "admin",
+				RequestId: "request",
+				Changes:   entity.Changes,
+				Version:   3,
+				CreatedAt: entity.CreatedAt,
+			}},
+			Page:    2,
+			PerPage: 10,
+		}, actual)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.historyMock = func(ctx context.Context, userId string, limit int, offset int) ([]AuditEntity, error) {
+			return nil, fmt.Errorf("mock error")
+		}
+
+		service := NewService(WithRepository(mockRepo))
+
+		_, err := service.GetHistory(context.Background(), GetUserHistoryRequest{Id: e.Id, Page: 1, PerPage: 10})
+		assert.IsType(t, apierr.ApiError{}, err)
+		assert.Equal(t, http.StatusInternalServerError, err.(apierr.ApiError).StatusCode)
+	})
+}
+
+func TestService_GetSnapshot(t *testing.T) {
+	at := time.Now()
+
+	t.Run("success", func(t *testing.T) {
+		changedAt := at.Add(-time.Hour)
+		snapshot := User{Id: e.Id, Nickname: e.Nickname, Version: 2}
+
+		mockRepo := &mockRepository{}
+		mockRepo.snapshotMock = func(ctx context.Context, userId string, time time.Time) (AuditEntity, error) {
+			assert.Equal(t, at, time)
+
+			return AuditEntity{UserId: userId, Snapshot: AuditSnapshot{snapshot}, CreatedAt: changedAt}, nil
+		}
+
+		service := NewService(WithRepository(mockRepo))
+
+		actual, err := service.GetSnapshot(context.Background(), GetUserSnapshotRequest{Id: e.Id, At: at})
+		assert.NoError(t, err)
+		assert.Equal(t, UserSnapshotResponse{User: snapshot, ChangedAt: changedAt}, actual)
+	})
+
+	t.Run("should return not found when there is no change until the time", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.snapshotMock = func(ctx context.Context, userId string, time time.Time) (AuditEntity, error) {
+			return AuditEntity{}, ErrNotFound
+		}
+
+		service := NewService(WithRepository(mockRepo))
+
+		_, err := service.GetSnapshot(context.Background(), GetUserSnapshotRequest{Id: e.Id, At: at})
+		assert.Equal(t, userSnapshotNotFoundError(e.Id), err)
+	})
+}
@@ -0,0 +1,81 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxNicknameSuggestions is how many available nicknames are suggested in place of a taken one.
+	maxNicknameSuggestions = 3
+	maxNicknameLength      = 30
+)
+
+// nicknameCandidates returns the nicknames which can be suggested in place of the taken one in the order of preference,
+// the numbered ones first and then the ones having random suffixes. The nickname is shortened if a candidate doesn't fit
+// into the nickname column.
+func nicknameCandidates(nickname string, random *rand.Rand) []string {
+	var candidates []string
+	add := func(suffix string) {
+		base := nickname
+		if len(base)+len(suffix) > maxNicknameLength {
+			base = base[:maxNicknameLength-len(suffix)]
+		}
+		candidates = append(candidates, base+suffix)
+	}
+
+	for i := 1; i <= 9; i++ {
+		add(strconv.Itoa(i))
+	}
+	for i := 0; i < maxNicknameSuggestions; i++ {
+		add(fmt.Sprintf("_%03d", random.Intn(1000)))
+	}
+
+	return candidates
+}
+
+// CheckNicknameAvailability reports whether the nickname is available, the available nicknames similar to it
+// are suggested when it is taken. The nicknames of the deleted users are not available until they are purged.
+func (s *service) CheckNicknameAvailability(ctx context.Context, request NicknameAvailabilityRequest) (NicknameAvailabilityResponse, error) {
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	candidates := nicknameCandidates(request.Nickname, random)
+
+	taken, err := s.repo.TakenNicknames(ctx, append([]string{request.Nickname}, candidates...))
+	if err != nil {
+		return NicknameAvailabilityResponse{}, repositoryError(err)
+	}
+
+	takenNicknames := make(map[string]bool, len(taken))
+	for _, nickname := range taken {
+		takenNicknames[nickname] = true
+	}
+
+	resp := NicknameAvailabilityResponse{Nickname: request.Nickname, Available: !takenNicknames[request.Nickname]}
+	if resp.Available {
+		return resp, nil
+	}
+
+	for _, candidate := range candidates {
+		if len(resp.Suggestions) == maxNicknameSuggestions {
+			break
+		}
+		if !takenNicknames[candidate] && !contains(resp.Suggestions, candidate) {
+			resp.Suggestions = append(resp.Suggestions, candidate)
+		}
+	}
+
+	return resp, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,237 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"faceit-backend-test/internal/apierr"
+	"faceit-backend-test/internal/mail"
+	"faceit-backend-test/internal/pubsub"
+	"fmt"
+	"net/http"
+)
+
+// errBatchFailed rolls back the transaction of an atomic batch when one of its operations fails.
+var errBatchFailed = errors.New("batch operation failed")
+
+type message struct {
+	topic string
+	msg   interface{}
+}
+
+// bufferedBroker keeps the published messages until they are flushed,
+// so that the events of a batch are only published once the transaction is committed.
+type bufferedBroker struct {
+	messages []message
+}
+
+var _ pubsub.Broker = (*bufferedBroker)(nil)
+
+func (b *bufferedBroker) Subscribe(s pubsub.Subscriber, topic string) {}
+
+func (b *bufferedBroker) Unsubscribe(s pubsub.Subscriber, topic string) {}
+
+func (b *bufferedBroker) RemoveSubscriber(s pubsub.Subscriber) {}
+
+func (b *bufferedBroker) Publish(topic string, msg interface{}) {
+	b.messages = append(b.messages, message{topic: topic, msg: msg})
+}
+
+func (b *bufferedBroker) flush(broker pubsub.Broker) {
+	for _, m := range b.messages {
+		broker.Publish(m.topic, m.msg)
+	}
+	b.messages = nil
+}
+
+// bufferedMailer keeps the sent mails until they are flushed,
+// so that the verification mails of a batch are only sent once the transaction is committed.
+type bufferedMailer struct {
+	messages []mail.Message
+}
+
+var _ mail.Sender = (*bufferedMailer)(nil)
+
+func (m *bufferedMailer) Send(ctx context.Context, message mail.Message) error {
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+// flush sends the mails by the mailer, the failures are ignored like they are out of the batches.
+func (m *bufferedMailer) flush(ctx context.Context, mailer mail.Sender) {
+	if mailer != nil {
+		for _, message := range m.messages {
+			_ = mailer.Send(ctx, message)
+		}
+	}
+	m.messages = nil
+}
+
+// Batch executes the operations in a transaction and returns their results in the same order.
+// The operations are validated and their passwords are hashed before the transaction starts to keep it short,
+// and the events of the applied operations are published after the transaction is committed.
+func (s *service) Batch(ctx context.Context, request BatchUsersRequest) (BatchUsersResponse, error) {
+	if err := validateBatch(request); err != nil {
+		return BatchUsersResponse{}, err
+	}
+
+	entities, err := s.batchEntities(request.Operations)
+	if err != nil {
+		return BatchUsersResponse{}, err
+	}
+
+	results := make([]BatchOperationResult, len(request.Operations))
+	events := &bufferedBroker{}
+	mails := &bufferedMailer{}
+
+	err = s.repo.Transaction(ctx, func(repo Repository) error {
+		for i, op := range request.Operations {
+			if request.Mode == BatchModePartial {
+				// every operation is run in a savepoint, so that the failed ones are rolled back alone.
+				opEvents := &bufferedBroker{}
+				opMails := &bufferedMailer{}
+				err := repo.Transaction(ctx, func(repo Repository) error {
+					results[i] = s.batchOperation(ctx, repo, opEvents, opMails, op, entities[i])
+					if results[i].Error != nil {
+						return *results[i].Error
+					}
+					return nil
+				})
+				if err == nil {
+					events.messages = append(events.messages, opEvents.messages...)
+					mails.messages = append(mails.messages, opMails.messages...)
+				}
+				continue
+			}
+
+			results[i] = s.batchOperation(ctx, repo, events, mails, op, entities[i])
+			if results[i].Error != nil {
+				// the applied operations are rolled back and the following ones are not executed.
+				for j := range results {
+					if j != i {
+						rolledBack := batchRolledBackError(i)
+						results[j] = BatchOperationResult{Status: rolledBack.StatusCode, Id: request.Operations[j].Id, Error: &rolledBack}
+					}
+				}
+				return errBatchFailed
+			}
+		}
+
+		return nil
+	})
+	if errors.Is(err, errBatchFailed) {
+		return BatchUsersResponse{Committed: false, Results: results}, nil
+	}
+	if err != nil {
+		return BatchUsersResponse{}, repositoryError(err)
+	}
+
+	events.flush(s.broker)
+	mails.flush(ctx, s.mailer)
+
+	return BatchUsersResponse{Committed: true, Results: results}, nil
+}
+
+// validateBatch checks that every operation has what it needs, so that an invalid batch is rejected
+// before any password is hashed.
+func validateBatch(request BatchUsersRequest) error {
+	for i, op := range request.Operations {
+		switch op.Op {
+		case BatchOpCreate, BatchOpUpdate, BatchOpDelete:
+		default:
+			return apierr.BadRequest(fmt.Sprintf("unknown operation %s of operations[%d]", op.Op, i))
+		}
+
+		if op.Op != BatchOpDelete && op.User == nil {
+			return apierr.BadRequest(fmt.Sprintf("the user of operations[%d] is required", i))
+		}
+		if op.Op != BatchOpCreate && op.Id == "" {
+			return apierr.BadRequest(fmt.Sprintf("the id of operations[%d] is required", i))
+		}
+	}
+
+	return nil
+}
+
+// batchEntities creates the entities of the operations, the passwords are hashed in parallel
+// since hashing them one by one would take seconds for the largest batches.
+func (s *service) batchEntities(operations []BatchOperation) ([]Entity, error) {
+	var indexes []int
+	var passwords []string
+	for i, op := range operations {
+		if op.Op != BatchOpDelete {
+			indexes = append(indexes, i)
+			passwords = append(passwords, op.User.Password)
+		}
+	}
+
+	hashes, errs := hashPasswords(s.hasher, passwords)
+	for _, err := range errs {
+		if err != nil {
+			return nil, passwordHashError()
+		}
+	}
+
+	entities := make([]Entity, len(operations))
+	for n, i := range indexes {
+		op := operations[i]
+		entities[i] = Entity{
+			Id:        op.Id,
+			FirstName: op.User.FirstName,
+			LastName:  op.User.LastName,
+			Nickname:  op.User.Nickname,
+			Password:  hashes[n],
+			Email:     op.User.Email,
+			Country:   op.User.Country,
+			Version:   op.Version,
+		}
+	}
+
+	return entities, nil
+}
+
+// withTransaction returns a copy of the service that uses the repository, the broker and the mailer
+// of a transaction, the rest of its configuration is kept.
+func (s *service) withTransaction(repo Repository, broker pubsub.Broker, mailer mail.Sender) *service {
+	txService := *s
+	txService.repo = repo
+	txService.broker = broker
+	txService.mailer = mailer
+
+	return &txService
+}
+
+// batchOperation applies the operation by the repository of the transaction, the events are published to the broker
+// and the mails are sent by the mailer.
+func (s *service) batchOperation(ctx context.Context, repo Repository, broker pubsub.Broker, mailer mail.Sender, op BatchOperation, entity Entity) BatchOperationResult {
+	txService := s.withTransaction(repo, broker, mailer)
+
+	var result BatchOperationResult
+	var err error
+
+	switch op.Op {
+	case BatchOpCreate:
+		var resp CreateUserResponse
+		resp, err = txService.create(ctx, entity)
+		result = BatchOperationResult{Status: http.StatusCreated, Id: resp.Id, User: &resp.User}
+	case BatchOpUpdate:
+		var resp UpdateUserResponse
+		resp, err = txService.update(ctx, entity)
+		result = BatchOperationResult{Status: http.StatusOK, Id: resp.Id, User: &resp.User}
+	case BatchOpDelete:
+		var resp DeleteUserResponse
+		resp, err = txService.DeleteById(ctx, DeleteUserByIdRequest{Id: op.Id, Version: op.Version})
+		result = BatchOperationResult{Status: http.StatusOK, Id: resp.Id}
+	default:
+		err = apierr.BadRequest("unknown operation " + op.Op)
+	}
+
+	if err != nil {
+		apiErr, ok := err.(apierr.ApiError)
+		if !ok {
+			apiErr = repositoryError(err)
+		}
+		return BatchOperationResult{Status: apiErr.StatusCode, Id: op.Id, Error: &apiErr}
+	}
+
+	return result
+}
@@ -2,43 +2,79 @@ package user
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	_ "faceit-backend-test/docs"
 	"faceit-backend-test/internal/apierr"
+	"faceit-backend-test/internal/auth"
 	"faceit-backend-test/internal/router"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 )
 
 const (
 	defaultPage    = 1
 	defaultPerPage = 10
+	maxPerPage     = 100
 )
 const route = "/users"
 
 type Service interface {
 	Create(ctx context.Context, request CreateUserRequest) (CreateUserResponse, error)
 	Update(ctx context.Context, request UpdateUserRequest) (UpdateUserResponse, error)
+	Patch(ctx context.Context, request PatchUserRequest) (UpdateUserResponse, error)
 	DeleteById(ctx context.Context, request DeleteUserByIdRequest) (DeleteUserResponse, error)
+	Restore(ctx context.Context, request RestoreUserRequest) (RestoreUserResponse, error)
+	SendVerification(ctx context.Context, request SendVerificationRequest) (VerificationResponse, error)
+	VerifyEmail(ctx context.Context, request VerifyEmailRequest) (VerifyEmailResponse, error)
+	RequestPasswordReset(ctx context.Context, request PasswordResetRequest) error
+	ConfirmPasswordReset(ctx context.Context, request ConfirmPasswordResetRequest) error
 	GetMany(ctx context.Context, request GetUsersManyRequest) (GetUsersManyResponse, error)
+	Search(ctx context.Context, request SearchUsersRequest) (SearchUsersResponse, error)
+	CheckNicknameAvailability(ctx context.Context, request NicknameAvailabilityRequest) (NicknameAvailabilityResponse, error)
+	Batch(ctx context.Context, request BatchUsersRequest) (BatchUsersResponse, error)
+	Import(ctx context.Context, request ImportUsersRequest) (ImportJobResponse, error)
+	GetImport(ctx context.Context, request GetImportRequest) (ImportJobResponse, error)
+	GetImportErrors(ctx context.Context, request GetImportRequest) (ImportErrorsResponse, error)
+	Export(ctx context.Context, request ExportUsersRequest, w io.Writer) error
+	GetById(ctx context.Context, request GetUserByIdRequest) (GetUserResponse, error)
+	GetHistory(ctx context.Context, request GetUserHistoryRequest) (UserHistoryResponse, error)
+	GetSnapshot(ctx context.Context, request GetUserSnapshotRequest) (UserSnapshotResponse, error)
+	ExportPersonalData(ctx context.Context, request GetPersonalDataRequest) (PersonalDataResponse, error)
+	Erase(ctx context.Context, request EraseUserRequest) (EraseUserResponse, error)
 }
 
 // controller it handles the operations related to users
 // @tag.name UserController
 type controller struct {
 	service Service
+	// identify stores the subject of the access token in the context on the public routes, if there is one.
+	identify gin.HandlerFunc
+	// idempotent replays the stored responses of the mutating routes to the retries made with the same idempotency key.
+	idempotent gin.HandlerFunc
+	// maxImportSize limits the size of the uploads, since they are stored in temporary files until they are imported.
+	maxImportSize int64
 }
 
 var _ router.Controller = (*controller)(nil)
+var _ router.AuthenticatedController = (*controller)(nil)
 
 type ControllerOpts func(*controller)
 
 // NewController create new instance of user.Controller with options
 func NewController(opts ...ControllerOpts) *controller {
-	c := &controller{}
+	c := &controller{
+		identify:      func(ctx *gin.Context) {},
+		idempotent:    func(ctx *gin.Context) {},
+		maxImportSize: defaultMaxImportSize,
+	}
 
 	for _, opt := range opts {
 		opt(c)
@@ -52,30 +88,126 @@ func WithService(service Service) ControllerOpts {
 	}
 }
 
+func WithIdentityMiddleware(identify gin.HandlerFunc) ControllerOpts {
+	return func(controller *controller) {
+		controller.identify = identify
+	}
+}
+
+func WithIdempotencyMiddleware(idempotent gin.HandlerFunc) ControllerOpts {
+	return func(controller *controller) {
+		controller.idempotent = idempotent
+	}
+}
+
+// WithMaxImportSize sets the largest upload accepted by the imports in bytes.
+func WithMaxImportSize(size int64) ControllerOpts {
+	return func(controller *controller) {
+		controller.maxImportSize = size
+	}
+}
+
 // Register it registers the routes and handlers
 // to the router group passed as an argument.
 func (c *controller) Register(r *gin.RouterGroup) {
-	r.GET(route, c.GetUsersMany)
-	r.POST(route, c.CreateUser)
-	r.PATCH(fmt.Sprintf("%v/:id", route), c.UpdateUser)
-	r.DELETE(fmt.Sprintf("%v/:id", route), c.DeleteUserById)
+	r.GET(route, c.identify, c.GetUsersMany)
+	r.GET(fmt.Sprintf("%v/search", route), c.SearchUsers)
+	r.GET(fmt.Sprintf("%v/availability", route), c.CheckNicknameAvailability)
+	r.POST(fmt.Sprintf("%v/sign-up", route), c.SignUp)
+	r.POST(fmt.Sprintf("%v/verification", route), c.VerifyEmail)
+	r.POST(fmt.Sprintf("%v/password-reset", route), c.RequestPasswordReset)
+	r.POST(fmt.Sprintf("%v/password-reset/confirm", route), c.ConfirmPasswordReset)
+	r.GET(fmt.Sprintf("%v/:id", route), c.GetUserById)
+}
+
+// RegisterAuthenticated it registers the routes and handlers
+// that require an access token to the router group passed as an argument.
+func (c *controller) RegisterAuthenticated(r *gin.RouterGroup) {
+	r.GET(fmt.Sprintf("%v/me", route), c.GetMe)
+	// the users can only manage themselves, the admins can manage all the users.
+	r.POST(route, c.authorizeAdmin, c.idempotent, c.CreateUser)
+	r.PUT(fmt.Sprintf("%v/:id", route), c.authorizeOwner, c.UpdateUser)
+	r.PATCH(fmt.Sprintf("%v/:id", route), c.authorizeOwner, c.idempotent, c.PatchUser)
+	r.DELETE(fmt.Sprintf("%v/:id", route), c.authorizeOwner, c.idempotent, c.DeleteUserById)
+	r.POST(fmt.Sprintf("%v/:id/restore", route), c.authorizeOwner, c.idempotent, c.RestoreUser)
+	r.POST(fmt.Sprintf("%v/:id/verification", route), c.authorizeOwner, c.idempotent, c.SendVerification)
+	r.GET(fmt.Sprintf("%v/:id/history", route), c.authorizeOwner, c.GetUserHistory)
+	r.GET(fmt.Sprintf("%v/:id/history/snapshot", route), c.authorizeOwner, c.GetUserSnapshot)
+	r.GET(fmt.Sprintf("%v/:id/personal-data", route), c.authorizeOwner, c.GetPersonalData)
+	r.POST(fmt.Sprintf("%v/:id/erase", route), c.authorizeOwner, c.idempotent, c.EraseUser)
+	// the custom methods are registered as a parameter since the router does not allow a literal after the route.
+	r.POST(fmt.Sprintf("%v:operation", route), c.authorizeAdmin, c.idempotentCustomMethod, c.customMethod)
+	r.GET(fmt.Sprintf("%v/export", route), c.authorizeAdmin, c.ExportUsers)
+	r.GET(fmt.Sprintf("%v/imports/:id", route), c.authorizeAdmin, c.GetImport)
+	r.GET(fmt.Sprintf("%v/imports/:id/errors", route), c.authorizeAdmin, c.GetImportErrors)
+}
+
+// authorizeOwner rejects the requests about another user than the subject of the access token, unless it is an admin.
+func (c *controller) authorizeOwner(ctx *gin.Context) {
+	if subject, _ := auth.Subject(ctx); subject != ctx.Param("id") && !auth.IsAdmin(ctx) {
+		c.decodeError(ctx, apierr.Forbidden("the users can only be managed by themselves or by the admins"))
+		ctx.Abort()
+	}
+}
+
+// authorizeAdmin rejects the requests not made by an admin.
+func (c *controller) authorizeAdmin(ctx *gin.Context) {
+	if !auth.IsAdmin(ctx) {
+		c.decodeError(ctx, apierr.Forbidden("only the admins are allowed"))
+		ctx.Abort()
+	}
+}
+
+// idempotentCustomMethod applies the idempotency middleware to the custom methods but the import, since its uploads
+// are streamed and too large to be read in memory to be compared with the retries.
+func (c *controller) idempotentCustomMethod(ctx *gin.Context) {
+	if ctx.Param("operation") == ":import" {
+		return
+	}
+	c.idempotent(ctx)
+}
+
+// customMethod dispatches the request to the handler of the custom method in the path.
+func (c *controller) customMethod(ctx *gin.Context) {
+	switch ctx.Param("operation") {
+	case ":batch":
+		c.BatchUsers(ctx)
+	case ":import":
+		c.ImportUsers(ctx)
+	default:
+		c.decodeError(ctx, apierr.NotFound(fmt.Sprintf("unknown method %s", ctx.Param("operation"))))
+	}
 }
 
 // CreateUser godoc
-// @Summary creates a user
+// @Summary creates a user, only the admins are allowed
 // @tags UserController
 // @Accept json
 // @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "key of the request, the retries made with the same key are answered with the first response"
 // @Param CreateUserRequest body CreateUserRequest true "user details"
+// @Param fields query string false "comma separated fields of the user that are returned, all the fields are returned by default" example(id,nickname)
 // @Success 200 {object} CreateUserResponse
+// @Header 200 {string} ETag "entity tag of the user"
 // @Failure 400 {object} apierr.ApiError
+// @Failure 401 {object} apierr.ApiError
+// @Failure 403 {object} apierr.ApiError
+// @Failure 409 {object} apierr.ApiError
+// @Failure 422 {object} apierr.ApiError
 // @Failure 500 {object} apierr.ApiError
 // @Router /v1/users [post]
 func (c *controller) CreateUser(ctx *gin.Context) {
 	var req CreateUserRequest
-	err := ctx.ShouldBindJSON(&req)
+	err := bindJSON(ctx, &req)
 	if err != nil {
-		c.decodeError(ctx, apierr.BadRequest(err.Error()))
+		c.decodeError(ctx, bindingError(err))
+		return
+	}
+
+	fields, err := parseFields(ctx.Query("fields"))
+	if err != nil {
+		c.decodeError(ctx, err)
 		return
 	}
 
@@ -85,25 +217,67 @@ func (c *controller) CreateUser(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, resp)
+	ctx.Header(headerETag, etag(resp.Version))
+	c.render(ctx, http.StatusCreated, resp, fields)
+}
+
+// SignUp godoc
+// @Summary creates a user signing up by itself
+// @Description the user logs in with POST /v1/auth/login afterwards, a verification mail is sent to its email.
+// @tags UserController
+// @Accept json
+// @Produce json
+// @Param CreateUserRequest body CreateUserRequest true "user details"
+// @Param fields query string false "comma separated fields of the user that are returned, all the fields are returned by default" example(id,nickname)
+// @Success 200 {object} CreateUserResponse
+// @Header 200 {string} ETag "entity tag of the user"
+// @Failure 400 {object} apierr.ApiError
+// @Failure 409 {object} apierr.ApiError
+// @Failure 422 {object} apierr.ApiError
+// @Failure 500 {object} apierr.ApiError
+// @Router /v1/users/sign-up [post]
+func (c *controller) SignUp(ctx *gin.Context) {
+	c.CreateUser(ctx)
 }
 
 // UpdateUser godoc
-// @Summary updates a user having id provided in path param
+// @Summary replaces all the fields of the user having id provided in path param
 // @tags UserController
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path string true "id of the user"
+// @Param If-Match header string false "entity tag of the user, the update fails when the user has been modified"
 // @Param UpdateUserRequest body UpdateUserRequest true "user details"
+// @Param fields query string false "comma separated fields of the user that are returned, all the fields are returned by default" example(id,nickname)
 // @Success 200 {object} UpdateUserResponse
+// @Header 200 {string} ETag "entity tag of the user"
 // @Failure 400 {object} apierr.ApiError
+// @Failure 401 {object} apierr.ApiError
+// @Failure 403 {object} apierr.ApiError
+// @Failure 404 {object} apierr.ApiError
+// @Failure 412 {object} apierr.ApiError
+// @Failure 409 {object} apierr.ApiError
+// @Failure 422 {object} apierr.ApiError
 // @Failure 500 {object} apierr.ApiError
-// @Router /v1/users/{id} [patch]
+// @Router /v1/users/{id} [put]
 func (c *controller) UpdateUser(ctx *gin.Context) {
 	var req UpdateUserRequest
-	err := ctx.ShouldBindJSON(&req)
+	err := bindJSON(ctx, &req)
 	if err != nil {
-		c.decodeError(ctx, apierr.BadRequest(err.Error()))
+		c.decodeError(ctx, bindingError(err))
+		return
+	}
+
+	fields, err := parseFields(ctx.Query("fields"))
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	req.Version, err = parseIfMatch(ctx.GetHeader(headerIfMatch))
+	if err != nil {
+		c.decodeError(ctx, err)
 		return
 	}
 
@@ -114,7 +288,62 @@ func (c *controller) UpdateUser(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, resp)
+	ctx.Header(headerETag, etag(resp.Version))
+	c.render(ctx, http.StatusOK, resp, fields)
+}
+
+// PatchUser godoc
+// @Summary partially updates the user having id provided in path param, only the fields in the JSON merge patch are changed
+// @tags UserController
+// @Accept json
+// @Accept application/merge-patch+json
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "key of the request, the retries made with the same key are answered with the first response"
+// @Param id path string true "id of the user"
+// @Param If-Match header string false "entity tag of the user, the update fails when the user has been modified"
+// @Param PatchUserRequest body PatchUserRequest true "user fields to change"
+// @Param fields query string false "comma separated fields of the user that are returned, all the fields are returned by default" example(id,nickname)
+// @Success 200 {object} UpdateUserResponse
+// @Header 200 {string} ETag "entity tag of the user"
+// @Failure 400 {object} apierr.ApiError
+// @Failure 401 {object} apierr.ApiError
+// @Failure 403 {object} apierr.ApiError
+// @Failure 404 {object} apierr.ApiError
+// @Failure 412 {object} apierr.ApiError
+// @Failure 409 {object} apierr.ApiError
+// @Failure 422 {object} apierr.ApiError
+// @Failure 500 {object} apierr.ApiError
+// @Router /v1/users/{id} [patch]
+func (c *controller) PatchUser(ctx *gin.Context) {
+	var req PatchUserRequest
+	err := bindJSON(ctx, &req)
+	if err != nil {
+		c.decodeError(ctx, bindingError(err))
+		return
+	}
+
+	fields, err := parseFields(ctx.Query("fields"))
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	req.Version, err = parseIfMatch(ctx.GetHeader(headerIfMatch))
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	req.Id = ctx.Param("id")
+	resp, err := c.service.Patch(ctx.Request.Context(), req)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	ctx.Header(headerETag, etag(resp.Version))
+	c.render(ctx, http.StatusOK, resp, fields)
 }
 
 // DeleteUserById godoc
@@ -122,9 +351,18 @@ func (c *controller) UpdateUser(ctx *gin.Context) {
 // @tags UserController
 // @Accept json
 // @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "key of the request, the retries made with the same key are answered with the first response"
 // @Param id path string true "id of the user"
+// @Param If-Match header string false "entity tag of the user, the deletion fails when the user has been modified"
 // @Success 200 {object} DeleteUserResponse
 // @Failure 400 {object} apierr.ApiError
+// @Failure 401 {object} apierr.ApiError
+// @Failure 403 {object} apierr.ApiError
+// @Failure 404 {object} apierr.ApiError
+// @Failure 412 {object} apierr.ApiError
+// @Failure 409 {object} apierr.ApiError
+// @Failure 422 {object} apierr.ApiError
 // @Failure 500 {object} apierr.ApiError
 // @Router /v1/users/{id} [delete]
 func (c *controller) DeleteUserById(ctx *gin.Context) {
@@ -135,6 +373,12 @@ func (c *controller) DeleteUserById(ctx *gin.Context) {
 		return
 	}
 
+	req.Version, err = parseIfMatch(ctx.GetHeader(headerIfMatch))
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
 	resp, err := c.service.DeleteById(ctx.Request.Context(), req)
 	if err != nil {
 		c.decodeError(ctx, err)
@@ -144,63 +388,879 @@ func (c *controller) DeleteUserById(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, resp)
 }
 
-// GetUsersMany godoc
-// @Summary returns the users with respect to the pagination and filter parameters
+// RestoreUser godoc
+// @Summary restores the soft deleted user having id provided in path param
 // @tags UserController
-// @Accept json
 // @Produce json
-// @Param page query int false "page number that will be returned" Default(1)
-// @Param perPage query int false "how many rows are returned by page" Default(10)
-// @Param filter query string false "filtering parameters that will be used while fetching the users" example({"country": "UK", "first_name": "Alisson"})
-// @Success 200 {object} GetUsersManyResponse
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "key of the request, the retries made with the same key are answered with the first response"
+// @Param id path string true "id of the user"
+// @Param fields query string false "comma separated fields of the user that are returned, all the fields are returned by default" example(id,nickname)
+// @Success 200 {object} RestoreUserResponse
+// @Header 200 {string} ETag "entity tag of the user"
 // @Failure 400 {object} apierr.ApiError
+// @Failure 401 {object} apierr.ApiError
+// @Failure 403 {object} apierr.ApiError
+// @Failure 404 {object} apierr.ApiError
+// @Failure 409 {object} apierr.ApiError
+// @Failure 422 {object} apierr.ApiError
 // @Failure 500 {object} apierr.ApiError
-// @Router /v1/users [get]
-func (c *controller) GetUsersMany(ctx *gin.Context) {
-	var req GetUsersManyRequest
-	var err error
-	req.Page = defaultPage
-	req.PerPage = defaultPerPage
+// @Router /v1/users/{id}/restore [post]
+func (c *controller) RestoreUser(ctx *gin.Context) {
+	var req RestoreUserRequest
+	err := ctx.ShouldBindUri(&req)
+	if err != nil {
+		c.decodeError(ctx, apierr.BadRequest(err.Error()))
+		return
+	}
 
-	if page := ctx.Query("page"); page != "" {
-		req.Page, err = strconv.Atoi(page)
-		if err != nil {
-			c.decodeError(ctx, apierr.BadRequest(err.Error()))
-			return
-		}
+	fields, err := parseFields(ctx.Query("fields"))
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
 	}
 
-	if perPage := ctx.Query("perPage"); perPage != "" {
-		req.PerPage, err = strconv.Atoi(perPage)
-		if err != nil {
-			c.decodeError(ctx, apierr.BadRequest(err.Error()))
-			return
-		}
+	resp, err := c.service.Restore(ctx.Request.Context(), req)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
 	}
 
-	if filter := ctx.Query("filter"); filter != "" {
-		decodedFilter, err := url.QueryUnescape(filter)
-		if err != nil {
-			c.decodeError(ctx, apierr.BadRequest(err.Error()))
-			return
-		}
+	ctx.Header(headerETag, etag(resp.Version))
+	c.render(ctx, http.StatusOK, resp, fields)
+}
 
-		err = json.Unmarshal([]byte(decodedFilter), &req.Filter)
-		if err != nil {
-			c.decodeError(ctx, apierr.BadRequest(err.Error()))
-			return
-		}
+// GetUserHistory godoc
+// @Summary returns the recorded changes of the user having id provided in path param, the latest first
+// @Description the history is kept after the user is deleted. the values of the password are never recorded.
+// @tags UserController
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "id of the user"
+// @Param page query int false "page number starting from 1" default(1)
+// @Param perPage query int false "number of the changes per page, at most 100" default(10)
+// @Success 200 {object} UserHistoryResponse
+// @Failure 400 {object} apierr.ApiError
+// @Failure 401 {object} apierr.ApiError
+// @Failure 403 {object} apierr.ApiError
+// @Failure 500 {object} apierr.ApiError
+// @Router /v1/users/{id}/history [get]
+func (c *controller) GetUserHistory(ctx *gin.Context) {
+	var req GetUserHistoryRequest
+	err := ctx.ShouldBindUri(&req)
+	if err != nil {
+		c.decodeError(ctx, apierr.BadRequest(err.Error()))
+		return
 	}
 
-	resp, err := c.service.GetMany(ctx, req)
+	req.Page, req.PerPage, err = c.parsePagination(ctx)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	resp, err := c.service.GetHistory(ctx.Request.Context(), req)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// GetUserSnapshot godoc
+// @Summary reconstructs the user having id provided in path param as of the time
+// @Description the user is reconstructed from the latest change recorded until the time,
+// @Description so it cannot be reconstructed before its first recorded change.
+// @tags UserController
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "id of the user"
+// @Param at query string true "RFC 3339 time the user is reconstructed as of" example(2022-09-01T10:00:00Z)
+// @Success 200 {object} UserSnapshotResponse
+// @Failure 400 {object} apierr.ApiError
+// @Failure 401 {object} apierr.ApiError
+// @Failure 403 {object} apierr.ApiError
+// @Failure 404 {object} apierr.ApiError
+// @Failure 500 {object} apierr.ApiError
+// @Router /v1/users/{id}/history/snapshot [get]
+func (c *controller) GetUserSnapshot(ctx *gin.Context) {
+	var req GetUserSnapshotRequest
+	err := ctx.ShouldBindUri(&req)
+	if err != nil {
+		c.decodeError(ctx, apierr.BadRequest(err.Error()))
+		return
+	}
+
+	req.At, err = time.Parse(time.RFC3339, ctx.Query("at"))
+	if err != nil {
+		c.decodeError(ctx, apierr.BadRequest(fmt.Sprintf("at must be an RFC 3339 time: %v", err)))
+		return
+	}
+	// the changes are recorded in UTC without a time zone, so the time is compared in UTC whatever its offset is.
+	req.At = req.At.UTC()
+
+	resp, err := c.service.GetSnapshot(ctx.Request.Context(), req)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// GetPersonalData godoc
+// @Summary downloads everything held about the user having id provided in path param
+// @Description the archive contains the user, its change history, its password reset tokens without the tokens,
+// @Description and the notifications sent about it together with the subscriptions they are sent to. Only the topic,
+// @Description the subject, the callback and the outcome of the notifications are kept, in memory. The deleted
+// @Description and the erased users can be downloaded as well. A failure after the archive has started aborts the
+// @Description connection.
+// @tags UserController
+// @Produce application/zip
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "id of the user"
+// @Success 200 {file} file "zip archive of user.json, history.json, password_reset_tokens.json, subscriptions.json and deliveries.json"
+// @Failure 400 {object} apierr.ApiError
+// @Failure 401 {object} apierr.ApiError
+// @Failure 403 {object} apierr.ApiError
+// @Failure 404 {object} apierr.ApiError
+// @Failure 500 {object} apierr.ApiError
+// @Router /v1/users/{id}/personal-data [get]
+func (c *controller) GetPersonalData(ctx *gin.Context) {
+	var req GetPersonalDataRequest
+	err := ctx.ShouldBindUri(&req)
+	if err != nil {
+		c.decodeError(ctx, apierr.BadRequest(err.Error()))
+		return
+	}
+
+	resp, err := c.service.ExportPersonalData(ctx.Request.Context(), req)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s.zip"`, req.Id))
+	ctx.Header("Content-Type", mimeZip)
+	ctx.Status(http.StatusOK)
+
+	err = writePersonalDataArchive(ctx.Writer, resp)
+	if err != nil {
+		// the status cannot be changed once the archive has started, so the connection is aborted
+		// for the client not to take the truncated archive for a complete one.
+		_ = ctx.Error(err)
+		abortConnection(ctx)
+	}
+}
+
+// EraseUser godoc
+// @Summary erases the personal data of the user having id provided in path param irreversibly
+// @Description the user is anonymized and deleted but its id is kept, its password reset tokens, its change history,
+// @Description its idempotency keys and the notifications kept about it are removed. The user.erased notification is sent with its id.
+// @tags UserController
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "key of the request, the retries made with the same key are answered with the first response"
+// @Param id path string true "id of the user"
+// @Success 200 {object} EraseUserResponse
+// @Failure 400 {object} apierr.ApiError
+// @Failure 401 {object} apierr.ApiError
+// @Failure 403 {object} apierr.ApiError
+// @Failure 404 {object} apierr.ApiError
+// @Failure 409 {object} apierr.ApiError
+// @Failure 422 {object} apierr.ApiError
+// @Failure 500 {object} apierr.ApiError
+// @Router /v1/users/{id}/erase [post]
+func (c *controller) EraseUser(ctx *gin.Context) {
+	var req EraseUserRequest
+	err := ctx.ShouldBindUri(&req)
+	if err != nil {
+		c.decodeError(ctx, apierr.BadRequest(err.Error()))
+		return
+	}
+
+	resp, err := c.service.Erase(ctx.Request.Context(), req)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// SendVerification godoc
+// @Summary mails the user having id provided in path param another link to verify its email
+// @Description the links sent before are still valid until they expire.
+// @tags UserController
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "key of the request, the retries made with the same key are answered with the first response"
+// @Param id path string true "id of the user"
+// @Success 202 {object} VerificationResponse
+// @Failure 400 {object} apierr.ApiError
+// @Failure 401 {object} apierr.ApiError
+// @Failure 403 {object} apierr.ApiError
+// @Failure 404 {object} apierr.ApiError
+// @Failure 409 {object} apierr.ApiError
+// @Failure 422 {object} apierr.ApiError
+// @Failure 500 {object} apierr.ApiError
+// @Failure 503 {object} apierr.ApiError
+// @Router /v1/users/{id}/verification [post]
+func (c *controller) SendVerification(ctx *gin.Context) {
+	var req SendVerificationRequest
+	err := ctx.ShouldBindUri(&req)
+	if err != nil {
+		c.decodeError(ctx, apierr.BadRequest(err.Error()))
+		return
+	}
+
+	resp, err := c.service.SendVerification(ctx.Request.Context(), req)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, resp)
+}
+
+// VerifyEmail godoc
+// @Summary verifies the email of the user by the token of the verification mail
+// @Description a token can be used once, and it is rejected once the user changes its email.
+// @tags UserController
+// @Accept json
+// @Produce json
+// @Param VerifyEmailRequest body VerifyEmailRequest true "token of the verification mail"
+// @Success 200 {object} VerifyEmailResponse
+// @Header 200 {string} ETag "entity tag of the user"
+// @Failure 400 {object} apierr.ApiError
+// @Failure 404 {object} apierr.ApiError
+// @Failure 409 {object} apierr.ApiError
+// @Failure 422 {object} apierr.ApiError
+// @Failure 500 {object} apierr.ApiError
+// @Router /v1/users/verification [post]
+func (c *controller) VerifyEmail(ctx *gin.Context) {
+	var req VerifyEmailRequest
+	err := bindJSON(ctx, &req)
+	if err != nil {
+		c.decodeError(ctx, bindingError(err))
+		return
+	}
+
+	resp, err := c.service.VerifyEmail(ctx.Request.Context(), req)
 	if err != nil {
 		c.decodeError(ctx, err)
 		return
 	}
 
+	ctx.Header(headerETag, etag(resp.Version))
 	ctx.JSON(http.StatusOK, resp)
 }
 
+// RequestPasswordReset godoc
+// @Summary mails the user having the email provided a link to reset its password
+// @Description the request is accepted whether the email belongs to a user or not, so it does not reveal the registered emails.
+// @tags UserController
+// @Accept json
+// @Param PasswordResetRequest body PasswordResetRequest true "email of the user"
+// @Success 202
+// @Failure 400 {object} apierr.ApiError
+// @Failure 422 {object} apierr.ApiError
+// @Failure 500 {object} apierr.ApiError
+// @Router /v1/users/password-reset [post]
+func (c *controller) RequestPasswordReset(ctx *gin.Context) {
+	var req PasswordResetRequest
+	err := bindJSON(ctx, &req)
+	if err != nil {
+		c.decodeError(ctx, bindingError(err))
+		return
+	}
+
+	err = c.service.RequestPasswordReset(ctx.Request.Context(), req)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+// ConfirmPasswordReset godoc
+// @Summary sets the password of the user by the token of the password reset mail
+// @Description a token can be used once, and the other tokens of the user cannot be used afterwards.
+// @tags UserController
+// @Accept json
+// @Param ConfirmPasswordResetRequest body ConfirmPasswordResetRequest true "token of the password reset mail and the new password"
+// @Success 204
+// @Failure 400 {object} apierr.ApiError
+// @Failure 422 {object} apierr.ApiError
+// @Failure 500 {object} apierr.ApiError
+// @Router /v1/users/password-reset/confirm [post]
+func (c *controller) ConfirmPasswordReset(ctx *gin.Context) {
+	var req ConfirmPasswordResetRequest
+	err := bindJSON(ctx, &req)
+	if err != nil {
+		c.decodeError(ctx, bindingError(err))
+		return
+	}
+
+	err = c.service.ConfirmPasswordReset(ctx.Request.Context(), req)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// BatchUsers godoc
+// @Summary creates, updates and deletes the users in a transaction
+// @Description the operations are applied in order and their results are returned in the same order.
+// @Description in the atomic mode, the default, none of the operations are applied if any of them fails,
+// @Description in the partial mode only the failed ones are not applied. The events are published after the commit.
+// @tags UserController
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "key of the request, the retries made with the same key are answered with the first response"
+// @Param BatchUsersRequest body BatchUsersRequest true "operations of the batch"
+// @Success 200 {object} BatchUsersResponse
+// @Failure 400 {object} apierr.ApiError
+// @Failure 401 {object} apierr.ApiError
+// @Failure 403 {object} apierr.ApiError
+// @Failure 404 {object} apierr.ApiError
+// @Failure 422 {object} apierr.ApiError
+// @Failure 409 {object} apierr.ApiError
+// @Failure 500 {object} apierr.ApiError
+// @Router /v1/users:batch [post]
+func (c *controller) BatchUsers(ctx *gin.Context) {
+	var req BatchUsersRequest
+	err := bindJSON(ctx, &req)
+	if err != nil {
+		c.decodeError(ctx, bindingError(err))
+		return
+	}
+
+	resp, err := c.service.Batch(ctx.Request.Context(), req)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// ImportUsers godoc
+// @Summary imports the users from a csv or ndjson upload in the background
+// @Description the upload is either the body itself or the file part of a multipart form, its format is resolved by the content type
+// @Description or the extension of the file. The rows are validated like the create requests and the valid ones are imported,
+// @Description the progress is returned by the import job in the Location header.
+// @tags UserController
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file false "csv or ndjson file of the users"
+// @Success 202 {object} ImportJobResponse
+// @Header 202 {string} Location "path of the import job"
+// @Failure 400 {object} apierr.ApiError
+// @Failure 401 {object} apierr.ApiError
+// @Failure 403 {object} apierr.ApiError
+// @Failure 413 {object} apierr.ApiError
+// @Failure 415 {object} apierr.ApiError
+// @Failure 409 {object} apierr.ApiError
+// @Failure 422 {object} apierr.ApiError
+// @Failure 500 {object} apierr.ApiError
+// @Router /v1/users:import [post]
+func (c *controller) ImportUsers(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, c.maxImportSize)
+
+	req, err := importUpload(ctx.Request)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	resp, err := c.service.Import(ctx.Request.Context(), req)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	ctx.Header("Location", fmt.Sprintf("%s/imports/%s", strings.TrimSuffix(ctx.Request.URL.Path, ":import"), resp.Id))
+	ctx.JSON(http.StatusAccepted, resp)
+}
+
+// ExportUsers godoc
+// @Summary exports the users as csv, ndjson or parquet
+// @Description the users are filtered and sorted the same as the listing, but they are not paginated.
+// @Description the format is given by the format query parameter or the Accept header, csv is the default.
+// @Description the export is streamed, a failure after the export has started is reported by the X-Export-Error trailer.
+// @tags UserController
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.apache.parquet
+// @Security BearerAuth
+// @Param format query string false "format of the export" Enums(csv, ndjson, parquet)
+// @Param filter query string false "filter users by the user fields in json format, filter[column][operator] query parameters are accepted as well"
+// @Param sort query string false "comma separated user fields the users are sorted by, prefixed with - for descending order" example(-created_at)
+// @Param fields query string false "comma separated fields of the users that are exported, all the fields are exported by default" example(id,nickname)
+// @Param includeDeleted query bool false "whether the soft deleted users are exported as well"
+// @Success 200 {file} file "users"
+// @Failure 400 {object} apierr.ApiError
+// @Failure 401 {object} apierr.ApiError
+// @Failure 403 {object} apierr.ApiError
+// @Failure 406 {object} apierr.ApiError
+// @Failure 500 {object} apierr.ApiError
+// @Router /v1/users/export [get]
+func (c *controller) ExportUsers(ctx *gin.Context) {
+	var req ExportUsersRequest
+	var err error
+
+	req.Format, err = exportFormat(ctx)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	req.Fields, err = parseFields(ctx.Query("fields"))
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	req.Sort, err = parseSort(ctx.Query("sort"))
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	if includeDeleted := ctx.Query("includeDeleted"); includeDeleted != "" {
+		req.IncludeDeleted, err = strconv.ParseBool(includeDeleted)
+		if err != nil {
+			c.decodeError(ctx, apierr.BadRequest(err.Error()))
+			return
+		}
+	}
+
+	req.Filter, err = c.parseFilter(ctx)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	w := &exportWriter{ctx: ctx, format: req.Format}
+	err = c.service.Export(ctx.Request.Context(), req, w)
+	if err != nil && !w.started {
+		c.decodeError(ctx, err)
+		return
+	}
+	if err != nil {
+		// the status cannot be changed once the export has started, so the failure is reported by the trailer.
+		ctx.Writer.Header().Set(headerExportError, err.Error())
+		_ = ctx.Error(err)
+		return
+	}
+
+	w.start()
+}
+
+// GetImport godoc
+// @Summary returns the progress of the import job
+// @tags UserController
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "id of the import job"
+// @Success 200 {object} ImportJobResponse
+// @Failure 400 {object} apierr.ApiError
+// @Failure 401 {object} apierr.ApiError
+// @Failure 403 {object} apierr.ApiError
+// @Failure 404 {object} apierr.ApiError
+// @Router /v1/users/imports/{id} [get]
+func (c *controller) GetImport(ctx *gin.Context) {
+	var req GetImportRequest
+	err := ctx.ShouldBindUri(&req)
+	if err != nil {
+		c.decodeError(ctx, apierr.BadRequest(err.Error()))
+		return
+	}
+
+	resp, err := c.service.GetImport(ctx.Request.Context(), req)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// GetImportErrors godoc
+// @Summary returns the rows of the import that have not been imported
+// @Description the report is a csv file with the row and the error columns unless json is accepted,
+// @Description the rows are numbered from the first one after the header for csv and by the lines for ndjson.
+// @tags UserController
+// @Produce text/csv
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "id of the import job"
+// @Success 200 {object} ImportErrorsResponse
+// @Failure 400 {object} apierr.ApiError
+// @Failure 401 {object} apierr.ApiError
+// @Failure 403 {object} apierr.ApiError
+// @Failure 404 {object} apierr.ApiError
+// @Router /v1/users/imports/{id}/errors [get]
+func (c *controller) GetImportErrors(ctx *gin.Context) {
+	var req GetImportRequest
+	err := ctx.ShouldBindUri(&req)
+	if err != nil {
+		c.decodeError(ctx, apierr.BadRequest(err.Error()))
+		return
+	}
+
+	resp, err := c.service.GetImportErrors(ctx.Request.Context(), req)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	if ctx.NegotiateFormat(mimeCSV, binding.MIMEJSON) == binding.MIMEJSON {
+		ctx.JSON(http.StatusOK, resp)
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="import-%s-errors.csv"`, req.Id))
+	ctx.Header("Content-Type", mimeCSV+"; charset=utf-8")
+	ctx.Status(http.StatusOK)
+
+	w := csv.NewWriter(ctx.Writer)
+	_ = w.Write([]string{"row", "error"})
+	for _, rowErr := range resp.Errors {
+		_ = w.Write([]string{strconv.Itoa(rowErr.Row), rowErr.Error})
+	}
+	w.Flush()
+}
+
+// SearchUsers godoc
+// @Summary searches the users by their names, nickname and email
+// @Description the users containing words starting with the query words or having words similar to the query are returned,
+// @Description the misspelled names are found by the similarity.
+// @tags UserController
+// @Produce json
+// @Param q query string true "search query" example(firmino)
+// @Param page query int false "page number that will be returned" Default(1)
+// @Param perPage query int false "how many rows are returned by page, at most 100" Default(10)
+// @Success 200 {object} SearchUsersResponse
+// @Failure 400 {object} apierr.ApiError
+// @Failure 500 {object} apierr.ApiError
+// @Router /v1/users/search [get]
+func (c *controller) SearchUsers(ctx *gin.Context) {
+	var req SearchUsersRequest
+	err := ctx.ShouldBindQuery(&req)
+	if err != nil {
+		c.decodeError(ctx, apierr.BadRequest(err.Error()))
+		return
+	}
+
+	req.Page, req.PerPage, err = c.parsePagination(ctx)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	resp, err := c.service.Search(ctx.Request.Context(), req)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// CheckNicknameAvailability godoc
+// @Summary checks whether the nickname is available
+// @Description the nicknames are compared by their canonical forms, which are trimmed, NFKC normalized and lower case.
+// @Description the available nicknames similar to the requested one are suggested when it is taken.
+// @tags UserController
+// @Produce json
+// @Param nickname query string true "nickname to check" example(firmino)
+// @Success 200 {object} NicknameAvailabilityResponse
+// @Failure 400 {object} apierr.ApiError
+// @Failure 422 {object} apierr.ApiError
+// @Failure 500 {object} apierr.ApiError
+// @Router /v1/users/availability [get]
+func (c *controller) CheckNicknameAvailability(ctx *gin.Context) {
+	var req NicknameAvailabilityRequest
+	err := bindQuery(ctx, &req)
+	if err != nil {
+		c.decodeError(ctx, bindingError(err))
+		return
+	}
+
+	resp, err := c.service.CheckNicknameAvailability(ctx.Request.Context(), req)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// GetUserById godoc
+// @Summary returns the user having id provided in path param
+// @tags UserController
+// @Produce json
+// @Param id path string true "id of the user"
+// @Param If-None-Match header string false "entity tag of the user the client has"
+// @Param fields query string false "comma separated fields of the user that are returned, all the fields are returned by default" example(id,nickname)
+// @Success 200 {object} GetUserResponse
+// @Header 200 {string} ETag "entity tag of the user"
+// @Success 304 "the user has not been modified"
+// @Failure 400 {object} apierr.ApiError
+// @Failure 404 {object} apierr.ApiError
+// @Failure 500 {object} apierr.ApiError
+// @Router /v1/users/{id} [get]
+func (c *controller) GetUserById(ctx *gin.Context) {
+	var req GetUserByIdRequest
+	err := ctx.ShouldBindUri(&req)
+	if err != nil {
+		c.decodeError(ctx, apierr.BadRequest(err.Error()))
+		return
+	}
+
+	fields, err := parseFields(ctx.Query("fields"))
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	resp, err := c.service.GetById(ctx.Request.Context(), req)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	if c.notModified(ctx, resp.Version) {
+		return
+	}
+
+	c.render(ctx, http.StatusOK, resp, fields)
+}
+
+// GetMe godoc
+// @Summary returns the user the access token is issued for
+// @tags UserController
+// @Produce json
+// @Security BearerAuth
+// @Param If-None-Match header string false "entity tag of the user the client has"
+// @Param fields query string false "comma separated fields of the user that are returned, all the fields are returned by default" example(id,nickname)
+// @Success 200 {object} GetUserResponse
+// @Header 200 {string} ETag "entity tag of the user"
+// @Success 304 "the user has not been modified"
+// @Failure 401 {object} apierr.ApiError
+// @Failure 404 {object} apierr.ApiError
+// @Failure 500 {object} apierr.ApiError
+// @Router /v1/users/me [get]
+func (c *controller) GetMe(ctx *gin.Context) {
+	id, ok := auth.Subject(ctx)
+	if !ok {
+		c.decodeError(ctx, apierr.Unauthorized("missing access token subject"))
+		return
+	}
+
+	fields, err := parseFields(ctx.Query("fields"))
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	resp, err := c.service.GetById(ctx.Request.Context(), GetUserByIdRequest{Id: id})
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	if c.notModified(ctx, resp.Version) {
+		return
+	}
+
+	c.render(ctx, http.StatusOK, resp, fields)
+}
+
+// GetUsersMany godoc
+// @Summary returns the users with respect to the pagination and filter parameters
+// @tags UserController
+// @Accept json
+// @Produce json
+// @Param page query int false "page number that will be returned" Default(1)
+// @Param perPage query int false "how many rows are returned by page, at most 100" Default(10)
+// @Param cursor query string false "cursor returned as next or prev by the previous request with the same sort, page is ignored when it is given"
+// @Param sort query string false "comma separated columns the users are ordered by, prefixed with - for descending order" example(-updated_at,nickname) Default(created_at)
+// @Param skipTotal query bool false "whether counting the users is skipped, total and totalPages are not returned then" Default(false)
+// @Param filter query string false "equality filter as a json object, filter[column][operator]=value parameters can be used as well, see the readme" example({"country": "UK", "first_name": "Alisson"})
+// @Param fields query string false "comma separated fields of the users that are returned, all the fields are returned by default" example(id,nickname)
+// @Param includeDeleted query bool false "whether the soft deleted users are returned, requires the access token of an admin" Default(false)
+// @Success 200 {object} GetUsersManyResponse
+// @Header 200 {string} Link "links of the first, prev, next and last pages"
+// @Failure 400 {object} apierr.ApiError
+// @Failure 401 {object} apierr.ApiError
+// @Failure 403 {object} apierr.ApiError
+// @Failure 500 {object} apierr.ApiError
+// @Router /v1/users [get]
+func (c *controller) GetUsersMany(ctx *gin.Context) {
+	var req GetUsersManyRequest
+	var err error
+
+	req.Page, req.PerPage, err = c.parsePagination(ctx)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	req.Cursor = ctx.Query("cursor")
+
+	req.Fields, err = parseFields(ctx.Query("fields"))
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	req.Sort, err = parseSort(ctx.Query("sort"))
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	if skipTotal := ctx.Query("skipTotal"); skipTotal != "" {
+		req.SkipTotal, err = strconv.ParseBool(skipTotal)
+		if err != nil {
+			c.decodeError(ctx, apierr.BadRequest(err.Error()))
+			return
+		}
+	}
+
+	if includeDeleted := ctx.Query("includeDeleted"); includeDeleted != "" {
+		req.IncludeDeleted, err = strconv.ParseBool(includeDeleted)
+		if err != nil {
+			c.decodeError(ctx, apierr.BadRequest(err.Error()))
+			return
+		}
+
+		if _, ok := auth.Subject(ctx); req.IncludeDeleted && !ok {
+			c.decodeError(ctx, apierr.Unauthorized("an access token is required to include the deleted users"))
+			return
+		}
+		if req.IncludeDeleted && !auth.IsAdmin(ctx) {
+			c.decodeError(ctx, apierr.Forbidden("only the admins are allowed to include the deleted users"))
+			return
+		}
+	}
+
+	req.Filter, err = c.parseFilter(ctx)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	resp, err := c.service.GetMany(ctx, req)
+	if err != nil {
+		c.decodeError(ctx, err)
+		return
+	}
+
+	if link := linkHeader(*ctx.Request.URL, req, resp); link != "" {
+		ctx.Header(headerLink, link)
+	}
+	c.render(ctx, http.StatusOK, resp, req.Fields)
+}
+
+// parseFilter parses the filter given either as a json object by the filter query parameter,
+// or by the filter[column][operator] query parameters.
+func (c *controller) parseFilter(ctx *gin.Context) ([]FilterCondition, error) {
+	var filter []FilterCondition
+
+	if value := ctx.Query("filter"); value != "" {
+		decodedFilter, err := url.QueryUnescape(value)
+		if err != nil {
+			return nil, apierr.BadRequest(err.Error())
+		}
+
+		var filterUser User
+		err = json.Unmarshal([]byte(decodedFilter), &filterUser)
+		if err != nil {
+			return nil, apierr.BadRequest(err.Error())
+		}
+
+		filter, err = equalityFilter(filterUser)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	conditions, err := parseFilterQuery(ctx.Request.URL.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	return append(filter, conditions...), nil
+}
+
+// notModified sets the entity tag of the user and responds with http.StatusNotModified
+// when the client already has the same version of the user.
+func (c *controller) notModified(ctx *gin.Context, version int) bool {
+	tag := etag(version)
+	ctx.Header(headerETag, tag)
+
+	if matchesIfNoneMatch(ctx.GetHeader(headerIfNoneMatch), tag) {
+		ctx.Status(http.StatusNotModified)
+		return true
+	}
+
+	return false
+}
+
+// parsePagination parses the page and perPage query parameters falling back to their defaults,
+// the page starts from 1 and perPage cannot exceed maxPerPage.
+func (c *controller) parsePagination(ctx *gin.Context) (int, int, error) {
+	page, perPage := defaultPage, defaultPerPage
+	var err error
+
+	if value := ctx.Query("page"); value != "" {
+		page, err = strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, apierr.BadRequest(err.Error())
+		}
+	}
+
+	if page < 1 {
+		return 0, 0, apierr.BadRequest("page must be at least 1")
+	}
+
+	if value := ctx.Query("perPage"); value != "" {
+		perPage, err = strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, apierr.BadRequest(err.Error())
+		}
+	}
+
+	if perPage < 1 || perPage > maxPerPage {
+		return 0, 0, apierr.BadRequest(fmt.Sprintf("perPage must be between 1 and %d", maxPerPage))
+	}
+
+	return page, perPage, nil
+}
+
+// render writes the response, only the requested fields of the users are written if there are any.
+func (c *controller) render(ctx *gin.Context, status int, resp sparser, fields []string) {
+	if len(fields) == 0 {
+		ctx.JSON(status, resp)
+		return
+	}
+
+	ctx.JSON(status, resp.sparse(fields))
+}
+
 func (c *controller) decodeError(ctx *gin.Context, err error) {
 	apiError, ok := err.(apierr.ApiError)
 	if !ok {
@@ -1,24 +1,48 @@
 package user
 
 import (
+	"archive/zip"
 	"bytes"
 	"context"
 	"encoding/json"
 	"faceit-backend-test/internal/apierr"
+	"faceit-backend-test/internal/auth"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 type mockService struct {
-	createMock     func(context.Context, CreateUserRequest) (CreateUserResponse, error)
-	updateMock     func(context.Context, UpdateUserRequest) (UpdateUserResponse, error)
-	deleteByIdMock func(context.Context, DeleteUserByIdRequest) (DeleteUserResponse, error)
-	getManyMock    func(context.Context, GetUsersManyRequest) (GetUsersManyResponse, error)
+	createMock           func(context.Context, CreateUserRequest) (CreateUserResponse, error)
+	updateMock           func(context.Context, UpdateUserRequest) (UpdateUserResponse, error)
+	patchMock            func(context.Context, PatchUserRequest) (UpdateUserResponse, error)
+	deleteByIdMock       func(context.Context, DeleteUserByIdRequest) (DeleteUserResponse, error)
+	restoreMock          func(context.Context, RestoreUserRequest) (RestoreUserResponse, error)
+	getManyMock          func(context.Context, GetUsersManyRequest) (GetUsersManyResponse, error)
+	searchMock           func(context.Context, SearchUsersRequest) (SearchUsersResponse, error)
+	availabilityMock     func(context.Context, NicknameAvailabilityRequest) (NicknameAvailabilityResponse, error)
+	sendVerificationMock func(context.Context, SendVerificationRequest) (VerificationResponse, error)
+	verifyEmailMock      func(context.Context, VerifyEmailRequest) (VerifyEmailResponse, error)
+	requestResetMock     func(context.Context, PasswordResetRequest) error
+	confirmResetMock     func(context.Context, ConfirmPasswordResetRequest) error
+	batchMock            func(context.Context, BatchUsersRequest) (BatchUsersResponse, error)
+	importMock           func(context.Context, ImportUsersRequest) (ImportJobResponse, error)
+	getImportMock        func(context.Context, GetImportRequest) (ImportJobResponse, error)
+	importErrsMock       func(context.Context, GetImportRequest) (ImportErrorsResponse, error)
+	exportMock           func(context.Context, ExportUsersRequest, io.Writer) error
+	getByIdMock          func(context.Context, GetUserByIdRequest) (GetUserResponse, error)
+	historyMock          func(context.Context, GetUserHistoryRequest) (UserHistoryResponse, error)
+	snapshotMock         func(context.Context, GetUserSnapshotRequest) (UserSnapshotResponse, error)
+	personalDataMock     func(context.Context, GetPersonalDataRequest) (PersonalDataResponse, error)
+	eraseMock            func(context.Context, EraseUserRequest) (EraseUserResponse, error)
 }
 
 func (s *mockService) Create(ctx context.Context, request CreateUserRequest) (CreateUserResponse, error) {
@@ -29,14 +53,86 @@ func (s *mockService) Update(ctx context.Context, request UpdateUserRequest) (Up
 	return s.updateMock(ctx, request)
 }
 
+func (s *mockService) Patch(ctx context.Context, request PatchUserRequest) (UpdateUserResponse, error) {
+	return s.patchMock(ctx, request)
+}
+
 func (s *mockService) DeleteById(ctx context.Context, request DeleteUserByIdRequest) (DeleteUserResponse, error) {
 	return s.deleteByIdMock(ctx, request)
 }
 
+func (s *mockService) Restore(ctx context.Context, request RestoreUserRequest) (RestoreUserResponse, error) {
+	return s.restoreMock(ctx, request)
+}
+
 func (s *mockService) GetMany(ctx context.Context, request GetUsersManyRequest) (GetUsersManyResponse, error) {
 	return s.getManyMock(ctx, request)
 }
 
+func (s *mockService) Search(ctx context.Context, request SearchUsersRequest) (SearchUsersResponse, error) {
+	return s.searchMock(ctx, request)
+}
+
+func (s *mockService) CheckNicknameAvailability(ctx context.Context, request NicknameAvailabilityRequest) (NicknameAvailabilityResponse, error) {
+	return s.availabilityMock(ctx, request)
+}
+
+func (s *mockService) SendVerification(ctx context.Context, request SendVerificationRequest) (VerificationResponse, error) {
+	return s.sendVerificationMock(ctx, request)
+}
+
+func (s *mockService) VerifyEmail(ctx context.Context, request VerifyEmailRequest) (VerifyEmailResponse, error) {
+	return s.verifyEmailMock(ctx, request)
+}
+
+func (s *mockService) RequestPasswordReset(ctx context.Context, request PasswordResetRequest) error {
+	return s.requestResetMock(ctx, request)
+}
+
+func (s *mockService) ConfirmPasswordReset(ctx context.Context, request ConfirmPasswordResetRequest) error {
+	return s.confirmResetMock(ctx, request)
+}
+
+func (s *mockService) Batch(ctx context.Context, request BatchUsersRequest) (BatchUsersResponse, error) {
+	return s.batchMock(ctx, request)
+}
+
+func (s *mockService) Import(ctx context.Context, request ImportUsersRequest) (ImportJobResponse, error) {
+	return s.importMock(ctx, request)
+}
+
+func (s *mockService) GetImport(ctx context.Context, request GetImportRequest) (ImportJobResponse, error) {
+	return s.getImportMock(ctx, request)
+}
+
+func (s *mockService) GetImportErrors(ctx context.Context, request GetImportRequest) (ImportErrorsResponse, error) {
+	return s.importErrsMock(ctx, request)
+}
+
+func (s *mockService) Export(ctx context.Context, request ExportUsersRequest, w io.Writer) error {
+	return s.exportMock(ctx, request, w)
+}
+
+func (s *mockService) GetById(ctx context.Context, request GetUserByIdRequest) (GetUserResponse, error) {
+	return s.getByIdMock(ctx, request)
+}
+
+func (s *mockService) GetHistory(ctx context.Context, request GetUserHistoryRequest) (UserHistoryResponse, error) {
+	return s.historyMock(ctx, request)
+}
+
+func (s *mockService) GetSnapshot(ctx context.Context, request GetUserSnapshotRequest) (UserSnapshotResponse, error) {
+	return s.snapshotMock(ctx, request)
+}
+
+func (s *mockService) ExportPersonalData(ctx context.Context, request GetPersonalDataRequest) (PersonalDataResponse, error) {
+	return s.personalDataMock(ctx, request)
+}
+
+func (s *mockService) Erase(ctx context.Context, request EraseUserRequest) (EraseUserResponse, error) {
+	return s.eraseMock(ctx, request)
+}
+
 func TestController_Register(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
@@ -45,6 +141,71 @@ func TestController_Register(t *testing.T) {
 	t.Run("", func(t *testing.T) {
 		controller.Register(&router.RouterGroup)
 
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/users?page=s", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.NotEqual(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should allow the users to sign up without an access token", func(t *testing.T) {
+		mockService := &mockService{}
+		mockService.createMock = func(ctx context.Context, request CreateUserRequest) (CreateUserResponse, error) {
+			return CreateUserResponse{User{Id: e.Id, Nickname: request.Nickname}}, nil
+		}
+
+		controller := NewController(WithService(mockService))
+		router := gin.Default()
+		controller.Register(&router.RouterGroup)
+		controller.RegisterAuthenticated(authenticatedAs(&router.RouterGroup, e.Id))
+
+		reqBody, err := json.Marshal(CreateUserRequest{
+			FirstName: e.FirstName,
+			LastName:  e.LastName,
+			Nickname:  e.Nickname,
+			Password:  e.Password,
+			Email:     e.Email,
+			Country:   e.Country,
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/users/sign-up", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+}
+
+// testAdmin is the subject of the access tokens of an admin in the tests.
+const testAdmin = "admin"
+
+// authenticatedAs returns a group of the routes whose requests are authenticated as the subject unless they have
+// an access token, the subjects of the admins are authorized as admins.
+func authenticatedAs(group *gin.RouterGroup, subject string, admins ...string) *gin.RouterGroup {
+	key, _ := auth.NewSigningKey(auth.AlgorithmHS256, "secret", "")
+	tokens := auth.NewTokenManager(auth.WithSigningKey(key), auth.WithTokenTTL(time.Minute))
+	token, _ := tokens.Issue(subject)
+
+	return group.Group("", func(ctx *gin.Context) {
+		if ctx.GetHeader("Authorization") == "" {
+			ctx.Request.Header.Set("Authorization", "Bearer "+token.Value)
+		}
+	}, auth.NewMiddleware(tokens, auth.WithAdmins(admins...)))
+}
+
+func TestController_RegisterAuthenticated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller := NewController()
+
+	t.Run("", func(t *testing.T) {
+		controller.RegisterAuthenticated(&router.RouterGroup)
+
 		rr := httptest.NewRecorder()
 		request, err := http.NewRequest(http.MethodPost, "/users", nil)
 		assert.NoError(t, err)
@@ -53,6 +214,178 @@ func TestController_Register(t *testing.T) {
 
 		assert.NotEqual(t, http.StatusNotFound, rr.Code)
 	})
+
+	t.Run("should apply the idempotency middleware to the mutating routes", func(t *testing.T) {
+		var applied []string
+		controller := NewController(WithService(&mockService{}), WithIdempotencyMiddleware(func(ctx *gin.Context) {
+			applied = append(applied, ctx.Request.Method+" "+ctx.FullPath())
+			ctx.AbortWithStatus(http.StatusNoContent)
+		}))
+		router := gin.Default()
+		controller.RegisterAuthenticated(authenticatedAs(&router.RouterGroup, testAdmin, testAdmin))
+
+		for _, r := range []struct {
+			method string
+			target string
+		}{
+			{http.MethodPost, "/users"},
+			{http.MethodPatch, "/users/1"},
+			{http.MethodDelete, "/users/1"},
+			{http.MethodPost, "/users/1/restore"},
+			{http.MethodPost, "/users:batch"},
+		} {
+			request, err := http.NewRequest(r.method, r.target, nil)
+			assert.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusNoContent, rr.Code, r.target)
+		}
+
+		request, err := http.NewRequest(http.MethodPost, "/users:import", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.NotEqual(t, http.StatusNoContent, rr.Code, "should not apply it to the streamed imports")
+
+		assert.Equal(t, []string{
+			"POST /users",
+			"PATCH /users/:id",
+			"DELETE /users/:id",
+			"POST /users/:id/restore",
+			"POST /users:operation",
+		}, applied)
+	})
+
+	t.Run("should only allow the users to manage themselves", func(t *testing.T) {
+		mockService := &mockService{}
+		mockService.historyMock = func(ctx context.Context, request GetUserHistoryRequest) (UserHistoryResponse, error) {
+			return UserHistoryResponse{}, nil
+		}
+
+		controller := NewController(WithService(mockService))
+		router := gin.Default()
+		controller.RegisterAuthenticated(authenticatedAs(&router.RouterGroup, e.Id))
+
+		for _, r := range []struct {
+			method string
+			target string
+		}{
+			{http.MethodPut, "/users/" + entities[1].Id},
+			{http.MethodPatch, "/users/" + entities[1].Id},
+			{http.MethodDelete, "/users/" + entities[1].Id},
+			{http.MethodPost, "/users/" + entities[1].Id + "/restore"},
+			{http.MethodPost, "/users/" + entities[1].Id + "/verification"},
+			{http.MethodGet, "/users/" + entities[1].Id + "/history"},
+			{http.MethodGet, "/users/" + entities[1].Id + "/history/snapshot?at=2022-09-01T10:00:00Z"},
+			{http.MethodGet, "/users/" + entities[1].Id + "/personal-data"},
+			{http.MethodPost, "/users/" + entities[1].Id + "/erase"},
+		} {
+			request, err := http.NewRequest(r.method, r.target, nil)
+			assert.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusForbidden, rr.Code, "%s %s", r.method, r.target)
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users/"+e.Id+"/history", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code, "should allow the users to read their own history")
+	})
+
+	t.Run("should only allow the users and the admins to export and erase the personal data", func(t *testing.T) {
+		mockService := &mockService{}
+		mockService.personalDataMock = func(ctx context.Context, request GetPersonalDataRequest) (PersonalDataResponse, error) {
+			return PersonalDataResponse{User: User{Id: request.Id}}, nil
+		}
+		mockService.eraseMock = func(ctx context.Context, request EraseUserRequest) (EraseUserResponse, error) {
+			assert.NotEqual(t, entities[1].Id, request.Id, "should not erase another user")
+			return EraseUserResponse{Id: request.Id}, nil
+		}
+
+		for _, c := range []struct {
+			subject  string
+			id       string
+			expected int
+		}{
+			{e.Id, entities[1].Id, http.StatusForbidden},
+			{e.Id, e.Id, http.StatusOK},
+			{testAdmin, e.Id, http.StatusOK},
+		} {
+			controller := NewController(WithService(mockService))
+			router := gin.Default()
+			controller.RegisterAuthenticated(authenticatedAs(&router.RouterGroup, c.subject, testAdmin))
+
+			for _, method := range []string{http.MethodGet, http.MethodPost} {
+				target := "/users/" + c.id + "/personal-data"
+				if method == http.MethodPost {
+					target = "/users/" + c.id + "/erase"
+				}
+
+				request, err := http.NewRequest(method, target, nil)
+				assert.NoError(t, err)
+
+				rr := httptest.NewRecorder()
+				router.ServeHTTP(rr, request)
+
+				assert.Equal(t, c.expected, rr.Code, "%s %s as %s", method, target, c.subject)
+			}
+		}
+	})
+
+	t.Run("should allow the admins to manage the other users", func(t *testing.T) {
+		mockService := &mockService{}
+		mockService.historyMock = func(ctx context.Context, request GetUserHistoryRequest) (UserHistoryResponse, error) {
+			return UserHistoryResponse{}, nil
+		}
+
+		controller := NewController(WithService(mockService))
+		router := gin.Default()
+		controller.RegisterAuthenticated(authenticatedAs(&router.RouterGroup, testAdmin, testAdmin))
+
+		request, err := http.NewRequest(http.MethodGet, "/users/"+e.Id+"/history", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should only allow the admins to create and manage many users at once", func(t *testing.T) {
+		controller := NewController(WithService(&mockService{}))
+		router := gin.Default()
+		controller.RegisterAuthenticated(authenticatedAs(&router.RouterGroup, e.Id))
+
+		for _, r := range []struct {
+			method string
+			target string
+		}{
+			{http.MethodPost, "/users"},
+			{http.MethodPost, "/users:batch"},
+			{http.MethodPost, "/users:import"},
+			{http.MethodGet, "/users/export"},
+			{http.MethodGet, "/users/imports/" + e.Id},
+			{http.MethodGet, "/users/imports/" + e.Id + "/errors"},
+		} {
+			request, err := http.NewRequest(r.method, r.target, nil)
+			assert.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusForbidden, rr.Code, "%s %s", r.method, r.target)
+		}
+	})
 }
 
 func TestController_CreateUser(t *testing.T) {
@@ -68,9 +401,9 @@ func TestController_CreateUser(t *testing.T) {
 			FirstName: e.FirstName,
 			LastName:  e.LastName,
 			Nickname:  e.Nickname,
-			Password:  e.Password,
 			Email:     e.Email,
 			Country:   e.Country,
+			Version:   e.Version,
 			CreatedAt: e.CreatedAt,
 			UpdatedAt: e.UpdatedAt,
 		}}
@@ -105,7 +438,7 @@ func TestController_CreateUser(t *testing.T) {
 		assert.Equal(t, respBody, rr.Body.Bytes())
 	})
 
-	t.Run("should return bad request when a required field is missing", func(t *testing.T) {
+	t.Run("should return unprocessable entity when a required field is missing", func(t *testing.T) {
 		req := CreateUserRequest{
 			FirstName: e.FirstName,
 			Nickname:  e.Nickname,
@@ -113,7 +446,7 @@ func TestController_CreateUser(t *testing.T) {
 			Email:     e.Email,
 			Country:   e.Country,
 		}
-		expected := apierr.BadRequest("invalid request body")
+		expected := validationError(nil)
 
 		reqBody, err := json.Marshal(req)
 		assert.NoError(t, err)
@@ -131,6 +464,53 @@ func TestController_CreateUser(t *testing.T) {
 		assert.Equal(t, expected.Code, actualResp.Code)
 	})
 
+	t.Run("should create the user by the canonical nickname and email", func(t *testing.T) {
+		mockService.createMock = func(ctx context.Context, request CreateUserRequest) (CreateUserResponse, error) {
+			assert.Equal(t, "bobby.firmino", request.Nickname)
+			assert.Equal(t, "robertofirmino@lfc.co.uk", request.Email)
+
+			return CreateUserResponse{}, nil
+		}
+
+		body := `{"first_name":"Roberto","last_name":"Firmino","nickname":" Bobby.Firmino","password":"liverpool321",` +
+			`"email":"RobertoFirmino@LFC.co.uk ","country":"BR"}`
+
+		request, err := http.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("should report all the violations of the validation rules together", func(t *testing.T) {
+		body := `{"first_name":"` + strings.Repeat("a", 51) + `","last_name":"Firmino","nickname":"bobby firmino",` +
+			`"password":"liverpool321","email":"robertofirmino","country":"UK"}`
+
+		request, err := http.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		var actualResp struct {
+			Code string           `json:"code"`
+			Data []FieldViolation `json:"data"`
+		}
+		err = json.Unmarshal(rr.Body.Bytes(), &actualResp)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Equal(t, "1016", actualResp.Code)
+		assert.Equal(t, []FieldViolation{
+			{Field: "first_name", Rule: "max", Message: "must be at most 50 characters long"},
+			{Field: "nickname", Rule: "nickname", Message: violationMessages["nickname"]},
+			{Field: "email", Rule: "email", Message: "must be a valid email address"},
+			{Field: "country", Rule: "iso3166_1_alpha2", Message: "must be an ISO 3166-1 alpha-2 country code"},
+		}, actualResp.Data)
+	})
+
 	t.Run("should return internal error when service fails", func(t *testing.T) {
 		req := CreateUserRequest{
 			FirstName: e.FirstName,
@@ -170,7 +550,7 @@ func TestController_UpdateUser(t *testing.T) {
 
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
-	router.PATCH(fmt.Sprintf("%v/:id", route), controller.UpdateUser)
+	router.PUT(fmt.Sprintf("%v/:id", route), controller.UpdateUser)
 
 	t.Run("success", func(t *testing.T) {
 		expected := UpdateUserResponse{User{
@@ -178,9 +558,9 @@ func TestController_UpdateUser(t *testing.T) {
 			FirstName: e.FirstName,
 			LastName:  e.LastName,
 			Nickname:  e.Nickname,
-			Password:  e.Password,
 			Email:     e.Email,
 			Country:   e.Country,
+			Version:   e.Version,
 			CreatedAt: e.CreatedAt,
 			UpdatedAt: e.UpdatedAt,
 		}}
@@ -203,7 +583,7 @@ func TestController_UpdateUser(t *testing.T) {
 		reqBody, err := json.Marshal(req)
 		assert.NoError(t, err)
 
-		request, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("/users/%v", e.Id), bytes.NewBuffer(reqBody))
+		request, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/users/%v", e.Id), bytes.NewBuffer(reqBody))
 		assert.NoError(t, err)
 
 		rr := httptest.NewRecorder()
@@ -216,7 +596,7 @@ func TestController_UpdateUser(t *testing.T) {
 		assert.Equal(t, respBody, rr.Body.Bytes())
 	})
 
-	t.Run("should return bad request when a required field is missing", func(t *testing.T) {
+	t.Run("should return unprocessable entity when a required field is missing", func(t *testing.T) {
 		req := UpdateUserRequest{
 			FirstName: e.FirstName,
 			Nickname:  e.Nickname,
@@ -224,12 +604,12 @@ func TestController_UpdateUser(t *testing.T) {
 			Email:     e.Email,
 			Country:   e.Country,
 		}
-		expected := apierr.BadRequest("invalid request body")
+		expected := validationError(nil)
 
 		reqBody, err := json.Marshal(req)
 		assert.NoError(t, err)
 
-		request, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("/users/%v", e.Id), bytes.NewBuffer(reqBody))
+		request, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/users/%v", e.Id), bytes.NewBuffer(reqBody))
 		assert.NoError(t, err)
 
 		rr := httptest.NewRecorder()
@@ -260,7 +640,7 @@ func TestController_UpdateUser(t *testing.T) {
 		reqBody, err := json.Marshal(req)
 		assert.NoError(t, err)
 
-		request, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("/users/%v", e.Id), bytes.NewBuffer(reqBody))
+		request, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/users/%v", e.Id), bytes.NewBuffer(reqBody))
 		assert.NoError(t, err)
 
 		rr := httptest.NewRecorder()
@@ -275,33 +655,42 @@ func TestController_UpdateUser(t *testing.T) {
 	})
 }
 
-func TestController_DeleteUserById(t *testing.T) {
+func TestController_PatchUser(t *testing.T) {
 	mockService := &mockService{}
 	controller := NewController(WithService(mockService))
 
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
-	router.DELETE(fmt.Sprintf("%v/:id", route), controller.DeleteUserById)
+	router.PATCH(fmt.Sprintf("%v/:id", route), controller.PatchUser)
 
 	t.Run("success", func(t *testing.T) {
-		expected := DeleteUserResponse{
-			Id: e.Id,
-		}
-		req := DeleteUserByIdRequest{
-			Id: e.Id,
-		}
+		expected := UpdateUserResponse{User{
+			Id:        e.Id,
+			FirstName: e.FirstName,
+			LastName:  e.LastName,
+			Nickname:  e.Nickname,
+			Email:     e.Email,
+			Country:   "DE",
+			CreatedAt: e.CreatedAt,
+			UpdatedAt: e.UpdatedAt,
+		}}
 
-		mockService.deleteByIdMock = func(ctx context.Context, request DeleteUserByIdRequest) (DeleteUserResponse, error) {
-			assert.EqualValues(t, req, request)
+		mockService.patchMock = func(ctx context.Context, request PatchUserRequest) (UpdateUserResponse, error) {
+			assert.Equal(t, e.Id, request.Id)
+			assert.NotNil(t, request.Country)
+			assert.Equal(t, "DE", *request.Country)
+			assert.Nil(t, request.FirstName, "should not set the fields missing in the patch")
+			assert.Nil(t, request.LastName, "should not set the fields missing in the patch")
+			assert.Nil(t, request.Nickname, "should not set the fields missing in the patch")
+			assert.Nil(t, request.Password, "should not set the fields missing in the patch")
+			assert.Nil(t, request.Email, "should not set the fields missing in the patch")
 
 			return expected, nil
 		}
 
-		reqBody, err := json.Marshal(req)
-		assert.NoError(t, err)
-
-		request, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%v", e.Id), bytes.NewBuffer(reqBody))
+		request, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("/users/%v", e.Id), bytes.NewBufferString(`{"country":"DE"}`))
 		assert.NoError(t, err)
+		request.Header.Set("Content-Type", "application/merge-patch+json")
 
 		rr := httptest.NewRecorder()
 		router.ServeHTTP(rr, request)
@@ -311,23 +700,32 @@ func TestController_DeleteUserById(t *testing.T) {
 
 		assert.Equal(t, http.StatusOK, rr.Code)
 		assert.Equal(t, respBody, rr.Body.Bytes())
+		assert.Equal(t, etag(expected.Version), rr.Header().Get(headerETag))
 	})
 
-	t.Run("should return internal error when service fails", func(t *testing.T) {
-		req := DeleteUserByIdRequest{
-			Id: e.Id,
-		}
+	t.Run("should pass the version in If-Match header", func(t *testing.T) {
+		mockService.patchMock = func(ctx context.Context, request PatchUserRequest) (UpdateUserResponse, error) {
+			assert.Equal(t, 3, request.Version)
 
-		expected := repositoryError(fmt.Errorf("mock error"))
-		mockService.deleteByIdMock = func(ctx context.Context, request DeleteUserByIdRequest) (DeleteUserResponse, error) {
-			return DeleteUserResponse{}, expected
+			return UpdateUserResponse{}, nil
 		}
 
-		reqBody, err := json.Marshal(req)
+		request, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("/users/%v", e.Id), bytes.NewBufferString(`{"country":"DE"}`))
 		assert.NoError(t, err)
+		request.Header.Set(headerIfMatch, `"3"`)
 
-		request, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%v", e.Id), bytes.NewBuffer(reqBody))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should return bad request when If-Match header is invalid", func(t *testing.T) {
+		expected := invalidETagError(headerIfMatch)
+
+		request, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("/users/%v", e.Id), bytes.NewBufferString(`{"country":"DE"}`))
 		assert.NoError(t, err)
+		request.Header.Set(headerIfMatch, `W/"3"`)
 
 		rr := httptest.NewRecorder()
 		router.ServeHTTP(rr, request)
@@ -337,79 +735,215 @@ func TestController_DeleteUserById(t *testing.T) {
 
 		assert.Equal(t, expected.StatusCode, rr.Code)
 		assert.Equal(t, expected.Code, actualResp.Code)
-		assert.Equal(t, expected.Message, actualResp.Message)
 	})
-}
 
-func TestController_GetUsersMany(t *testing.T) {
-	mockService := &mockService{}
-	controller := NewController(WithService(mockService))
+	t.Run("should return precondition failed when the version does not match", func(t *testing.T) {
+		expected := versionMismatchError(e.Id)
+		mockService.patchMock = func(ctx context.Context, request PatchUserRequest) (UpdateUserResponse, error) {
+			return UpdateUserResponse{}, expected
+		}
 
-	gin.SetMode(gin.TestMode)
-	router := gin.Default()
-	router.GET(fmt.Sprintf("%v", route), controller.GetUsersMany)
+		request, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("/users/%v", e.Id), bytes.NewBufferString(`{"country":"DE"}`))
+		assert.NoError(t, err)
+		request.Header.Set(headerIfMatch, `"1"`)
 
-	t.Run("success with default query params", func(t *testing.T) {
-		req := GetUsersManyRequest{
-			Page:    1,
-			PerPage: 10,
-			Filter:  User{},
-		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
 
-		expected := GetUsersManyResponse{}
-		expected.Users = make([]User, len(entities))
-		for i, val := range entities {
-			expected.Users[i] = User{
-				Id:        val.Id,
-				FirstName: val.FirstName,
-				LastName:  val.LastName,
-				Nickname:  val.Nickname,
-				Password:  val.Password,
-				Email:     val.Email,
-				Country:   val.Country,
-				CreatedAt: val.CreatedAt,
-				UpdatedAt: val.UpdatedAt,
-			}
-		}
+		var actualResp apierr.ApiError
+		err = json.Unmarshal(rr.Body.Bytes(), &actualResp)
 
-		mockService.getManyMock = func(ctx context.Context, request GetUsersManyRequest) (GetUsersManyResponse, error) {
-			assert.EqualValues(t, req, request)
+		assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+		assert.Equal(t, expected.Code, actualResp.Code)
+		assert.Equal(t, expected.Message, actualResp.Message)
+	})
 
-			return expected, nil
-		}
+	t.Run("should return bad request when a field is removed", func(t *testing.T) {
+		expected := apierr.BadRequest("field nickname cannot be removed")
 
-		request, err := http.NewRequest(http.MethodGet, "/users", nil)
+		request, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("/users/%v", e.Id), bytes.NewBufferString(`{"nickname":null}`))
 		assert.NoError(t, err)
 
 		rr := httptest.NewRecorder()
 		router.ServeHTTP(rr, request)
 
-		expectedBytes, err := json.Marshal(expected)
+		var actualResp apierr.ApiError
+		err = json.Unmarshal(rr.Body.Bytes(), &actualResp)
+
+		assert.Equal(t, expected.StatusCode, rr.Code)
+		assert.Equal(t, expected.Code, actualResp.Code)
+		assert.Equal(t, expected.Message, actualResp.Message)
+	})
+
+	t.Run("should return bad request when the body is not an object", func(t *testing.T) {
+		expected := apierr.BadRequest("")
+
+		request, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("/users/%v", e.Id), bytes.NewBufferString(`["country"]`))
 		assert.NoError(t, err)
 
-		assert.Equal(t, http.StatusOK, rr.Code)
-		assert.Equal(t, expectedBytes, rr.Body.Bytes())
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		var actualResp apierr.ApiError
+		err = json.Unmarshal(rr.Body.Bytes(), &actualResp)
+
+		assert.Equal(t, expected.StatusCode, rr.Code)
+		assert.Equal(t, expected.Code, actualResp.Code)
 	})
 
-	t.Run("success with valid query parameters", func(t *testing.T) {
-		req := GetUsersManyRequest{
-			Page:    1,
-			PerPage: 10,
-			Filter: User{
-				Country: "UK",
-			},
+	t.Run("should return not found when the user does not exist", func(t *testing.T) {
+		expected := userNotFoundError(e.Id)
+		mockService.patchMock = func(ctx context.Context, request PatchUserRequest) (UpdateUserResponse, error) {
+			return UpdateUserResponse{}, expected
 		}
-		countryParam := fmt.Sprintf("{\"country\":\"%s\"}", req.Filter.Country)
 
-		expected := GetUsersManyResponse{}
-		expected.Users = make([]User, len(entities))
+		request, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("/users/%v", e.Id), bytes.NewBufferString(`{"country":"DE"}`))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		var actualResp apierr.ApiError
+		err = json.Unmarshal(rr.Body.Bytes(), &actualResp)
+
+		assert.Equal(t, expected.StatusCode, rr.Code)
+		assert.Equal(t, expected.Code, actualResp.Code)
+		assert.Equal(t, expected.Message, actualResp.Message)
+	})
+}
+
+func TestController_DeleteUserById(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.DELETE(fmt.Sprintf("%v/:id", route), controller.DeleteUserById)
+
+	t.Run("success", func(t *testing.T) {
+		expected := DeleteUserResponse{
+			Id: e.Id,
+		}
+		req := DeleteUserByIdRequest{
+			Id: e.Id,
+		}
+
+		mockService.deleteByIdMock = func(ctx context.Context, request DeleteUserByIdRequest) (DeleteUserResponse, error) {
+			assert.EqualValues(t, req, request)
+
+			return expected, nil
+		}
+
+		reqBody, err := json.Marshal(req)
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%v", e.Id), bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(expected)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("should return internal error when service fails", func(t *testing.T) {
+		req := DeleteUserByIdRequest{
+			Id: e.Id,
+		}
+
+		expected := repositoryError(fmt.Errorf("mock error"))
+		mockService.deleteByIdMock = func(ctx context.Context, request DeleteUserByIdRequest) (DeleteUserResponse, error) {
+			return DeleteUserResponse{}, expected
+		}
+
+		reqBody, err := json.Marshal(req)
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%v", e.Id), bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		var actualResp apierr.ApiError
+		err = json.Unmarshal(rr.Body.Bytes(), &actualResp)
+
+		assert.Equal(t, expected.StatusCode, rr.Code)
+		assert.Equal(t, expected.Code, actualResp.Code)
+		assert.Equal(t, expected.Message, actualResp.Message)
+	})
+}
+
+func TestController_GetUsersMany(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET(fmt.Sprintf("%v", route), controller.GetUsersMany)
+
+	t.Run("success with default query params", func(t *testing.T) {
+		req := GetUsersManyRequest{
+			Page:    1,
+			PerPage: 10,
+		}
+
+		expected := GetUsersManyResponse{}
+		expected.Users = make([]User, len(entities))
+		for i, val := range entities {
+			expected.Users[i] = User{
+				Id:        val.Id,
+				FirstName: val.FirstName,
+				LastName:  val.LastName,
+				Nickname:  val.Nickname,
+				Email:     val.Email,
+				Country:   val.Country,
+				CreatedAt: val.CreatedAt,
+				UpdatedAt: val.UpdatedAt,
+			}
+		}
+
+		mockService.getManyMock = func(ctx context.Context, request GetUsersManyRequest) (GetUsersManyResponse, error) {
+			assert.EqualValues(t, req, request)
+
+			return expected, nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		expectedBytes, err := json.Marshal(expected)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, expectedBytes, rr.Body.Bytes())
+	})
+
+	t.Run("success with valid query parameters", func(t *testing.T) {
+		req := GetUsersManyRequest{
+			Page:    1,
+			PerPage: 10,
+			Filter: []FilterCondition{
+				{Column: "country", Operator: FilterEq, Values: []interface{}{"UK"}},
+			},
+		}
+		countryParam := `{"country":"UK"}`
+
+		expected := GetUsersManyResponse{}
+		expected.Users = make([]User, len(entities))
 		for i, val := range entities {
 			expected.Users[i] = User{
 				Id:        val.Id,
 				FirstName: val.FirstName,
 				LastName:  val.LastName,
 				Nickname:  val.Nickname,
-				Password:  val.Password,
 				Email:     val.Email,
 				Country:   val.Country,
 				CreatedAt: val.CreatedAt,
@@ -436,6 +970,24 @@ func TestController_GetUsersMany(t *testing.T) {
 		assert.Equal(t, expectedBytes, rr.Body.Bytes())
 	})
 
+	t.Run("should pass the cursor query parameter", func(t *testing.T) {
+		cursor := encodeCursor(after(e, normalizeSort(nil)))
+
+		mockService.getManyMock = func(ctx context.Context, request GetUsersManyRequest) (GetUsersManyResponse, error) {
+			assert.Equal(t, cursor, request.Cursor)
+
+			return GetUsersManyResponse{}, nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/users?cursor=%v", cursor), nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
 	t.Run("should return bad request when page query parameter is in invalid syntax", func(t *testing.T) {
 		invalidParam := "s"
 		invalidSyntaxErr := &strconv.NumError{Err: strconv.ErrSyntax, Func: "Atoi", Num: invalidParam}
@@ -474,6 +1026,153 @@ func TestController_GetUsersMany(t *testing.T) {
 		assert.Equal(t, expected.Message, actualResp.Message)
 	})
 
+	t.Run("should return only the requested fields of the users", func(t *testing.T) {
+		mockService.getManyMock = func(ctx context.Context, request GetUsersManyRequest) (GetUsersManyResponse, error) {
+			assert.Equal(t, []string{"id", "nickname"}, request.Fields)
+
+			return GetUsersManyResponse{Users: []User{{Id: e.Id, Nickname: e.Nickname, Country: e.Country}}, Next: "next", PerPage: 10}, nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users?fields=id,nickname", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, fmt.Sprintf(`{"users":[{"id":"%s","nickname":"%s"}],"next":"next","perPage":10}`, e.Id, e.Nickname), rr.Body.String())
+	})
+
+	t.Run("should parse the sort query parameter", func(t *testing.T) {
+		mockService.getManyMock = func(ctx context.Context, request GetUsersManyRequest) (GetUsersManyResponse, error) {
+			assert.Equal(t, []SortField{{Column: "updated_at", Descending: true}, {Column: "nickname"}}, request.Sort)
+
+			return GetUsersManyResponse{}, nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users?sort=-updated_at,nickname", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should return bad request when the sort is invalid", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodGet, "/users?sort=password", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		var actualResp apierr.ApiError
+		err = json.Unmarshal(rr.Body.Bytes(), &actualResp)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, "1009", actualResp.Code)
+	})
+
+	t.Run("should return bad request when the page is out of range", func(t *testing.T) {
+		for _, query := range []string{"page=0", "page=-1", "perPage=0", fmt.Sprintf("perPage=%d", maxPerPage+1)} {
+			request, err := http.NewRequest(http.MethodGet, "/users?"+query, nil)
+			assert.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+		}
+	})
+
+	t.Run("should pass the skipTotal query parameter", func(t *testing.T) {
+		mockService.getManyMock = func(ctx context.Context, request GetUsersManyRequest) (GetUsersManyResponse, error) {
+			assert.True(t, request.SkipTotal)
+
+			return GetUsersManyResponse{}, nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users?skipTotal=true", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should set the link header", func(t *testing.T) {
+		total, totalPages := 25, 3
+		mockService.getManyMock = func(ctx context.Context, request GetUsersManyRequest) (GetUsersManyResponse, error) {
+			return GetUsersManyResponse{Next: "next", Prev: "prev", Page: 2, PerPage: 10, Total: &total, TotalPages: &totalPages}, nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users?page=2&perPage=10", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `</users?page=1&perPage=10>; rel="first", `+
+			`</users?page=1&perPage=10>; rel="prev", `+
+			`</users?page=3&perPage=10>; rel="next", `+
+			`</users?page=3&perPage=10>; rel="last"`, rr.Header().Get(headerLink))
+	})
+
+	t.Run("should parse the filter operators", func(t *testing.T) {
+		createdAfter := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+		expected := []FilterCondition{
+			{Column: "country", Operator: FilterIn, Values: []interface{}{"UK", "DE"}},
+			{Column: "created_at", Operator: FilterGte, Values: []interface{}{createdAfter}},
+			{Column: "nickname", Operator: FilterPrefix, Values: []interface{}{"ali"}},
+		}
+
+		mockService.getManyMock = func(ctx context.Context, request GetUsersManyRequest) (GetUsersManyResponse, error) {
+			assert.EqualValues(t, expected, request.Filter)
+
+			return GetUsersManyResponse{}, nil
+		}
+
+		query := url.Values{}
+		query.Set("filter[country][in]", "UK,DE")
+		query.Set("filter[created_at][gte]", createdAfter.Format(time.RFC3339))
+		query.Set("filter[nickname][prefix]", "ali")
+
+		request, err := http.NewRequest(http.MethodGet, "/users?"+query.Encode(), nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should return bad request when the filter is invalid", func(t *testing.T) {
+		for _, query := range []string{
+			"filter[password]=secret",
+			"filter[created_at][prefix]=2022",
+			"filter[created_at][gte]=yesterday",
+			"filter[country][like]=UK",
+			"filter[id]=1",
+			"filter[country",
+		} {
+			request, err := http.NewRequest(http.MethodGet, "/users?"+query, nil)
+			assert.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+
+			var actualResp apierr.ApiError
+			err = json.Unmarshal(rr.Body.Bytes(), &actualResp)
+			assert.NoError(t, err)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+			assert.Equal(t, "1007", actualResp.Code, query)
+		}
+	})
+
 	t.Run("should return bad request when filter parameter schema is invalid", func(t *testing.T) {
 		invalidParam := "test"
 		expected := apierr.BadRequest("")
@@ -511,4 +1210,1300 @@ func TestController_GetUsersMany(t *testing.T) {
 		assert.Equal(t, expected.Code, actualResp.Code)
 		assert.Equal(t, expected.Message, actualResp.Message)
 	})
+
+	t.Run("should include the deleted users when the request is authenticated by an admin", func(t *testing.T) {
+		signingKey, err := auth.NewSigningKey(auth.AlgorithmHS256, "secret", "")
+		assert.NoError(t, err)
+
+		tokens := auth.NewTokenManager(auth.WithSigningKey(signingKey), auth.WithTokenTTL(time.Minute))
+		token, err := tokens.Issue(testAdmin)
+		assert.NoError(t, err)
+
+		controller := NewController(WithService(mockService), WithIdentityMiddleware(auth.NewIdentityMiddleware(tokens, auth.WithAdmins(testAdmin))))
+		router := gin.Default()
+		controller.Register(&router.RouterGroup)
+
+		mockService.getManyMock = func(ctx context.Context, request GetUsersManyRequest) (GetUsersManyResponse, error) {
+			assert.True(t, request.IncludeDeleted)
+
+			return GetUsersManyResponse{}, nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users?includeDeleted=true", nil)
+		assert.NoError(t, err)
+		request.Header.Set("Authorization", "Bearer "+token.Value)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should return unauthorized when the deleted users are requested without an access token", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodGet, "/users?includeDeleted=true", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("should return forbidden when the deleted users are requested by a non admin", func(t *testing.T) {
+		signingKey, err := auth.NewSigningKey(auth.AlgorithmHS256, "secret", "")
+		assert.NoError(t, err)
+
+		tokens := auth.NewTokenManager(auth.WithSigningKey(signingKey), auth.WithTokenTTL(time.Minute))
+		token, err := tokens.Issue(e.Id)
+		assert.NoError(t, err)
+
+		controller := NewController(WithService(mockService), WithIdentityMiddleware(auth.NewIdentityMiddleware(tokens, auth.WithAdmins(testAdmin))))
+		router := gin.Default()
+		controller.Register(&router.RouterGroup)
+
+		mockService.getManyMock = func(ctx context.Context, request GetUsersManyRequest) (GetUsersManyResponse, error) {
+			assert.Fail(t, "should not list the deleted users")
+			return GetUsersManyResponse{}, nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users?includeDeleted=true", nil)
+		assert.NoError(t, err)
+		request.Header.Set("Authorization", "Bearer "+token.Value)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
+
+func TestController_RestoreUser(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST(fmt.Sprintf("%v/:id/restore", route), controller.RestoreUser)
+
+	t.Run("success", func(t *testing.T) {
+		expected := RestoreUserResponse{User{
+			Id:        e.Id,
+			FirstName: e.FirstName,
+			LastName:  e.LastName,
+			Nickname:  e.Nickname,
+			Email:     e.Email,
+			Country:   e.Country,
+			Version:   e.Version,
+			CreatedAt: e.CreatedAt,
+			UpdatedAt: e.UpdatedAt,
+		}}
+
+		mockService.restoreMock = func(ctx context.Context, request RestoreUserRequest) (RestoreUserResponse, error) {
+			assert.Equal(t, e.Id, request.Id)
+
+			return expected, nil
+		}
+
+		request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%v/restore", e.Id), nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(expected)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		assert.Equal(t, etag(expected.Version), rr.Header().Get(headerETag))
+	})
+
+	t.Run("should return not found when the user does not exist", func(t *testing.T) {
+		expected := userNotFoundError(e.Id)
+		mockService.restoreMock = func(ctx context.Context, request RestoreUserRequest) (RestoreUserResponse, error) {
+			return RestoreUserResponse{}, expected
+		}
+
+		request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%v/restore", e.Id), nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		var actualResp apierr.ApiError
+		err = json.Unmarshal(rr.Body.Bytes(), &actualResp)
+
+		assert.Equal(t, expected.StatusCode, rr.Code)
+		assert.Equal(t, expected.Code, actualResp.Code)
+		assert.Equal(t, expected.Message, actualResp.Message)
+	})
+}
+
+func TestController_BatchUsers(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller.RegisterAuthenticated(authenticatedAs(&router.RouterGroup, testAdmin, testAdmin))
+
+	t.Run("success", func(t *testing.T) {
+		req := BatchUsersRequest{
+			Mode: BatchModePartial,
+			Operations: []BatchOperation{
+				{Op: BatchOpCreate, User: &CreateUserRequest{
+					FirstName: e.FirstName,
+					LastName:  e.LastName,
+					Nickname:  e.Nickname,
+					Password:  e.Password,
+					Email:     e.Email,
+					Country:   e.Country,
+				}},
+				{Op: BatchOpDelete, Id: e.Id, Version: 2},
+			},
+		}
+		expected := BatchUsersResponse{
+			Committed: true,
+			Results: []BatchOperationResult{
+				{Status: http.StatusCreated, Id: e.Id, User: &User{Id: e.Id, Nickname: e.Nickname}},
+				{Status: http.StatusOK, Id: e.Id},
+			},
+		}
+
+		mockService.batchMock = func(ctx context.Context, request BatchUsersRequest) (BatchUsersResponse, error) {
+			assert.EqualValues(t, req, request)
+
+			return expected, nil
+		}
+
+		body, err := json.Marshal(req)
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/users:batch", bytes.NewReader(body))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		expectedBytes, err := json.Marshal(expected)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, expectedBytes, rr.Body.Bytes())
+	})
+
+	t.Run("should return not found when the method is unknown", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodPost, "/users:merge", bytes.NewReader([]byte(`{}`)))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	invalid := map[string]string{
+		"no operations":       `{"operations": []}`,
+		"unknown mode":        `{"mode": "eventual", "operations": [{"op": "delete", "id": "` + e.Id + `"}]}`,
+		"unknown operation":   `{"operations": [{"op": "merge", "id": "` + e.Id + `"}]}`,
+		"delete without id":   `{"operations": [{"op": "delete"}]}`,
+		"update without user": `{"operations": [{"op": "update", "id": "` + e.Id + `"}]}`,
+	}
+	for name, body := range invalid {
+		t.Run("should return unprocessable entity when "+name, func(t *testing.T) {
+			request, err := http.NewRequest(http.MethodPost, "/users:batch", bytes.NewReader([]byte(body)))
+			assert.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		})
+	}
+
+	t.Run("should return bad request when the body is malformed", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodPost, "/users:batch", bytes.NewReader([]byte(`{"operations": [`)))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should return internal error when service fails", func(t *testing.T) {
+		expected := repositoryError(fmt.Errorf("mock error"))
+		mockService.batchMock = func(ctx context.Context, request BatchUsersRequest) (BatchUsersResponse, error) {
+			return BatchUsersResponse{}, expected
+		}
+
+		body := `{"operations": [{"op": "delete", "id": "` + e.Id + `"}]}`
+		request, err := http.NewRequest(http.MethodPost, "/users:batch", bytes.NewReader([]byte(body)))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, expected.StatusCode, rr.Code)
+	})
+}
+
+func TestController_ImportUsers(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller.RegisterAuthenticated(authenticatedAs(router.Group("/v1"), testAdmin, testAdmin))
+
+	t.Run("success", func(t *testing.T) {
+		upload := "first_name,last_name,nickname,password,email,country\n"
+		expected := ImportJobResponse{ImportJob{Id: e.Id, Status: ImportStatusPending, Format: ImportFormatCSV}}
+
+		mockService.importMock = func(ctx context.Context, request ImportUsersRequest) (ImportJobResponse, error) {
+			assert.Equal(t, ImportFormatCSV, request.Format)
+
+			file, err := io.ReadAll(request.File)
+			assert.NoError(t, err)
+			assert.Equal(t, upload, string(file))
+
+			return expected, nil
+		}
+
+		request, err := http.NewRequest(http.MethodPost, "/v1/users:import", bytes.NewReader([]byte(upload)))
+		assert.NoError(t, err)
+		request.Header.Set("Content-Type", "text/csv")
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		expectedBytes, err := json.Marshal(expected)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Equal(t, "/v1/users/imports/"+e.Id, rr.Header().Get("Location"))
+		assert.Equal(t, expectedBytes, rr.Body.Bytes())
+	})
+
+	t.Run("should limit the size of the upload", func(t *testing.T) {
+		controller := NewController(WithService(mockService), WithMaxImportSize(10))
+		router := gin.Default()
+		controller.RegisterAuthenticated(authenticatedAs(router.Group("/v1"), testAdmin, testAdmin))
+
+		mockService.importMock = func(ctx context.Context, request ImportUsersRequest) (ImportJobResponse, error) {
+			_, err := io.ReadAll(request.File)
+
+			var tooLarge *http.MaxBytesError
+			assert.ErrorAs(t, err, &tooLarge)
+			return ImportJobResponse{}, importTooLargeError(tooLarge.Limit)
+		}
+
+		request, err := http.NewRequest(http.MethodPost, "/v1/users:import", strings.NewReader("first_name,last_name,nickname\n"))
+		assert.NoError(t, err)
+		request.Header.Set("Content-Type", "text/csv")
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+
+	t.Run("should return unsupported media type when the content type is unknown", func(t *testing.T) {
+		mockService.importMock = func(ctx context.Context, request ImportUsersRequest) (ImportJobResponse, error) {
+			return ImportJobResponse{}, unsupportedImportFormatError(request.Format)
+		}
+
+		request, err := http.NewRequest(http.MethodPost, "/v1/users:import", bytes.NewReader([]byte("<users/>")))
+		assert.NoError(t, err)
+		request.Header.Set("Content-Type", "application/xml")
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+	})
+}
+
+func TestController_ExportUsers(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller.Register(&router.RouterGroup)
+	controller.RegisterAuthenticated(authenticatedAs(&router.RouterGroup, testAdmin, testAdmin))
+
+	t.Run("success", func(t *testing.T) {
+		mockService.exportMock = func(ctx context.Context, request ExportUsersRequest, w io.Writer) error {
+			assert.EqualValues(t, ExportUsersRequest{
+				Format:         ExportFormatCSV,
+				Fields:         []string{"id", "nickname"},
+				Sort:           []SortField{{Column: "nickname", Descending: true}},
+				Filter:         []FilterCondition{{Column: "country", Operator: FilterEq, Values: []interface{}{"UK"}}},
+				IncludeDeleted: true,
+			}, request)
+
+			_, err := w.Write([]byte("id,nickname\n"))
+			return err
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users/export?fields=id,nickname&sort=-nickname&filter[country]=UK&includeDeleted=true", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, mimeCSV, rr.Header().Get("Content-Type"), "should export csv by default")
+		assert.Equal(t, `attachment; filename="users.csv"`, rr.Header().Get("Content-Disposition"))
+		assert.Equal(t, "id,nickname\n", rr.Body.String())
+	})
+
+	t.Run("should negotiate the format by the accept header", func(t *testing.T) {
+		mockService.exportMock = func(ctx context.Context, request ExportUsersRequest, w io.Writer) error {
+			assert.Equal(t, ExportFormatParquet, request.Format)
+			return nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users/export", nil)
+		assert.NoError(t, err)
+		request.Header.Set("Accept", mimeParquet)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, mimeParquet, rr.Header().Get("Content-Type"))
+	})
+
+	t.Run("should prefer the format query parameter", func(t *testing.T) {
+		mockService.exportMock = func(ctx context.Context, request ExportUsersRequest, w io.Writer) error {
+			assert.Equal(t, ExportFormatNDJSON, request.Format)
+			return nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users/export?format=ndjson", nil)
+		assert.NoError(t, err)
+		request.Header.Set("Accept", mimeParquet)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, mimeNDJSON, rr.Header().Get("Content-Type"))
+	})
+
+	t.Run("should return not acceptable when the format is unknown", func(t *testing.T) {
+		for _, target := range []string{"/users/export?format=xml", "/users/export"} {
+			request, err := http.NewRequest(http.MethodGet, target, nil)
+			assert.NoError(t, err)
+			request.Header.Set("Accept", "application/xml")
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusNotAcceptable, rr.Code, target)
+		}
+	})
+
+	t.Run("should return internal error when service fails before writing", func(t *testing.T) {
+		expected := repositoryError(fmt.Errorf("mock error"))
+		mockService.exportMock = func(ctx context.Context, request ExportUsersRequest, w io.Writer) error {
+			return expected
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users/export", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, expected.StatusCode, rr.Code)
+	})
+
+	t.Run("should report the error by the trailer when service fails after writing", func(t *testing.T) {
+		mockService.exportMock = func(ctx context.Context, request ExportUsersRequest, w io.Writer) error {
+			_, err := w.Write([]byte("id\n"))
+			assert.NoError(t, err)
+
+			return repositoryError(fmt.Errorf("mock error"))
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users/export", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, repositoryError(fmt.Errorf("mock error")).Message, rr.Result().Trailer.Get(headerExportError))
+	})
+}
+
+func TestController_GetImport(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller.Register(&router.RouterGroup)
+	controller.RegisterAuthenticated(authenticatedAs(&router.RouterGroup, testAdmin, testAdmin))
+
+	t.Run("success", func(t *testing.T) {
+		expected := ImportJobResponse{ImportJob{Id: e.Id, Status: ImportStatusRunning, Format: ImportFormatNDJSON, Processed: 10, Imported: 9, Failed: 1}}
+
+		mockService.getImportMock = func(ctx context.Context, request GetImportRequest) (ImportJobResponse, error) {
+			assert.EqualValues(t, GetImportRequest{Id: e.Id}, request)
+
+			return expected, nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users/imports/"+e.Id, nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		expectedBytes, err := json.Marshal(expected)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, expectedBytes, rr.Body.Bytes())
+	})
+
+	t.Run("should return bad request when the id is invalid", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodGet, "/users/imports/1", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should return not found when the job does not exist", func(t *testing.T) {
+		mockService.getImportMock = func(ctx context.Context, request GetImportRequest) (ImportJobResponse, error) {
+			return ImportJobResponse{}, importJobNotFoundError()
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users/imports/"+e.Id, nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestController_GetImportErrors(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller.RegisterAuthenticated(authenticatedAs(&router.RouterGroup, testAdmin, testAdmin))
+
+	expected := ImportErrorsResponse{Errors: []ImportRowError{
+		{Row: 2, Error: "Key: 'CreateUserRequest.Password' Error:Field validation for 'Password' failed on the 'required' tag"},
+		{Row: 3, Error: "duplicate nickname"},
+	}}
+	mockService.importErrsMock = func(ctx context.Context, request GetImportRequest) (ImportErrorsResponse, error) {
+		assert.EqualValues(t, GetImportRequest{Id: e.Id}, request)
+
+		return expected, nil
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodGet, "/users/imports/"+e.Id+"/errors", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+		assert.Contains(t, rr.Header().Get("Content-Disposition"), "attachment")
+		assert.Equal(t, "row,error\n"+
+			"2,Key: 'CreateUserRequest.Password' Error:Field validation for 'Password' failed on the 'required' tag\n"+
+			"3,duplicate nickname\n", rr.Body.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodGet, "/users/imports/"+e.Id+"/errors", nil)
+		assert.NoError(t, err)
+		request.Header.Set("Accept", "application/json")
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		expectedBytes, err := json.Marshal(expected)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, expectedBytes, rr.Body.Bytes())
+	})
+}
+
+func TestController_SearchUsers(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller.Register(&router.RouterGroup)
+
+	t.Run("success", func(t *testing.T) {
+		expected := SearchUsersResponse{
+			Users: []UserMatch{{
+				User:       User{Id: e.Id, FirstName: e.FirstName, LastName: e.LastName, Nickname: e.Nickname},
+				Rank:       0.5,
+				Highlights: map[string]string{"last_name": "<em>Firm</em>ino"},
+			}},
+			Page:    2,
+			PerPage: 5,
+		}
+
+		mockService.searchMock = func(ctx context.Context, request SearchUsersRequest) (SearchUsersResponse, error) {
+			assert.EqualValues(t, SearchUsersRequest{Query: "firm", Page: 2, PerPage: 5}, request)
+
+			return expected, nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users/search?q=firm&page=2&perPage=5", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		expectedBytes, err := json.Marshal(expected)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, expectedBytes, rr.Body.Bytes())
+	})
+
+	t.Run("should return bad request when the query is missing", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodGet, "/users/search", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should return bad request when the page is out of range", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodGet, "/users/search?q=firm&perPage=1000", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should return internal error when service fails", func(t *testing.T) {
+		expected := repositoryError(fmt.Errorf("mock error"))
+		mockService.searchMock = func(ctx context.Context, request SearchUsersRequest) (SearchUsersResponse, error) {
+			return SearchUsersResponse{}, expected
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users/search?q=firm", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, expected.StatusCode, rr.Code)
+	})
+}
+
+func TestController_GetUserById(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET(fmt.Sprintf("%v/:id", route), controller.GetUserById)
+
+	t.Run("success", func(t *testing.T) {
+		expected := GetUserResponse{User{
+			Id:        e.Id,
+			FirstName: e.FirstName,
+			LastName:  e.LastName,
+			Nickname:  e.Nickname,
+			Email:     e.Email,
+			Country:   e.Country,
+			Version:   e.Version,
+			CreatedAt: e.CreatedAt,
+			UpdatedAt: e.UpdatedAt,
+		}}
+		req := GetUserByIdRequest{
+			Id: e.Id,
+		}
+
+		mockService.getByIdMock = func(ctx context.Context, request GetUserByIdRequest) (GetUserResponse, error) {
+			assert.EqualValues(t, req, request)
+
+			return expected, nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%v", e.Id), nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(expected)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+		assert.Equal(t, etag(expected.Version), rr.Header().Get(headerETag))
+	})
+
+	t.Run("should return only the requested fields", func(t *testing.T) {
+		mockService.getByIdMock = func(ctx context.Context, request GetUserByIdRequest) (GetUserResponse, error) {
+			return GetUserResponse{User{Id: e.Id, Nickname: e.Nickname, Country: e.Country, Version: e.Version}}, nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%v?fields=id,nickname", e.Id), nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, fmt.Sprintf(`{"id":"%s","nickname":"%s"}`, e.Id, e.Nickname), rr.Body.String())
+		assert.Equal(t, etag(e.Version), rr.Header().Get(headerETag))
+	})
+
+	t.Run("should return bad request when a field is unknown", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%v?fields=id,password", e.Id), nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should return not modified when the entity tag matches", func(t *testing.T) {
+		mockService.getByIdMock = func(ctx context.Context, request GetUserByIdRequest) (GetUserResponse, error) {
+			return GetUserResponse{User{Id: e.Id, Version: e.Version}}, nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%v", e.Id), nil)
+		assert.NoError(t, err)
+		request.Header.Set(headerIfNoneMatch, etag(e.Version))
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+		assert.Empty(t, rr.Body.Bytes())
+		assert.Equal(t, etag(e.Version), rr.Header().Get(headerETag))
+	})
+
+	t.Run("should return bad request when id is not a uuid", func(t *testing.T) {
+		expected := apierr.BadRequest("")
+
+		request, err := http.NewRequest(http.MethodGet, "/users/invalid", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		var actualResp apierr.ApiError
+		err = json.Unmarshal(rr.Body.Bytes(), &actualResp)
+
+		assert.Equal(t, expected.StatusCode, rr.Code)
+		assert.Equal(t, expected.Code, actualResp.Code)
+	})
+
+	t.Run("should return not found when the user does not exist", func(t *testing.T) {
+		expected := userNotFoundError(e.Id)
+		mockService.getByIdMock = func(ctx context.Context, request GetUserByIdRequest) (GetUserResponse, error) {
+			return GetUserResponse{}, expected
+		}
+
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%v", e.Id), nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		var actualResp apierr.ApiError
+		err = json.Unmarshal(rr.Body.Bytes(), &actualResp)
+
+		assert.Equal(t, expected.StatusCode, rr.Code)
+		assert.Equal(t, expected.Code, actualResp.Code)
+		assert.Equal(t, expected.Message, actualResp.Message)
+	})
+}
+
+func TestController_GetMe(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	signingKey, err := auth.NewSigningKey(auth.AlgorithmHS256, "secret", "")
+	assert.NoError(t, err)
+
+	tokens := auth.NewTokenManager(auth.WithSigningKey(signingKey), auth.WithTokenTTL(time.Minute))
+	token, err := tokens.Issue(e.Id)
+	assert.NoError(t, err)
+
+	router.GET(fmt.Sprintf("%v/me", route), auth.NewMiddleware(tokens), controller.GetMe)
+
+	t.Run("success", func(t *testing.T) {
+		expected := GetUserResponse{User{
+			Id:        e.Id,
+			FirstName: e.FirstName,
+			LastName:  e.LastName,
+			Nickname:  e.Nickname,
+			Email:     e.Email,
+			Country:   e.Country,
+			Version:   e.Version,
+			CreatedAt: e.CreatedAt,
+			UpdatedAt: e.UpdatedAt,
+		}}
+
+		mockService.getByIdMock = func(ctx context.Context, request GetUserByIdRequest) (GetUserResponse, error) {
+			assert.Equal(t, e.Id, request.Id, "should fetch the user the token is issued for")
+
+			return expected, nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users/me", nil)
+		assert.NoError(t, err)
+		request.Header.Set("Authorization", "Bearer "+token.Value)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(expected)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("should return not found when the user does not exist", func(t *testing.T) {
+		expected := userNotFoundError(e.Id)
+		mockService.getByIdMock = func(ctx context.Context, request GetUserByIdRequest) (GetUserResponse, error) {
+			return GetUserResponse{}, expected
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users/me", nil)
+		assert.NoError(t, err)
+		request.Header.Set("Authorization", "Bearer "+token.Value)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		var actualResp apierr.ApiError
+		err = json.Unmarshal(rr.Body.Bytes(), &actualResp)
+
+		assert.Equal(t, expected.StatusCode, rr.Code)
+		assert.Equal(t, expected.Code, actualResp.Code)
+	})
+
+	t.Run("should return unauthorized when the request is not authenticated", func(t *testing.T) {
+		router := gin.Default()
+		router.GET(fmt.Sprintf("%v/me", route), controller.GetMe)
+
+		request, err := http.NewRequest(http.MethodGet, "/users/me", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
+
+func TestController_CheckNicknameAvailability(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller.Register(&router.RouterGroup)
+
+	t.Run("success", func(t *testing.T) {
+		expected := NicknameAvailabilityResponse{Nickname: "player", Suggestions: []string{"player1", "player2"}}
+
+		mockService.availabilityMock = func(ctx context.Context, request NicknameAvailabilityRequest) (NicknameAvailabilityResponse, error) {
+			assert.Equal(t, NicknameAvailabilityRequest{Nickname: "player"}, request, "should check the canonical nickname")
+
+			return expected, nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, "/users/availability?nickname=%20Player%20", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		expectedBytes, err := json.Marshal(expected)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, expectedBytes, rr.Body.Bytes())
+	})
+
+	t.Run("should return unprocessable entity when the nickname is invalid", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodGet, "/users/availability?nickname=a", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+}
+
+func TestController_SendVerification(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST(fmt.Sprintf("%v/:id/verification", route), controller.SendVerification)
+
+	t.Run("success", func(t *testing.T) {
+		expected := VerificationResponse{Id: e.Id, Email: e.Email, ExpiresAt: time.Now().Add(time.Hour)}
+
+		mockService.sendVerificationMock = func(ctx context.Context, request SendVerificationRequest) (VerificationResponse, error) {
+			assert.Equal(t, SendVerificationRequest{Id: e.Id}, request)
+
+			return expected, nil
+		}
+
+		request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%v/verification", e.Id), nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(expected)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("should return conflict when the email is already verified", func(t *testing.T) {
+		mockService.sendVerificationMock = func(ctx context.Context, request SendVerificationRequest) (VerificationResponse, error) {
+			return VerificationResponse{}, emailAlreadyVerifiedError(request.Id)
+		}
+
+		request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%v/verification", e.Id), nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+}
+
+func TestController_VerifyEmail(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller.Register(&router.RouterGroup)
+
+	t.Run("success", func(t *testing.T) {
+		verifiedAt := time.Now()
+		expected := VerifyEmailResponse{User{
+			Id:              e.Id,
+			FirstName:       e.FirstName,
+			LastName:        e.LastName,
+			Nickname:        e.Nickname,
+			Email:           e.Email,
+			Country:         e.Country,
+			Version:         e.Version,
+			CreatedAt:       e.CreatedAt,
+			UpdatedAt:       e.UpdatedAt,
+			EmailVerifiedAt: &verifiedAt,
+		}}
+
+		mockService.verifyEmailMock = func(ctx context.Context, request VerifyEmailRequest) (VerifyEmailResponse, error) {
+			assert.Equal(t, VerifyEmailRequest{Token: "token"}, request)
+
+			return expected, nil
+		}
+
+		request, err := http.NewRequest(http.MethodPost, "/users/verification", strings.NewReader(`{"token":"token"}`))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(expected)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("should return bad request when the token is invalid", func(t *testing.T) {
+		mockService.verifyEmailMock = func(ctx context.Context, request VerifyEmailRequest) (VerifyEmailResponse, error) {
+			return VerifyEmailResponse{}, invalidVerificationTokenError()
+		}
+
+		request, err := http.NewRequest(http.MethodPost, "/users/verification", strings.NewReader(`{"token":"token"}`))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should return unprocessable entity when the token is missing", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodPost, "/users/verification", strings.NewReader(`{}`))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+}
+
+func TestController_RequestPasswordReset(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller.Register(&router.RouterGroup)
+
+	t.Run("success", func(t *testing.T) {
+		mockService.requestResetMock = func(ctx context.Context, request PasswordResetRequest) error {
+			assert.Equal(t, PasswordResetRequest{Email: e.Email}, request, "should request the reset for the canonical email")
+
+			return nil
+		}
+
+		request, err := http.NewRequest(http.MethodPost, "/users/password-reset", strings.NewReader(`{"email":" RobertoFirmino@lfc.co.uk"}`))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Empty(t, rr.Body.Bytes())
+	})
+
+	t.Run("should return unprocessable entity when the email is invalid", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodPost, "/users/password-reset", strings.NewReader(`{"email":"robertofirmino"}`))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+}
+
+func TestController_ConfirmPasswordReset(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller.Register(&router.RouterGroup)
+
+	t.Run("success", func(t *testing.T) {
+		mockService.confirmResetMock = func(ctx context.Context, request ConfirmPasswordResetRequest) error {
+			assert.Equal(t, ConfirmPasswordResetRequest{Token: "token", Password: e.Password}, request)
+
+			return nil
+		}
+
+		body := fmt.Sprintf(`{"token":"token","password":"%v"}`, e.Password)
+		request, err := http.NewRequest(http.MethodPost, "/users/password-reset/confirm", strings.NewReader(body))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+	})
+
+	t.Run("should return bad request when the token is invalid", func(t *testing.T) {
+		mockService.confirmResetMock = func(ctx context.Context, request ConfirmPasswordResetRequest) error {
+			return invalidPasswordResetTokenError()
+		}
+
+		body := fmt.Sprintf(`{"token":"token","password":"%v"}`, e.Password)
+		request, err := http.NewRequest(http.MethodPost, "/users/password-reset/confirm", strings.NewReader(body))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should return unprocessable entity with the violations when the password is too long", func(t *testing.T) {
+		mockService.confirmResetMock = func(ctx context.Context, request ConfirmPasswordResetRequest) error {
+			assert.Fail(t, "should not reset the password")
+			return nil
+		}
+
+		body := fmt.Sprintf(`{"token":"token","password":"%v"}`, strings.Repeat("p", maxPasswordBytes+1))
+		request, err := http.NewRequest(http.MethodPost, "/users/password-reset/confirm", strings.NewReader(body))
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		var actual struct {
+			Data []FieldViolation `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &actual))
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Equal(t, []FieldViolation{{Field: "password", Rule: "password", Message: "must be at most 72 bytes long"}}, actual.Data)
+	})
+}
+
+func TestController_GetUserHistory(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET(fmt.Sprintf("%v/:id/history", route), controller.GetUserHistory)
+
+	t.Run("success", func(t *testing.T) {
+		expected := UserHistoryResponse{
+			Entries: []AuditEntry{{
+				Id:        1,
+				UserId:    e.Id,
+				Action:    AuditActionUpdated,
+				Changes:   AuditChanges{"country": {Old: "NL", New: e.Country}},
+				Version:   2,
+				CreatedAt: time.Now(),
+			}},
+			Page:    2,
+			PerPage: 5,
+		}
+
+		mockService.historyMock = func(ctx context.Context, request GetUserHistoryRequest) (UserHistoryResponse, error) {
+			assert.Equal(t, GetUserHistoryRequest{Id: e.Id, Page: 2, PerPage: 5}, request)
+
+			return expected, nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%v/history?page=2&perPage=5", e.Id), nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(expected)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodGet, "/users/invalid/history", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestController_GetUserSnapshot(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET(fmt.Sprintf("%v/:id/history/snapshot", route), controller.GetUserSnapshot)
+
+	at := time.Date(2022, 9, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("success", func(t *testing.T) {
+		expected := UserSnapshotResponse{User: User{Id: e.Id, Nickname: e.Nickname, Version: 1}, ChangedAt: at.Add(-time.Hour)}
+
+		mockService.snapshotMock = func(ctx context.Context, request GetUserSnapshotRequest) (UserSnapshotResponse, error) {
+			assert.Equal(t, e.Id, request.Id)
+			assert.True(t, at.Equal(request.At))
+
+			return expected, nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%v/history/snapshot?at=2022-09-01T10:00:00Z", e.Id), nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(expected)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("should compare the time in UTC", func(t *testing.T) {
+		mockService.snapshotMock = func(ctx context.Context, request GetUserSnapshotRequest) (UserSnapshotResponse, error) {
+			assert.Equal(t, at, request.At)
+			assert.Equal(t, time.UTC, request.At.Location())
+
+			return UserSnapshotResponse{}, nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%v/history/snapshot?at=2022-09-01T12:00:00%%2B02:00", e.Id), nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should require the time", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%v/history/snapshot?at=yesterday", e.Id), nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should return not found before the first change", func(t *testing.T) {
+		mockService.snapshotMock = func(ctx context.Context, request GetUserSnapshotRequest) (UserSnapshotResponse, error) {
+			return UserSnapshotResponse{}, userSnapshotNotFoundError(request.Id)
+		}
+
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%v/history/snapshot?at=2022-09-01T10:00:00Z", e.Id), nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestController_GetPersonalData(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET(fmt.Sprintf("%v/:id/personal-data", route), controller.GetPersonalData)
+
+	t.Run("success", func(t *testing.T) {
+		mockService.personalDataMock = func(ctx context.Context, request GetPersonalDataRequest) (PersonalDataResponse, error) {
+			assert.Equal(t, GetPersonalDataRequest{Id: e.Id}, request)
+
+			return PersonalDataResponse{User: User{Id: e.Id}, ExportedAt: time.Now()}, nil
+		}
+
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%v/personal-data", e.Id), nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, mimeZip, rr.Header().Get("Content-Type"))
+		assert.Contains(t, rr.Header().Get("Content-Disposition"), fmt.Sprintf(`filename="user-%s.zip"`, e.Id))
+
+		_, err = zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+		assert.NoError(t, err, "should respond with a zip archive")
+	})
+
+	t.Run("should return not found when the user does not exist", func(t *testing.T) {
+		mockService.personalDataMock = func(ctx context.Context, request GetPersonalDataRequest) (PersonalDataResponse, error) {
+			return PersonalDataResponse{}, userNotFoundError(request.Id)
+		}
+
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%v/personal-data", e.Id), nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Empty(t, rr.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("should abort the connection when the archive cannot be written", func(t *testing.T) {
+		mockService.personalDataMock = func(ctx context.Context, request GetPersonalDataRequest) (PersonalDataResponse, error) {
+			history := []AuditEntry{{Changes: AuditChanges{"first_name": {New: func() {}}}}}
+			return PersonalDataResponse{User: User{Id: e.Id}, History: history, ExportedAt: time.Now()}, nil
+		}
+
+		server := httptest.NewServer(router)
+		defer server.Close()
+
+		resp, err := http.Get(fmt.Sprintf("%v/users/%v/personal-data", server.URL, e.Id))
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		_, err = io.ReadAll(resp.Body)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF, "should not end the truncated archive")
+	})
+}
+
+func TestController_EraseUser(t *testing.T) {
+	mockService := &mockService{}
+	controller := NewController(WithService(mockService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST(fmt.Sprintf("%v/:id/erase", route), controller.EraseUser)
+
+	t.Run("success", func(t *testing.T) {
+		expected := EraseUserResponse{Id: e.Id, ErasedAt: time.Now()}
+
+		mockService.eraseMock = func(ctx context.Context, request EraseUserRequest) (EraseUserResponse, error) {
+			assert.Equal(t, EraseUserRequest{Id: e.Id}, request)
+
+			return expected, nil
+		}
+
+		request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%v/erase", e.Id), nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		respBody, err := json.Marshal(expected)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, respBody, rr.Body.Bytes())
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodPost, "/users/invalid/erase", nil)
+		assert.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
 }
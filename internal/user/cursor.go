@@ -0,0 +1,63 @@
+package user
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+)
+
+// Cursor is a position in the users ordered by the sort, the values are the ones of the sorted columns
+// of the user at the position. The users after the position are returned unless it is backward.
+type Cursor struct {
+	Values   []interface{} `json:"v"`
+	Sort     string        `json:"s"`
+	Backward bool          `json:"b,omitempty"`
+}
+
+// after returns the cursor pointing to the users after the entity.
+func after(entity Entity, sort []SortField) Cursor {
+	return Cursor{Values: sortValues(entity, sort), Sort: sortSpec(sort)}
+}
+
+// before returns the cursor pointing to the users before the entity.
+func before(entity Entity, sort []SortField) Cursor {
+	return Cursor{Values: sortValues(entity, sort), Sort: sortSpec(sort), Backward: true}
+}
+
+// encodeCursor creates the opaque representation of the cursor returned to the clients.
+func encodeCursor(cursor Cursor) string {
+	buf, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// decodeCursor parses the cursor created for the users ordered by the sort,
+// the cursors created for another order are rejected.
+func decodeCursor(value string, sort []SortField) (Cursor, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return Cursor{}, invalidCursorError()
+	}
+
+	var cursor Cursor
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	// the numbers are kept as they are, instead of converting them to float64.
+	decoder.UseNumber()
+
+	err = decoder.Decode(&cursor)
+	if err != nil || cursor.Sort != sortSpec(sort) || len(cursor.Values) != len(sort) {
+		return Cursor{}, invalidCursorError()
+	}
+
+	// the values are passed to the database as text, which converts them to the types of the columns.
+	for i, value := range cursor.Values {
+		switch v := value.(type) {
+		case string:
+		case json.Number:
+			cursor.Values[i] = v.String()
+		default:
+			return Cursor{}, invalidCursorError()
+		}
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,33 @@
+package user
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		sort := []SortField{{Column: "version", Descending: true}, {Column: "nickname"}, {Column: "id"}}
+		expected := before(e, sort)
+
+		actual, err := decodeCursor(encodeCursor(expected), sort)
+		assert.NoError(t, err)
+		assert.Equal(t, []interface{}{"1", e.Nickname, e.Id}, actual.Values, "should decode the values as text")
+		assert.Equal(t, "-version,nickname,id", actual.Sort)
+		assert.True(t, actual.Backward)
+	})
+
+	t.Run("should return error when the cursor is invalid", func(t *testing.T) {
+		sort := normalizeSort(nil)
+		for _, value := range []string{
+			"invalid!",
+			"e30",
+			encodeCursor(Cursor{Values: []interface{}{e.Id}, Sort: sortSpec(sort)}),
+			encodeCursor(Cursor{Values: []interface{}{e.CreatedAt, map[string]string{}}, Sort: sortSpec(sort)}),
+			encodeCursor(after(e, []SortField{{Column: "nickname"}, {Column: "id"}})),
+		} {
+			_, err := decodeCursor(value, sort)
+			assert.EqualValues(t, invalidCursorError(), err, value)
+		}
+	})
+}
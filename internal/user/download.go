@@ -0,0 +1,71 @@
+package user
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+const headerExportError = "X-Export-Error"
+
+// exportFormat returns the format given by the format query parameter, or else negotiated by the Accept header.
+func exportFormat(ctx *gin.Context) (string, error) {
+	if format := ctx.Query("format"); format != "" {
+		if _, ok := exportMediaTypes[format]; !ok {
+			return "", unsupportedExportFormatError(format)
+		}
+		return format, nil
+	}
+
+	switch ctx.NegotiateFormat(mimeCSV, mimeNDJSON, mimeParquet) {
+	case mimeCSV:
+		return ExportFormatCSV, nil
+	case mimeNDJSON:
+		return ExportFormatNDJSON, nil
+	case mimeParquet:
+		return ExportFormatParquet, nil
+	}
+
+	return "", unsupportedExportFormatError(ctx.GetHeader("Accept"))
+}
+
+// exportWriter starts the response on the first write, so that the error response
+// can still be written when the export fails before writing anything.
+type exportWriter struct {
+	ctx     *gin.Context
+	format  string
+	started bool
+}
+
+func (w *exportWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+
+	w.ctx.Header("Content-Type", exportMediaTypes[w.format])
+	w.ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="users.%s"`, w.format))
+	w.ctx.Header("Trailer", headerExportError)
+	w.ctx.Status(http.StatusOK)
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	w.start()
+	return w.ctx.Writer.Write(p)
+}
+
+// abortConnection flushes what is written of the response and closes the connection without ending it. It does
+// nothing when the connection cannot be hijacked, since gin panics when the underlying writer is not a http.Hijacker,
+// as with HTTP/2.
+func abortConnection(ctx *gin.Context) {
+	defer func() {
+		_ = recover()
+	}()
+
+	ctx.Writer.Flush()
+	conn, _, err := ctx.Writer.Hijack()
+	if err != nil {
+		return
+	}
+	_ = conn.Close()
+}
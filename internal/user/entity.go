@@ -3,13 +3,28 @@ package user
 import "time"
 
 type Entity struct {
-	Id        string
-	FirstName string    `db:"first_name"`
-	LastName  string    `db:"last_name"`
-	Nickname  string    `db:"nickname"`
-	Password  string    `db:"password"`
-	Email     string    `db:"email"`
-	Country   string    `db:"country"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	Id              string     `db:"id"`
+	FirstName       string     `db:"first_name"`
+	LastName        string     `db:"last_name"`
+	Nickname        string     `db:"nickname"`
+	Password        string     `db:"password"`
+	Email           string     `db:"email"`
+	Country         string     `db:"country"`
+	Version         int        `db:"version"`
+	CreatedAt       time.Time  `db:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at"`
+	DeletedAt       *time.Time `db:"deleted_at"`
+	EmailVerifiedAt *time.Time `db:"email_verified_at"`
+}
+
+// EntityChange is the state of a user after an update together with its state before the update.
+type EntityChange struct {
+	Entity
+	Previous Entity `db:"previous"`
+}
+
+// EntityMatch is a user found by a search together with its relevance to the search query.
+type EntityMatch struct {
+	Entity
+	Rank float64 `db:"rank"`
 }
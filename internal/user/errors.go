@@ -1,15 +1,242 @@
 package user
 
 import (
+	"errors"
 	"faceit-backend-test/internal/apierr"
+	"fmt"
 	"net/http"
 )
 
+// repositoryError translates the error of the repository, the conflicts are reported to the clients
+// while the other errors are hidden behind a generic message.
 func repositoryError(err error) apierr.ApiError {
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		return userConflictError(conflict.Field)
+	}
+
 	return apierr.ApiError{
 		StatusCode: http.StatusInternalServerError,
 		Code:       "1000",
-		Message:    err.Error(),
+		Message:    "the users cannot be accessed at the moment",
+		Data:       nil,
+		Cause:      err,
+	}
+}
+
+func passwordHashError() apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusInternalServerError,
+		Code:       "1001",
+		Message:    "cannot process the password",
+		Data:       nil,
+	}
+}
+
+func invalidCredentialsError() apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusUnauthorized,
+		Code:       "1002",
+		Message:    "invalid login or password",
+		Data:       nil,
+	}
+}
+
+func userNotFoundError(id string) apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusNotFound,
+		Code:       "1003",
+		Message:    fmt.Sprintf("user %s not found", id),
+		Data:       nil,
+	}
+}
+
+func versionMismatchError(id string) apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusPreconditionFailed,
+		Code:       "1004",
+		Message:    fmt.Sprintf("user %s has been modified", id),
+		Data:       nil,
+	}
+}
+
+func invalidETagError(header string) apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "1005",
+		Message:    fmt.Sprintf("invalid %s header", header),
+		Data:       nil,
+	}
+}
+
+func invalidCursorError() apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "1006",
+		Message:    "invalid cursor",
+		Data:       nil,
+	}
+}
+
+func invalidFilterError(message string) apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "1007",
+		Message:    message,
+		Data:       nil,
+	}
+}
+
+func invalidSearchQueryError() apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "1008",
+		Message:    "search query must contain a letter or a digit",
+		Data:       nil,
+	}
+}
+
+func invalidSortError(message string) apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "1009",
+		Message:    message,
+		Data:       nil,
+	}
+}
+
+func invalidFieldsError(message string) apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "1010",
+		Message:    message,
+		Data:       nil,
+	}
+}
+
+func batchRolledBackError(failed int) apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusFailedDependency,
+		Code:       "1011",
+		Message:    fmt.Sprintf("operation is not applied since operation %d has failed", failed),
+		Data:       nil,
+	}
+}
+
+func unsupportedImportFormatError(format string) apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusUnsupportedMediaType,
+		Code:       "1012",
+		Message:    fmt.Sprintf("unsupported import format %q, the users can be imported from csv or ndjson", format),
+		Data:       nil,
+	}
+}
+
+func invalidImportError(message string) apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "1013",
+		Message:    message,
+		Data:       nil,
+	}
+}
+
+func importJobNotFoundError() apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusNotFound,
+		Code:       "1014",
+		Message:    "import job not found",
+		Data:       nil,
+	}
+}
+
+func unsupportedExportFormatError(format string) apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusNotAcceptable,
+		Code:       "1015",
+		Message:    fmt.Sprintf("unsupported export format %q, the users can be exported as csv, ndjson or parquet", format),
+		Data:       nil,
+	}
+}
+
+func validationError(violations []FieldViolation) apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusUnprocessableEntity,
+		Code:       "1016",
+		Message:    "the request violates the validation rules",
+		Data:       violations,
+	}
+}
+
+func userConflictError(field string) apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusConflict,
+		Code:       "1017",
+		Message:    fmt.Sprintf("a user with the same %s already exists", field),
+		Data:       nil,
+	}
+}
+
+func emailAlreadyVerifiedError(id string) apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusConflict,
+		Code:       "1018",
+		Message:    fmt.Sprintf("email of user %s is already verified", id),
+		Data:       nil,
+	}
+}
+
+func invalidVerificationTokenError() apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "1019",
+		Message:    "invalid or expired verification token",
+		Data:       nil,
+	}
+}
+
+func verificationMailError(err error) apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusServiceUnavailable,
+		Code:       "1020",
+		Message:    "the verification mail cannot be sent at the moment",
+		Data:       nil,
+		Cause:      err,
+	}
+}
+
+func emailNotVerifiedError() apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusForbidden,
+		Code:       "1021",
+		Message:    "email is not verified",
+		Data:       nil,
+	}
+}
+
+func invalidPasswordResetTokenError() apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "1022",
+		Message:    "invalid or expired password reset token",
+		Data:       nil,
+	}
+}
+
+func userSnapshotNotFoundError(id string) apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusNotFound,
+		Code:       "1023",
+		Message:    fmt.Sprintf("no change of the user with id %v is recorded until the time", id),
+		Data:       nil,
+	}
+}
+
+func importTooLargeError(limit int64) apierr.ApiError {
+	return apierr.ApiError{
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Code:       "1024",
+		Message:    fmt.Sprintf("the uploads cannot be larger than %d bytes", limit),
 		Data:       nil,
 	}
 }
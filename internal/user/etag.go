@@ -0,0 +1,52 @@
+package user
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	headerETag        = "ETag"
+	headerIfMatch     = "If-Match"
+	headerIfNoneMatch = "If-None-Match"
+)
+
+// etag creates the entity tag of a user from its version.
+func etag(version int) string {
+	return fmt.Sprintf("\"%d\"", version)
+}
+
+// parseIfMatch returns the version in the If-Match header, 0 is returned
+// when the header is missing or "*" which means that any version is accepted.
+func parseIfMatch(header string) (int, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	// If-Match uses the strong comparison, so the weak tags never match.
+	if !strings.HasPrefix(header, "\"") || !strings.HasSuffix(header, "\"") || len(header) < 2 {
+		return 0, invalidETagError(headerIfMatch)
+	}
+
+	version, err := strconv.Atoi(header[1 : len(header)-1])
+	if err != nil || version < 1 {
+		return 0, invalidETagError(headerIfMatch)
+	}
+
+	return version, nil
+}
+
+// matchesIfNoneMatch reports whether the If-None-Match header contains the entity tag,
+// the tags are compared with the weak comparison.
+func matchesIfNoneMatch(header string, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,41 @@
+package user
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	t.Run("should accept any version when the header is missing", func(t *testing.T) {
+		version, err := parseIfMatch("")
+		assert.NoError(t, err)
+		assert.Equal(t, 0, version)
+	})
+
+	t.Run("should accept any version for wildcard", func(t *testing.T) {
+		version, err := parseIfMatch("*")
+		assert.NoError(t, err)
+		assert.Equal(t, 0, version)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		version, err := parseIfMatch(etag(7))
+		assert.NoError(t, err)
+		assert.Equal(t, 7, version)
+	})
+
+	t.Run("should return error for invalid entity tags", func(t *testing.T) {
+		for _, header := range []string{`7`, `W/"7"`, `"abc"`, `"0"`, `"`} {
+			_, err := parseIfMatch(header)
+			assert.Error(t, err, header)
+		}
+	})
+}
+
+func TestMatchesIfNoneMatch(t *testing.T) {
+	assert.True(t, matchesIfNoneMatch(`"1"`, etag(1)))
+	assert.True(t, matchesIfNoneMatch(`"2", W/"1"`, etag(1)), "should use the weak comparison")
+	assert.True(t, matchesIfNoneMatch("*", etag(1)))
+	assert.False(t, matchesIfNoneMatch(`"2"`, etag(1)))
+	assert.False(t, matchesIfNoneMatch("", etag(1)))
+}
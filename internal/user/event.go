@@ -0,0 +1,95 @@
+package user
+
+import "faceit-backend-test/internal/notify"
+
+var _ notify.Subject = User{}
+var _ notify.Subject = DeleteUserResponse{}
+var _ notify.Subject = EraseUserResponse{}
+
+// UserChangeEvent is the data of the user.update notifications, it contains the updated user
+// together with its previous state and the fields that are changed.
+// The password is not a part of the user model, so its changes are not reported.
+type UserChangeEvent struct {
+	User
+	Previous      User     `json:"previous"`
+	ChangedFields []string `json:"changed_fields"`
+}
+
+func newUserChangeEvent(change EntityChange) UserChangeEvent {
+	current := User{
+		Id:              change.Id,
+		FirstName:       change.FirstName,
+		LastName:        change.LastName,
+		Nickname:        change.Nickname,
+		Email:           change.Email,
+		Country:         change.Country,
+		Version:         change.Version,
+		CreatedAt:       change.CreatedAt,
+		UpdatedAt:       change.UpdatedAt,
+		EmailVerifiedAt: change.EmailVerifiedAt,
+		DeletedAt:       change.DeletedAt,
+	}
+	previous := User{
+		Id:              change.Previous.Id,
+		FirstName:       change.Previous.FirstName,
+		LastName:        change.Previous.LastName,
+		Nickname:        change.Previous.Nickname,
+		Email:           change.Previous.Email,
+		Country:         change.Previous.Country,
+		Version:         change.Previous.Version,
+		CreatedAt:       change.Previous.CreatedAt,
+		UpdatedAt:       change.Previous.UpdatedAt,
+		EmailVerifiedAt: change.Previous.EmailVerifiedAt,
+		DeletedAt:       change.Previous.DeletedAt,
+	}
+
+	return UserChangeEvent{
+		User:          current,
+		Previous:      previous,
+		ChangedFields: changedFields(previous, current),
+	}
+}
+
+// changedFields returns the json names of the user fields having different values,
+// the fields maintained by the database such as version and updated_at are skipped.
+func changedFields(previous User, current User) []string {
+	fields := []string{}
+
+	if previous.FirstName != current.FirstName {
+		fields = append(fields, "first_name")
+	}
+	if previous.LastName != current.LastName {
+		fields = append(fields, "last_name")
+	}
+	if previous.Nickname != current.Nickname {
+		fields = append(fields, "nickname")
+	}
+	if previous.Email != current.Email {
+		fields = append(fields, "email")
+	}
+	if previous.Country != current.Country {
+		fields = append(fields, "country")
+	}
+	if (previous.DeletedAt == nil) != (current.DeletedAt == nil) {
+		fields = append(fields, "deleted_at")
+	}
+	if (previous.EmailVerifiedAt == nil) != (current.EmailVerifiedAt == nil) {
+		fields = append(fields, "email_verified_at")
+	}
+
+	return fields
+}
+
+// NotificationSubject returns the id of the user, so that the notifications about the user can be found.
+// The events embedding the user are about the user as well.
+func (u User) NotificationSubject() string {
+	return u.Id
+}
+
+func (r DeleteUserResponse) NotificationSubject() string {
+	return r.Id
+}
+
+func (r EraseUserResponse) NotificationSubject() string {
+	return r.Id
+}
@@ -0,0 +1,31 @@
+package user
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestChangedFields(t *testing.T) {
+	t.Run("should return the fields having different values", func(t *testing.T) {
+		previous := User{Id: e.Id, Nickname: e.Nickname, Country: "UK", Version: 1}
+		current := User{Id: e.Id, Nickname: "firmino", Country: "BR", Version: 2}
+
+		assert.Equal(t, []string{"nickname", "country"}, changedFields(previous, current))
+	})
+
+	t.Run("should return deleted_at when the user is restored", func(t *testing.T) {
+		deletedAt := time.Now()
+		previous := User{Id: e.Id, DeletedAt: &deletedAt}
+		current := User{Id: e.Id}
+
+		assert.Equal(t, []string{"deleted_at"}, changedFields(previous, current))
+	})
+
+	t.Run("should return an empty list when nothing is changed", func(t *testing.T) {
+		previous := User{Id: e.Id, Version: 1, UpdatedAt: time.Now()}
+		current := User{Id: e.Id, Version: 2, UpdatedAt: time.Now()}
+
+		assert.Empty(t, changedFields(previous, current))
+	})
+}
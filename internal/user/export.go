@@ -0,0 +1,319 @@
+package user
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/xitongsys/parquet-go/writer"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	ExportFormatCSV     = "csv"
+	ExportFormatNDJSON  = "ndjson"
+	ExportFormatParquet = "parquet"
+)
+
+const mimeParquet = "application/vnd.apache.parquet"
+
+// exportMediaTypes are the media types of the export formats.
+var exportMediaTypes = map[string]string{
+	ExportFormatCSV:     mimeCSV,
+	ExportFormatNDJSON:  mimeNDJSON,
+	ExportFormatParquet: mimeParquet,
+}
+
+const (
+	// exportParquetRowGroupSize limits the rows of a parquet export buffered in memory before they are written.
+	exportParquetRowGroupSize = 8 * 1024 * 1024
+	// exportParquetConcurrency is how many goroutines marshal the rows of a parquet export.
+	exportParquetConcurrency = 1
+)
+
+// exportEncoder writes the exported users one by one, close writes what is left once the users are exported.
+type exportEncoder interface {
+	encode(user User) error
+	close() error
+}
+
+// newExportEncoder returns the encoder of the format writing the fields of the users,
+// all the fields are written when none is given.
+func newExportEncoder(format string, w io.Writer, fields []string) (exportEncoder, error) {
+	if len(fields) == 0 {
+		fields = userFieldNames
+	}
+
+	switch format {
+	case ExportFormatCSV:
+		return newCSVExportEncoder(w, fields)
+	case ExportFormatNDJSON:
+		return &ndjsonExportEncoder{encoder: json.NewEncoder(w), fields: fields}, nil
+	case ExportFormatParquet:
+		return newParquetExportEncoder(w, fields)
+	}
+
+	return nil, unsupportedExportFormatError(format)
+}
+
+// fieldValue returns the value of the user field by its json name, nil is returned for the missing timestamps.
+func fieldValue(u User, field string) interface{} {
+	switch field {
+	case "id":
+		return u.Id
+	case "first_name":
+		return u.FirstName
+	case "last_name":
+		return u.LastName
+	case "nickname":
+		return u.Nickname
+	case "email":
+		return u.Email
+	case "country":
+		return u.Country
+	case "version":
+		return u.Version
+	case "created_at":
+		return u.CreatedAt
+	case "updated_at":
+		return u.UpdatedAt
+	case "deleted_at":
+		if u.DeletedAt == nil {
+			return nil
+		}
+		return *u.DeletedAt
+	case "email_verified_at":
+		if u.EmailVerifiedAt == nil {
+			return nil
+		}
+		return *u.EmailVerifiedAt
+	}
+
+	return nil
+}
+
+// csvExportEncoder writes a header naming the fields and a row for each user,
+// the timestamps are written in RFC 3339.
+type csvExportEncoder struct {
+	writer *csv.Writer
+	fields []string
+	record []string
+}
+
+func newCSVExportEncoder(w io.Writer, fields []string) (*csvExportEncoder, error) {
+	e := &csvExportEncoder{writer: csv.NewWriter(w), fields: fields, record: make([]string, len(fields))}
+
+	err := e.writer.Write(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (e *csvExportEncoder) encode(user User) error {
+	for i, field := range e.fields {
+		switch value := fieldValue(user, field).(type) {
+		case nil:
+			e.record[i] = ""
+		case string:
+			e.record[i] = value
+		case int:
+			e.record[i] = strconv.Itoa(value)
+		case time.Time:
+			e.record[i] = value.Format(time.RFC3339Nano)
+		}
+	}
+
+	return e.writer.Write(e.record)
+}
+
+func (e *csvExportEncoder) close() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// ndjsonExportEncoder writes every user as a json object on its own line.
+type ndjsonExportEncoder struct {
+	encoder *json.Encoder
+	fields  []string
+}
+
+func (e *ndjsonExportEncoder) encode(user User) error {
+	object := make(map[string]interface{}, len(e.fields))
+	for _, field := range e.fields {
+		if value := fieldValue(user, field); value != nil {
+			object[field] = value
+		}
+	}
+
+	return e.encoder.Encode(object)
+}
+
+func (e *ndjsonExportEncoder) close() error {
+	return nil
+}
+
+// parquetUser is a row of a parquet export, the timestamps are in milliseconds since the epoch.
+type parquetUser struct {
+	Id              string
+	FirstName       string
+	LastName        string
+	Nickname        string
+	Email           string
+	Country         string
+	Version         int32
+	CreatedAt       int64
+	UpdatedAt       int64
+	DeletedAt       *int64
+	EmailVerifiedAt *int64
+}
+
+// parquetColumns are the parquet schema tags of the user fields, inname is the field of parquetUser.
+var parquetColumns = map[string]string{
+	"id":                "name=id, inname=Id, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=REQUIRED",
+	"first_name":        "name=first_name, inname=FirstName, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=REQUIRED",
+	"last_name":         "name=last_name, inname=LastName, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=REQUIRED",
+	"nickname":          "name=nickname, inname=Nickname, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=REQUIRED",
+	"email":             "name=email, inname=Email, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=REQUIRED",
+	"country":           "name=country, inname=Country, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=REQUIRED",
+	"version":           "name=version, inname=Version, type=INT32, repetitiontype=REQUIRED",
+	"created_at":        "name=created_at, inname=CreatedAt, type=INT64, convertedtype=TIMESTAMP_MILLIS, repetitiontype=REQUIRED",
+	"updated_at":        "name=updated_at, inname=UpdatedAt, type=INT64, convertedtype=TIMESTAMP_MILLIS, repetitiontype=REQUIRED",
+	"deleted_at":        "name=deleted_at, inname=DeletedAt, type=INT64, convertedtype=TIMESTAMP_MILLIS, repetitiontype=OPTIONAL",
+	"email_verified_at": "name=email_verified_at, inname=EmailVerifiedAt, type=INT64, convertedtype=TIMESTAMP_MILLIS, repetitiontype=OPTIONAL",
+}
+
+type parquetSchemaElement struct {
+	Tag    string
+	Fields []parquetSchemaElement `json:",omitempty"`
+}
+
+// parquetSchema returns the json schema of the parquet export having only the fields.
+func parquetSchema(fields []string) (string, error) {
+	schema := parquetSchemaElement{Tag: "name=users, repetitiontype=REQUIRED"}
+	for _, field := range fields {
+		tag, ok := parquetColumns[field]
+		if !ok {
+			return "", fmt.Errorf("unknown user field %s", field)
+		}
+
+		schema.Fields = append(schema.Fields, parquetSchemaElement{Tag: tag})
+	}
+
+	buf, err := json.Marshal(schema)
+	return string(buf), err
+}
+
+// parquetExportEncoder writes the users in row groups, so only the rows of a row group are kept in memory.
+// The writer is created on the first user, since it writes the header as soon as it is created,
+// so that the response is not started before the users are read.
+type parquetExportEncoder struct {
+	w      io.Writer
+	schema string
+	writer *writer.ParquetWriter
+}
+
+func newParquetExportEncoder(w io.Writer, fields []string) (*parquetExportEncoder, error) {
+	schema, err := parquetSchema(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parquetExportEncoder{w: w, schema: schema}, nil
+}
+
+func (e *parquetExportEncoder) start() error {
+	if e.writer != nil {
+		return nil
+	}
+
+	pw, err := writer.NewParquetWriterFromWriter(e.w, e.schema, exportParquetConcurrency)
+	if err != nil {
+		return err
+	}
+	pw.RowGroupSize = exportParquetRowGroupSize
+	e.writer = pw
+
+	return nil
+}
+
+func (e *parquetExportEncoder) encode(user User) error {
+	row := parquetUser{
+		Id:        user.Id,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Nickname:  user.Nickname,
+		Email:     user.Email,
+		Country:   user.Country,
+		Version:   int32(user.Version),
+		CreatedAt: user.CreatedAt.UnixMilli(),
+		UpdatedAt: user.UpdatedAt.UnixMilli(),
+	}
+	if user.DeletedAt != nil {
+		deletedAt := user.DeletedAt.UnixMilli()
+		row.DeletedAt = &deletedAt
+	}
+	if user.EmailVerifiedAt != nil {
+		emailVerifiedAt := user.EmailVerifiedAt.UnixMilli()
+		row.EmailVerifiedAt = &emailVerifiedAt
+	}
+
+	if err := e.start(); err != nil {
+		return err
+	}
+
+	return e.writer.Write(row)
+}
+
+func (e *parquetExportEncoder) close() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+
+	return e.writer.WriteStop()
+}
+
+// Export writes the users matching the filter of the request to w in the format of the request,
+// the users are read from the repository one by one, so that they are not kept in memory.
+func (s *service) Export(ctx context.Context, request ExportUsersRequest, w io.Writer) error {
+	encoder, err := newExportEncoder(request.Format, w, request.Fields)
+	if err != nil {
+		return err
+	}
+
+	parameters := GetManyParameters{
+		Sort:           request.Sort,
+		Fields:         request.Fields,
+		Filter:         request.Filter,
+		IncludeDeleted: request.IncludeDeleted,
+	}
+
+	err = s.repo.Export(ctx, parameters, func(entity Entity) error {
+		return encoder.encode(User{
+			Id:              entity.Id,
+			FirstName:       entity.FirstName,
+			LastName:        entity.LastName,
+			Nickname:        entity.Nickname,
+			Email:           entity.Email,
+			Country:         entity.Country,
+			Version:         entity.Version,
+			CreatedAt:       entity.CreatedAt,
+			UpdatedAt:       entity.UpdatedAt,
+			EmailVerifiedAt: entity.EmailVerifiedAt,
+			DeletedAt:       entity.DeletedAt,
+		})
+	})
+	if err != nil {
+		return repositoryError(err)
+	}
+
+	err = encoder.close()
+	if err != nil {
+		return repositoryError(err)
+	}
+
+	return nil
+}
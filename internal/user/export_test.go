@@ -0,0 +1,109 @@
+package user
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+	"strings"
+	"testing"
+	"time"
+)
+
+var exportedUsers = []User{
+	{
+		Id:        e.Id,
+		FirstName: "Roberto",
+		LastName:  "Firmino",
+		Nickname:  "bobby,firmino",
+		Email:     "robertofirmino@lfc.co.uk",
+		Country:   "UK",
+		Version:   2,
+		CreatedAt: time.Date(2022, 9, 1, 10, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2022, 9, 2, 10, 0, 0, 0, time.UTC),
+	},
+}
+
+func TestCSVExportEncoder(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	encoder, err := newExportEncoder(ExportFormatCSV, buf, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, encoder.encode(exportedUsers[0]))
+	assert.NoError(t, encoder.close())
+
+	expected := "id,first_name,last_name,nickname,email,country,version,created_at,updated_at,deleted_at,email_verified_at\n" +
+		e.Id + `,Roberto,Firmino,"bobby,firmino",robertofirmino@lfc.co.uk,UK,2,2022-09-01T10:00:00Z,2022-09-02T10:00:00Z,,` + "\n"
+	assert.Equal(t, expected, buf.String(), "should export all the fields by default")
+}
+
+func TestNDJSONExportEncoder(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	encoder, err := newExportEncoder(ExportFormatNDJSON, buf, []string{"nickname", "created_at"})
+	assert.NoError(t, err)
+	assert.NoError(t, encoder.encode(exportedUsers[0]))
+	assert.NoError(t, encoder.encode(exportedUsers[0]))
+	assert.NoError(t, encoder.close())
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Len(t, lines, 2)
+
+	var object map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &object))
+	assert.EqualValues(t, map[string]interface{}{"nickname": "bobby,firmino", "created_at": "2022-09-01T10:00:00Z"}, object,
+		"should export only the fields")
+}
+
+func TestParquetExportEncoder(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+
+		encoder, err := newExportEncoder(ExportFormatParquet, buf, []string{"id", "version", "created_at", "deleted_at"})
+		assert.NoError(t, err)
+		assert.NoError(t, encoder.encode(exportedUsers[0]))
+		assert.NoError(t, encoder.close())
+
+		file, err := buffer.NewBufferFile(buf.Bytes())
+		assert.NoError(t, err)
+
+		pr, err := reader.NewParquetReader(file, nil, 1)
+		assert.NoError(t, err)
+		defer pr.ReadStop()
+
+		assert.EqualValues(t, 1, pr.GetNumRows())
+		assert.Len(t, pr.SchemaHandler.ValueColumns, 4, "should export only the fields")
+
+		rows, err := pr.ReadByNumber(1)
+		assert.NoError(t, err)
+
+		row, err := json.Marshal(rows[0])
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"Id":"`+e.Id+`","Version":2,"Created_at":1662026400000,"Deleted_at":null}`, string(row))
+	})
+
+	t.Run("should not write anything before the first user", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+
+		encoder, err := newExportEncoder(ExportFormatParquet, buf, nil)
+		assert.NoError(t, err)
+		assert.Zero(t, buf.Len(), "should not start the response before the users are read")
+
+		assert.NoError(t, encoder.close())
+
+		file, err := buffer.NewBufferFile(buf.Bytes())
+		assert.NoError(t, err)
+
+		pr, err := reader.NewParquetReader(file, nil, 1)
+		assert.NoError(t, err, "should write an empty file when there is no user")
+		defer pr.ReadStop()
+
+		assert.EqualValues(t, 0, pr.GetNumRows())
+	})
+
+	t.Run("should reject the unsupported formats", func(t *testing.T) {
+		_, err := newExportEncoder("xml", &bytes.Buffer{}, nil)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,89 @@
+package user
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// userFieldNames are the json names of the user fields in the order of the User fields,
+// they are the same as the columns of the users.
+var userFieldNames = func() []string {
+	var names []string
+
+	t := reflect.TypeOf(User{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}()
+
+// userFields are the user fields which can be requested by the fields query parameter.
+var userFields = func() map[string]bool {
+	fields := map[string]bool{}
+	for _, name := range userFieldNames {
+		fields[name] = true
+	}
+
+	return fields
+}()
+
+// parseFields parses the comma separated fields of the user requested by the fields query parameter.
+func parseFields(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	fields := strings.Split(value, ",")
+	for _, field := range fields {
+		if !userFields[field] {
+			return nil, invalidFieldsError(fmt.Sprintf("unknown user field %s", field))
+		}
+	}
+
+	return fields, nil
+}
+
+// sparser is a response which can be limited to the requested fields of the users in it.
+type sparser interface {
+	sparse(fields []string) map[string]interface{}
+}
+
+// sparse returns the json object of the user having only the fields.
+func (u User) sparse(fields []string) map[string]interface{} {
+	object := toObject(u)
+
+	sparse := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if value, ok := object[field]; ok {
+			sparse[field] = value
+		}
+	}
+
+	return sparse
+}
+
+// sparse returns the json object of the response whose users have only the fields.
+func (r GetUsersManyResponse) sparse(fields []string) map[string]interface{} {
+	users := make([]map[string]interface{}, len(r.Users))
+	for i, user := range r.Users {
+		users[i] = user.sparse(fields)
+	}
+
+	object := toObject(r)
+	object["users"] = users
+	return object
+}
+
+func toObject(v interface{}) map[string]interface{} {
+	buf, _ := json.Marshal(v)
+
+	var object map[string]interface{}
+	_ = json.Unmarshal(buf, &object)
+	return object
+}
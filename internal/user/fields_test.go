@@ -0,0 +1,29 @@
+package user
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseFields(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		actual, err := parseFields("id,nickname,deleted_at")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"id", "nickname", "deleted_at"}, actual)
+	})
+
+	t.Run("should reject the unknown fields", func(t *testing.T) {
+		for _, value := range []string{"password", "id,", "Nickname"} {
+			_, err := parseFields(value)
+			assert.Error(t, err, value)
+		}
+	})
+}
+
+func TestUser_Sparse(t *testing.T) {
+	user := User{Id: e.Id, Nickname: e.Nickname, Country: e.Country}
+
+	actual := user.sparse([]string{"id", "nickname", "deleted_at"})
+	assert.Equal(t, map[string]interface{}{"id": e.Id, "nickname": e.Nickname}, actual,
+		"should skip the fields omitted when they are empty")
+}
@@ -0,0 +1,229 @@
+package user
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+type FilterOperator string
+
+const (
+	FilterEq     FilterOperator = "eq"
+	FilterNeq    FilterOperator = "neq"
+	FilterGt     FilterOperator = "gt"
+	FilterGte    FilterOperator = "gte"
+	FilterLt     FilterOperator = "lt"
+	FilterLte    FilterOperator = "lte"
+	FilterIn     FilterOperator = "in"
+	FilterPrefix FilterOperator = "prefix"
+	FilterIlike  FilterOperator = "ilike"
+)
+
+// FilterCondition restricts the listed users to the ones whose column matches the values by the operator,
+// only the in operator has more than one value.
+type FilterCondition struct {
+	Column   string
+	Operator FilterOperator
+	Values   []interface{}
+}
+
+// filterColumn describes how a column can be filtered, the values of the column are parsed by parse.
+type filterColumn struct {
+	operators map[FilterOperator]bool
+	parse     func(value string) (interface{}, error)
+}
+
+var (
+	idOperators     = map[FilterOperator]bool{FilterEq: true, FilterNeq: true, FilterIn: true}
+	textOperators   = map[FilterOperator]bool{FilterEq: true, FilterNeq: true, FilterIn: true, FilterPrefix: true, FilterIlike: true}
+	timeOperators   = map[FilterOperator]bool{FilterEq: true, FilterNeq: true, FilterGt: true, FilterGte: true, FilterLt: true, FilterLte: true}
+	filterOperators = map[FilterOperator]string{
+		FilterEq:     "=",
+		FilterNeq:    "<>",
+		FilterGt:     ">",
+		FilterGte:    ">=",
+		FilterLt:     "<",
+		FilterLte:    "<=",
+		FilterIn:     "IN",
+		FilterPrefix: "LIKE",
+		FilterIlike:  "ILIKE",
+	}
+)
+
+// filterColumns is the whitelist of the columns the users can be filtered by.
+var filterColumns = map[string]filterColumn{
+	"id":         {operators: idOperators, parse: parseUUID},
+	"first_name": {operators: textOperators, parse: parseText},
+	"last_name":  {operators: textOperators, parse: parseText},
+	"nickname":   {operators: textOperators, parse: parseText},
+	"email":      {operators: textOperators, parse: parseText},
+	"country":    {operators: textOperators, parse: parseText},
+	"created_at": {operators: timeOperators, parse: parseTime},
+	"updated_at": {operators: timeOperators, parse: parseTime},
+}
+
+func parseUUID(value string) (interface{}, error) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return id.String(), nil
+}
+
+func parseText(value string) (interface{}, error) {
+	return value, nil
+}
+
+func parseTime(value string) (interface{}, error) {
+	return time.Parse(time.RFC3339, value)
+}
+
+// newFilterCondition validates the operator and the values against the column whitelist.
+func newFilterCondition(column string, operator FilterOperator, values []string) (FilterCondition, error) {
+	col, ok := filterColumns[column]
+	if !ok {
+		return FilterCondition{}, invalidFilterError(fmt.Sprintf("users cannot be filtered by %s", column))
+	}
+
+	if !col.operators[operator] {
+		return FilterCondition{}, invalidFilterError(fmt.Sprintf("%s cannot be filtered by the %s operator", column, operator))
+	}
+
+	if len(values) == 0 || (operator != FilterIn && len(values) > 1) {
+		return FilterCondition{}, invalidFilterError(fmt.Sprintf("invalid number of values for %s %s", column, operator))
+	}
+
+	condition := FilterCondition{Column: column, Operator: operator, Values: make([]interface{}, len(values))}
+	for i, value := range values {
+		parsed, err := col.parse(value)
+		if err != nil {
+			return FilterCondition{}, invalidFilterError(fmt.Sprintf("invalid value %q for %s", value, column))
+		}
+
+		condition.Values[i] = parsed
+	}
+
+	return condition, nil
+}
+
+// parseFilterQuery parses the filter[column]=value and filter[column][operator]=value query parameters,
+// the operator is eq when it is not given and the values of the in operator are separated by commas.
+// A condition is created for every value of the other operators, so that they are all applied.
+func parseFilterQuery(query url.Values) ([]FilterCondition, error) {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if strings.HasPrefix(key, "filter[") {
+			keys = append(keys, key)
+		}
+	}
+	// the keys are sorted to keep the order of the conditions deterministic.
+	sort.Strings(keys)
+
+	var conditions []FilterCondition
+	for _, key := range keys {
+		column, operator, ok := parseFilterKey(key)
+		if !ok {
+			return nil, invalidFilterError(fmt.Sprintf("invalid filter parameter %s", key))
+		}
+
+		if operator == FilterIn {
+			var values []string
+			for _, value := range query[key] {
+				values = append(values, strings.Split(value, ",")...)
+			}
+
+			condition, err := newFilterCondition(column, operator, values)
+			if err != nil {
+				return nil, err
+			}
+
+			conditions = append(conditions, condition)
+			continue
+		}
+
+		for _, value := range query[key] {
+			condition, err := newFilterCondition(column, operator, []string{value})
+			if err != nil {
+				return nil, err
+			}
+
+			conditions = append(conditions, condition)
+		}
+	}
+
+	return conditions, nil
+}
+
+// parseFilterKey splits filter[column][operator] into the column and the operator.
+func parseFilterKey(key string) (string, FilterOperator, bool) {
+	rest := strings.TrimPrefix(key, "filter[")
+
+	end := strings.Index(rest, "]")
+	if end < 1 {
+		return "", "", false
+	}
+
+	column, rest := rest[:end], rest[end+1:]
+	if rest == "" {
+		return column, FilterEq, true
+	}
+
+	if !strings.HasPrefix(rest, "[") || !strings.HasSuffix(rest, "]") || len(rest) < 3 {
+		return "", "", false
+	}
+
+	return column, FilterOperator(rest[1 : len(rest)-1]), true
+}
+
+// equalityFilter converts the non-zero fields of the user to eq conditions,
+// it is used by the filter query parameter given as a json object.
+func equalityFilter(user User) ([]FilterCondition, error) {
+	var conditions []FilterCondition
+
+	text := map[string]string{
+		"id":         user.Id,
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+		"nickname":   user.Nickname,
+		"email":      user.Email,
+		"country":    user.Country,
+	}
+	for column, value := range text {
+		if value == "" {
+			continue
+		}
+
+		condition, err := newFilterCondition(column, FilterEq, []string{value})
+		if err != nil {
+			return nil, err
+		}
+
+		conditions = append(conditions, condition)
+	}
+
+	times := map[string]time.Time{
+		"created_at": user.CreatedAt,
+		"updated_at": user.UpdatedAt,
+	}
+	for column, value := range times {
+		if !value.IsZero() {
+			conditions = append(conditions, FilterCondition{Column: column, Operator: FilterEq, Values: []interface{}{value}})
+		}
+	}
+
+	sort.Slice(conditions, func(i, j int) bool {
+		return conditions[i].Column < conditions[j].Column
+	})
+
+	return conditions, nil
+}
+
+// escapeLike escapes the wildcards of the LIKE patterns, so that the value is matched literally.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
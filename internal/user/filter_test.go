@@ -0,0 +1,57 @@
+package user
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+func TestParseFilterQuery(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		query := url.Values{
+			"filter[country]":         {"UK"},
+			"filter[country][in]":     {"UK,DE", "NL"},
+			"filter[nickname][ilike]": {"bob", "fir"},
+			"page":                    {"2"},
+		}
+
+		expected := []FilterCondition{
+			{Column: "country", Operator: FilterEq, Values: []interface{}{"UK"}},
+			{Column: "country", Operator: FilterIn, Values: []interface{}{"UK", "DE", "NL"}},
+			{Column: "nickname", Operator: FilterIlike, Values: []interface{}{"bob"}},
+			{Column: "nickname", Operator: FilterIlike, Values: []interface{}{"fir"}},
+		}
+
+		actual, err := parseFilterQuery(query)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, actual)
+	})
+
+	t.Run("should reject the invalid parameters", func(t *testing.T) {
+		for _, key := range []string{"filter[]", "filter[country][]", "filter[country]in", "filter[country][in][x]"} {
+			_, err := parseFilterQuery(url.Values{key: {"UK"}})
+			assert.Error(t, err, key)
+		}
+	})
+}
+
+func TestEqualityFilter(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		actual, err := equalityFilter(User{Id: e.Id, Country: "UK", CreatedAt: e.CreatedAt})
+		assert.NoError(t, err)
+		assert.EqualValues(t, []FilterCondition{
+			{Column: "country", Operator: FilterEq, Values: []interface{}{"UK"}},
+			{Column: "created_at", Operator: FilterEq, Values: []interface{}{e.CreatedAt}},
+			{Column: "id", Operator: FilterEq, Values: []interface{}{e.Id}},
+		}, actual)
+	})
+
+	t.Run("should reject the invalid id", func(t *testing.T) {
+		_, err := equalityFilter(User{Id: "1"})
+		assert.EqualValues(t, invalidFilterError(`invalid value "1" for id`), err)
+	})
+}
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, `100\%\_a\\b`, escapeLike(`100%_a\b`))
+}
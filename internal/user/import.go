@@ -0,0 +1,485 @@
+package user
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"faceit-backend-test/internal/apierr"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+)
+
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+const (
+	defaultImportBatchSize = 1000
+	// defaultMaxImportSize is the largest upload accepted by the imports.
+	defaultMaxImportSize = 100 * 1024 * 1024
+	// importJobRetention is how long the finished jobs are kept together with their error reports.
+	importJobRetention = 24 * time.Hour
+	// maxImportLineSize is the longest line of a NDJSON upload.
+	maxImportLineSize = 1024 * 1024
+)
+
+// importJob is a running or finished import together with the errors of its rows.
+type importJob struct {
+	ImportJob
+	errors []ImportRowError
+}
+
+// importJobs keeps the import jobs of the instance in memory,
+// so the jobs are lost on a restart and their uploads are not imported anymore.
+type importJobs struct {
+	mu   sync.RWMutex
+	jobs map[string]*importJob
+}
+
+func newImportJobs() *importJobs {
+	return &importJobs{jobs: map[string]*importJob{}}
+}
+
+// add registers a new pending job, the jobs finished before the retention period are removed.
+func (j *importJobs) add(format string) ImportJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	for id, job := range j.jobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > importJobRetention {
+			delete(j.jobs, id)
+		}
+	}
+
+	job := &importJob{ImportJob: ImportJob{
+		Id:        uuid.New().String(),
+		Status:    ImportStatusPending,
+		Format:    format,
+		CreatedAt: now,
+	}}
+	j.jobs[job.Id] = job
+
+	return job.ImportJob
+}
+
+func (j *importJobs) get(id string) (ImportJob, []ImportRowError, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	job, ok := j.jobs[id]
+	if !ok {
+		return ImportJob{}, nil, false
+	}
+
+	rowErrors := make([]ImportRowError, len(job.errors))
+	copy(rowErrors, job.errors)
+
+	return job.ImportJob, rowErrors, true
+}
+
+func (j *importJobs) update(id string, fn func(job *importJob)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if job, ok := j.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+// importRow is a row of an upload, err is set when the row cannot be parsed.
+type importRow struct {
+	number  int
+	request CreateUserRequest
+	err     error
+}
+
+// importReader reads the rows of an upload one by one, io.EOF is returned after the last row.
+type importReader interface {
+	next() (importRow, error)
+}
+
+func newImportReader(format string, r io.Reader) (importReader, error) {
+	switch format {
+	case ImportFormatCSV:
+		return newCSVImportReader(r)
+	case ImportFormatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineSize)
+		return &ndjsonImportReader{scanner: scanner}, nil
+	}
+
+	return nil, unsupportedImportFormatError(format)
+}
+
+// csvImportReader reads the rows of a CSV upload, the first line is the header naming the columns
+// by the fields of CreateUserRequest, the order of the columns doesn't matter.
+type csvImportReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+	row     int
+}
+
+func newCSVImportReader(r io.Reader) (*csvImportReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, invalidImportError("the csv header is missing")
+	}
+	if err != nil {
+		return nil, invalidImportError(err.Error())
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, name := range importColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, invalidImportError(fmt.Sprintf("the csv header is missing the %s column", name))
+		}
+	}
+
+	return &csvImportReader{reader: reader, columns: columns}, nil
+}
+
+// importColumns are the columns of a CSV upload.
+var importColumns = []string{"first_name", "last_name", "nickname", "password", "email", "country"}
+
+func (r *csvImportReader) next() (importRow, error) {
+	record, err := r.reader.Read()
+	if errors.Is(err, io.EOF) {
+		return importRow{}, io.EOF
+	}
+
+	r.row++
+	row := importRow{number: r.row}
+	if err != nil {
+		var parseErr *csv.ParseError
+		if !errors.As(err, &parseErr) {
+			return importRow{}, err
+		}
+
+		row.err = parseErr.Err
+		return row, nil
+	}
+
+	if len(record) != len(r.columns) {
+		row.err = fmt.Errorf("the row has %d columns instead of %d", len(record), len(r.columns))
+		return row, nil
+	}
+
+	row.request = CreateUserRequest{
+		FirstName: record[r.columns["first_name"]],
+		LastName:  record[r.columns["last_name"]],
+		Nickname:  record[r.columns["nickname"]],
+		Password:  record[r.columns["password"]],
+		Email:     record[r.columns["email"]],
+		Country:   record[r.columns["country"]],
+	}
+
+	return row, nil
+}
+
+// ndjsonImportReader reads the rows of a NDJSON upload, every line is a CreateUserRequest,
+// the empty lines are skipped but counted, so that the rows are numbered by their lines.
+type ndjsonImportReader struct {
+	scanner *bufio.Scanner
+	row     int
+}
+
+func (r *ndjsonImportReader) next() (importRow, error) {
+	for r.scanner.Scan() {
+		r.row++
+
+		line := r.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		row := importRow{number: r.row}
+		row.err = json.Unmarshal(line, &row.request)
+
+		return row, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return importRow{}, err
+	}
+
+	return importRow{}, io.EOF
+}
+
+// Import stores the upload in a temporary file and imports its users in the background,
+// the returned job is used to follow the progress of the import.
+func (s *service) Import(ctx context.Context, request ImportUsersRequest) (ImportJobResponse, error) {
+	if request.Format != ImportFormatCSV && request.Format != ImportFormatNDJSON {
+		return ImportJobResponse{}, unsupportedImportFormatError(request.Format)
+	}
+
+	file, err := os.CreateTemp("", "users-import-*")
+	if err != nil {
+		return ImportJobResponse{}, apierr.InternalServerError()
+	}
+
+	_, err = io.Copy(file, request.File)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return ImportJobResponse{}, importTooLargeError(tooLarge.Limit)
+		}
+		return ImportJobResponse{}, invalidImportError(err.Error())
+	}
+
+	job := s.imports.add(request.Format)
+	// the import outlives the request, so it doesn't use its context, only the actor and the request id are kept for the audit.
+	importCtx := detachedContext(ctx)
+
+	go func() {
+		defer os.Remove(file.Name())
+		defer file.Close()
+
+		s.runImport(importCtx, job.Id, request.Format, file)
+	}()
+
+	return ImportJobResponse{ImportJob: job}, nil
+}
+
+// GetImport returns the progress of the import job.
+func (s *service) GetImport(ctx context.Context, request GetImportRequest) (ImportJobResponse, error) {
+	job, _, ok := s.imports.get(request.Id)
+	if !ok {
+		return ImportJobResponse{}, importJobNotFoundError()
+	}
+
+	return ImportJobResponse{ImportJob: job}, nil
+}
+
+// GetImportErrors returns the rows of the import job that have not been imported so far.
+func (s *service) GetImportErrors(ctx context.Context, request GetImportRequest) (ImportErrorsResponse, error) {
+	_, rowErrors, ok := s.imports.get(request.Id)
+	if !ok {
+		return ImportErrorsResponse{}, importJobNotFoundError()
+	}
+
+	return ImportErrorsResponse{Errors: rowErrors}, nil
+}
+
+// runImport validates the rows like the create endpoint does and inserts the valid ones in batches.
+func (s *service) runImport(ctx context.Context, id string, format string, r io.Reader) {
+	s.imports.update(id, func(job *importJob) {
+		job.Status = ImportStatusRunning
+	})
+
+	err := s.importRows(ctx, id, format, r)
+
+	s.imports.update(id, func(job *importJob) {
+		now := time.Now()
+		job.FinishedAt = &now
+		job.Status = ImportStatusCompleted
+		if err != nil {
+			job.Status = ImportStatusFailed
+			job.Error = err.Error()
+		}
+	})
+}
+
+func (s *service) importRows(ctx context.Context, id string, format string, r io.Reader) error {
+	reader, err := newImportReader(format, r)
+	if err != nil {
+		return err
+	}
+
+	batchSize := s.importBatchSize
+	if batchSize < 1 {
+		batchSize = defaultImportBatchSize
+	}
+
+	rows := make([]int, 0, batchSize)
+	batch := make([]Entity, 0, batchSize)
+
+	for {
+		row, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		entity, err := s.importEntity(row)
+		if err != nil {
+			s.imports.update(id, func(job *importJob) {
+				job.Processed++
+				job.Failed++
+				job.errors = append(job.errors, ImportRowError{Row: row.number, Error: err.Error()})
+			})
+			continue
+		}
+
+		rows = append(rows, row.number)
+		batch = append(batch, entity)
+		if len(batch) == batchSize {
+			s.importBatch(ctx, id, rows, batch)
+			rows, batch = rows[:0], batch[:0]
+		}
+	}
+
+	if len(batch) > 0 {
+		s.importBatch(ctx, id, rows, batch)
+	}
+
+	return nil
+}
+
+// importEntity normalizes and validates the row like CreateUserRequest and creates the entity to be inserted,
+// all the violations of the row are reported together. The password is left in plain text to be hashed together
+// with the rest of the batch.
+func (s *service) importEntity(row importRow) (Entity, error) {
+	if row.err != nil {
+		return Entity{}, row.err
+	}
+
+	row.request.normalize()
+	err := validate.Struct(&row.request)
+	if err != nil {
+		return Entity{}, violationsError(err)
+	}
+
+	// the ids and the timestamps are set here since they are not returned by COPY.
+	now := time.Now().UTC()
+	return Entity{
+		Id:        uuid.New().String(),
+		FirstName: row.request.FirstName,
+		LastName:  row.request.LastName,
+		Nickname:  row.request.Nickname,
+		Password:  row.request.Password,
+		Email:     row.request.Email,
+		Country:   row.request.Country,
+		Version:   1,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// importBatch hashes the passwords of the batch in parallel and inserts the batch at once, when it fails
+// the users are inserted one by one to find out the rows causing the failure.
+func (s *service) importBatch(ctx context.Context, id string, rows []int, batch []Entity) {
+	rows, batch = s.hashBatch(id, rows, batch)
+	if len(batch) == 0 {
+		return
+	}
+
+	err := s.createMany(ctx, batch)
+	if err == nil {
+		s.imported(id, batch)
+		return
+	}
+
+	for i, entity := range batch {
+		err := s.createMany(ctx, []Entity{entity})
+		if err != nil {
+			s.imports.update(id, func(job *importJob) {
+				job.Processed++
+				job.Failed++
+				job.errors = append(job.errors, ImportRowError{Row: rows[i], Error: err.Error()})
+			})
+			continue
+		}
+
+		s.imported(id, []Entity{entity})
+	}
+}
+
+// hashBatch replaces the passwords of the batch by their hashes, the rows whose passwords cannot be hashed
+// are reported and left out of the batch.
+func (s *service) hashBatch(id string, rows []int, batch []Entity) ([]int, []Entity) {
+	passwords := make([]string, len(batch))
+	for i, entity := range batch {
+		passwords[i] = entity.Password
+	}
+
+	hashes, errs := hashPasswords(s.hasher, passwords)
+
+	hashedRows := make([]int, 0, len(rows))
+	hashed := make([]Entity, 0, len(batch))
+	for i, entity := range batch {
+		if errs[i] != nil {
+			s.imports.update(id, func(job *importJob) {
+				job.Processed++
+				job.Failed++
+				job.errors = append(job.errors, ImportRowError{Row: rows[i], Error: passwordHashError().Error()})
+			})
+			continue
+		}
+
+		entity.Password = hashes[i]
+		hashedRows = append(hashedRows, rows[i])
+		hashed = append(hashed, entity)
+	}
+
+	return hashedRows, hashed
+}
+
+// createMany inserts the users and appends their audit entries in a transaction.
+func (s *service) createMany(ctx context.Context, entities []Entity) error {
+	return s.repo.Transaction(ctx, func(repo Repository) error {
+		err := repo.CreateMany(ctx, entities)
+		if err != nil {
+			return err
+		}
+
+		audits := make([]AuditEntity, len(entities))
+		for i, entity := range entities {
+			audits[i] = newAuditEntity(ctx, AuditActionCreated, entity, createdChanges(entity))
+		}
+
+		return repo.AppendAudit(ctx, audits...)
+	})
+}
+
+func (s *service) imported(id string, entities []Entity) {
+	s.imports.update(id, func(job *importJob) {
+		job.Processed += len(entities)
+		job.Imported += len(entities)
+	})
+
+	for _, entity := range entities {
+		s.broker.Publish(UserCreatedTopic, User{
+			Id:              entity.Id,
+			FirstName:       entity.FirstName,
+			LastName:        entity.LastName,
+			Nickname:        entity.Nickname,
+			Email:           entity.Email,
+			Country:         entity.Country,
+			Version:         entity.Version,
+			CreatedAt:       entity.CreatedAt,
+			UpdatedAt:       entity.UpdatedAt,
+			EmailVerifiedAt: entity.EmailVerifiedAt,
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package user
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+func readImportRows(t *testing.T, reader importReader) []importRow {
+	var rows []importRow
+	for {
+		row, err := reader.next()
+		if err == io.EOF {
+			return rows
+		}
+		assert.NoError(t, err)
+
+		rows = append(rows, row)
+	}
+}
+
+func TestCSVImportReader(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		upload := "country,nickname,first_name,last_name,email,password\n" +
+			"UK,bobby.firmino,Roberto,Firmino,robertofirmino@lfc.co.uk,liverpool321\n" +
+			"UK,mo\n"
+
+		reader, err := newImportReader(ImportFormatCSV, strings.NewReader(upload))
+		assert.NoError(t, err)
+
+		rows := readImportRows(t, reader)
+		assert.Len(t, rows, 2)
+		assert.NoError(t, rows[0].err)
+		assert.Equal(t, 1, rows[0].number)
+		assert.EqualValues(t, CreateUserRequest{
+			FirstName: "Roberto",
+			LastName:  "Firmino",
+			Nickname:  "bobby.firmino",
+			Password:  "liverpool321",
+			Email:     "robertofirmino@lfc.co.uk",
+			Country:   "UK",
+		}, rows[0].request, "should match the columns by the header")
+		assert.Error(t, rows[1].err, "should reject the rows missing a column")
+		assert.Equal(t, 2, rows[1].number)
+	})
+
+	t.Run("should reject the header missing a column", func(t *testing.T) {
+		_, err := newImportReader(ImportFormatCSV, strings.NewReader("first_name,last_name\nRoberto,Firmino\n"))
+		assert.Error(t, err)
+	})
+
+	t.Run("should reject the empty upload", func(t *testing.T) {
+		_, err := newImportReader(ImportFormatCSV, strings.NewReader(""))
+		assert.Error(t, err)
+	})
+}
+
+func TestNDJSONImportReader(t *testing.T) {
+	upload := `{"first_name":"Roberto","last_name":"Firmino","nickname":"bobby.firmino","password":"liverpool321","email":"robertofirmino@lfc.co.uk","country":"UK"}` +
+		"\n\n{\"first_name\": \n"
+
+	reader, err := newImportReader(ImportFormatNDJSON, strings.NewReader(upload))
+	assert.NoError(t, err)
+
+	rows := readImportRows(t, reader)
+	assert.Len(t, rows, 2, "should skip the empty lines")
+	assert.NoError(t, rows[0].err)
+	assert.Equal(t, "bobby.firmino", rows[0].request.Nickname)
+	assert.Error(t, rows[1].err)
+	assert.Equal(t, 3, rows[1].number, "should number the rows by their lines")
+}
@@ -0,0 +1,58 @@
+package user
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const headerLink = "Link"
+
+// linkHeader creates the RFC 8288 links of the pages around the listed one,
+// the query parameters of the request other than the pagination ones are kept.
+// The pages are linked by number, unless the listed page is requested by a cursor.
+func linkHeader(u url.URL, req GetUsersManyRequest, resp GetUsersManyResponse) string {
+	query := u.Query()
+	query.Del("page")
+	query.Del("cursor")
+
+	link := func(rel string, key string, value string) string {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		q.Set(key, value)
+
+		u.RawQuery = q.Encode()
+		return fmt.Sprintf(`<%s>; rel="%s"`, u.String(), rel)
+	}
+
+	links := []string{link("first", "page", "1")}
+
+	if req.Cursor != "" {
+		if resp.Prev != "" {
+			links = append(links, link("prev", "cursor", resp.Prev))
+		}
+		if resp.Next != "" {
+			links = append(links, link("next", "cursor", resp.Next))
+		}
+	} else {
+		if req.Page > 1 {
+			links = append(links, link("prev", "page", strconv.Itoa(req.Page-1)))
+		}
+		if resp.Next != "" {
+			links = append(links, link("next", "page", strconv.Itoa(req.Page+1)))
+		}
+	}
+
+	if resp.TotalPages != nil {
+		last := *resp.TotalPages
+		if last < 1 {
+			last = 1
+		}
+		links = append(links, link("last", "page", strconv.Itoa(last)))
+	}
+
+	return strings.Join(links, ", ")
+}
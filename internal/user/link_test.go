@@ -0,0 +1,27 @@
+package user
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+func TestLinkHeader(t *testing.T) {
+	t.Run("should link the pages by number", func(t *testing.T) {
+		u, _ := url.Parse("/users?page=1&perPage=10&filter=%7B%7D")
+		total, totalPages := 0, 0
+
+		actual := linkHeader(*u, GetUsersManyRequest{Page: 1, PerPage: 10}, GetUsersManyResponse{Total: &total, TotalPages: &totalPages})
+		assert.Equal(t, `</users?filter=%7B%7D&page=1&perPage=10>; rel="first", `+
+			`</users?filter=%7B%7D&page=1&perPage=10>; rel="last"`, actual)
+	})
+
+	t.Run("should link the pages by cursor", func(t *testing.T) {
+		u, _ := url.Parse("/users?cursor=current&perPage=10&skipTotal=true")
+
+		actual := linkHeader(*u, GetUsersManyRequest{Cursor: "current", PerPage: 10, SkipTotal: true}, GetUsersManyResponse{Next: "next", Prev: "prev"})
+		assert.Equal(t, `</users?page=1&perPage=10&skipTotal=true>; rel="first", `+
+			`</users?cursor=prev&perPage=10&skipTotal=true>; rel="prev", `+
+			`</users?cursor=next&perPage=10&skipTotal=true>; rel="next"`, actual)
+	})
+}
@@ -2,7 +2,9 @@ package user
 
 import (
 	"context"
+	"errors"
 	"github.com/sirupsen/logrus"
+	"io"
 	"time"
 )
 
@@ -28,7 +30,7 @@ func (s *serviceLoggingMiddleware) Create(ctx context.Context, request CreateUse
 	s.logger.WithFields(logrus.Fields{
 		"service":  "UserService",
 		"endpoint": "Create",
-		"request":  request,
+		"request":  request.redacted(),
 	}).Debug("received request")
 	var resp CreateUserResponse
 	var err error
@@ -39,7 +41,7 @@ func (s *serviceLoggingMiddleware) Create(ctx context.Context, request CreateUse
 			"took":     time.Since(start).String(),
 		})
 		if err != nil {
-			logger.WithField("error", err).Errorln("an error occurred")
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
 			return
 		}
 
@@ -53,7 +55,7 @@ func (s *serviceLoggingMiddleware) Update(ctx context.Context, request UpdateUse
 	s.logger.WithFields(logrus.Fields{
 		"service":  "UserService",
 		"endpoint": "Update",
-		"request":  request,
+		"request":  request.redacted(),
 	}).Debug("received request")
 	var resp UpdateUserResponse
 	var err error
@@ -64,7 +66,7 @@ func (s *serviceLoggingMiddleware) Update(ctx context.Context, request UpdateUse
 			"took":     time.Since(start).String(),
 		})
 		if err != nil {
-			logger.WithField("error", err).Errorln("an error occurred")
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
 			return
 		}
 
@@ -74,6 +76,31 @@ func (s *serviceLoggingMiddleware) Update(ctx context.Context, request UpdateUse
 	return resp, err
 }
 
+func (s *serviceLoggingMiddleware) Patch(ctx context.Context, request PatchUserRequest) (UpdateUserResponse, error) {
+	s.logger.WithFields(logrus.Fields{
+		"service":  "UserService",
+		"endpoint": "Patch",
+		"request":  request.redacted(),
+	}).Debug("received request")
+	var resp UpdateUserResponse
+	var err error
+	defer func(start time.Time) {
+		logger := s.logger.WithFields(logrus.Fields{
+			"service":  "UserService",
+			"endpoint": "Patch",
+			"took":     time.Since(start).String(),
+		})
+		if err != nil {
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
+			return
+		}
+
+		logger.WithField("response", resp).Debug()
+	}(time.Now())
+	resp, err = s.next.Patch(ctx, request)
+	return resp, err
+}
+
 func (s *serviceLoggingMiddleware) DeleteById(ctx context.Context, request DeleteUserByIdRequest) (DeleteUserResponse, error) {
 	s.logger.WithFields(logrus.Fields{
 		"service":  "UserService",
@@ -89,7 +116,7 @@ func (s *serviceLoggingMiddleware) DeleteById(ctx context.Context, request Delet
 			"took":     time.Since(start).String(),
 		})
 		if err != nil {
-			logger.WithField("error", err).Errorln("an error occurred")
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
 			return
 		}
 
@@ -99,6 +126,31 @@ func (s *serviceLoggingMiddleware) DeleteById(ctx context.Context, request Delet
 	return resp, err
 }
 
+func (s *serviceLoggingMiddleware) Restore(ctx context.Context, request RestoreUserRequest) (RestoreUserResponse, error) {
+	s.logger.WithFields(logrus.Fields{
+		"service":  "UserService",
+		"endpoint": "Restore",
+		"request":  request,
+	}).Debug("received request")
+	var resp RestoreUserResponse
+	var err error
+	defer func(start time.Time) {
+		logger := s.logger.WithFields(logrus.Fields{
+			"service":  "UserService",
+			"endpoint": "Restore",
+			"took":     time.Since(start).String(),
+		})
+		if err != nil {
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
+			return
+		}
+
+		logger.WithField("response", resp).Debug()
+	}(time.Now())
+	resp, err = s.next.Restore(ctx, request)
+	return resp, err
+}
+
 func (s *serviceLoggingMiddleware) GetMany(ctx context.Context, request GetUsersManyRequest) (GetUsersManyResponse, error) {
 	s.logger.WithFields(logrus.Fields{
 		"service":  "UserService",
@@ -114,7 +166,7 @@ func (s *serviceLoggingMiddleware) GetMany(ctx context.Context, request GetUsers
 			"took":     time.Since(start).String(),
 		})
 		if err != nil {
-			logger.WithField("error", err).Errorln("an error occurred")
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
 			return
 		}
 
@@ -123,3 +175,415 @@ func (s *serviceLoggingMiddleware) GetMany(ctx context.Context, request GetUsers
 	resp, err = s.next.GetMany(ctx, request)
 	return resp, err
 }
+
+func (s *serviceLoggingMiddleware) Search(ctx context.Context, request SearchUsersRequest) (SearchUsersResponse, error) {
+	s.logger.WithFields(logrus.Fields{
+		"service":  "UserService",
+		"endpoint": "Search",
+		"request":  request,
+	}).Debug("received request")
+	var resp SearchUsersResponse
+	var err error
+	defer func(start time.Time) {
+		logger := s.logger.WithFields(logrus.Fields{
+			"service":  "UserService",
+			"endpoint": "Search",
+			"took":     time.Since(start).String(),
+		})
+		if err != nil {
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
+			return
+		}
+
+		logger.WithField("response", resp).Debug()
+	}(time.Now())
+	resp, err = s.next.Search(ctx, request)
+	return resp, err
+}
+
+func (s *serviceLoggingMiddleware) SendVerification(ctx context.Context, request SendVerificationRequest) (VerificationResponse, error) {
+	s.logger.WithFields(logrus.Fields{
+		"service":  "UserService",
+		"endpoint": "SendVerification",
+		"request":  request,
+	}).Debug("received request")
+	var resp VerificationResponse
+	var err error
+	defer func(start time.Time) {
+		logger := s.logger.WithFields(logrus.Fields{
+			"service":  "UserService",
+			"endpoint": "SendVerification",
+			"took":     time.Since(start).String(),
+		})
+		if err != nil {
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
+			return
+		}
+
+		logger.WithField("response", resp).Debug()
+	}(time.Now())
+	resp, err = s.next.SendVerification(ctx, request)
+	return resp, err
+}
+
+func (s *serviceLoggingMiddleware) VerifyEmail(ctx context.Context, request VerifyEmailRequest) (VerifyEmailResponse, error) {
+	s.logger.WithFields(logrus.Fields{
+		"service":  "UserService",
+		"endpoint": "VerifyEmail",
+		"request":  request.redacted(),
+	}).Debug("received request")
+	var resp VerifyEmailResponse
+	var err error
+	defer func(start time.Time) {
+		logger := s.logger.WithFields(logrus.Fields{
+			"service":  "UserService",
+			"endpoint": "VerifyEmail",
+			"took":     time.Since(start).String(),
+		})
+		if err != nil {
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
+			return
+		}
+
+		logger.WithField("response", resp).Debug()
+	}(time.Now())
+	resp, err = s.next.VerifyEmail(ctx, request)
+	return resp, err
+}
+
+func (s *serviceLoggingMiddleware) RequestPasswordReset(ctx context.Context, request PasswordResetRequest) error {
+	s.logger.WithFields(logrus.Fields{
+		"service":  "UserService",
+		"endpoint": "RequestPasswordReset",
+		"request":  request,
+	}).Debug("received request")
+	var err error
+	defer func(start time.Time) {
+		logger := s.logger.WithFields(logrus.Fields{
+			"service":  "UserService",
+			"endpoint": "RequestPasswordReset",
+			"took":     time.Since(start).String(),
+		})
+		if err != nil {
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
+			return
+		}
+
+		logger.Debug()
+	}(time.Now())
+	err = s.next.RequestPasswordReset(ctx, request)
+	return err
+}
+
+func (s *serviceLoggingMiddleware) ConfirmPasswordReset(ctx context.Context, request ConfirmPasswordResetRequest) error {
+	s.logger.WithFields(logrus.Fields{
+		"service":  "UserService",
+		"endpoint": "ConfirmPasswordReset",
+		"request":  request.redacted(),
+	}).Debug("received request")
+	var err error
+	defer func(start time.Time) {
+		logger := s.logger.WithFields(logrus.Fields{
+			"service":  "UserService",
+			"endpoint": "ConfirmPasswordReset",
+			"took":     time.Since(start).String(),
+		})
+		if err != nil {
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
+			return
+		}
+
+		logger.Debug()
+	}(time.Now())
+	err = s.next.ConfirmPasswordReset(ctx, request)
+	return err
+}
+
+func (s *serviceLoggingMiddleware) GetHistory(ctx context.Context, request GetUserHistoryRequest) (UserHistoryResponse, error) {
+	s.logger.WithFields(logrus.Fields{
+		"service":  "UserService",
+		"endpoint": "GetHistory",
+		"request":  request,
+	}).Debug("received request")
+	var resp UserHistoryResponse
+	var err error
+	defer func(start time.Time) {
+		logger := s.logger.WithFields(logrus.Fields{
+			"service":  "UserService",
+			"endpoint": "GetHistory",
+			"took":     time.Since(start).String(),
+		})
+		if err != nil {
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
+			return
+		}
+
+		logger.WithField("response", resp).Debug()
+	}(time.Now())
+	resp, err = s.next.GetHistory(ctx, request)
+	return resp, err
+}
+
+func (s *serviceLoggingMiddleware) GetSnapshot(ctx context.Context, request GetUserSnapshotRequest) (UserSnapshotResponse, error) {
+	s.logger.WithFields(logrus.Fields{
+		"service":  "UserService",
+		"endpoint": "GetSnapshot",
+		"request":  request,
+	}).Debug("received request")
+	var resp UserSnapshotResponse
+	var err error
+	defer func(start time.Time) {
+		logger := s.logger.WithFields(logrus.Fields{
+			"service":  "UserService",
+			"endpoint": "GetSnapshot",
+			"took":     time.Since(start).String(),
+		})
+		if err != nil {
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
+			return
+		}
+
+		logger.WithField("response", resp).Debug()
+	}(time.Now())
+	resp, err = s.next.GetSnapshot(ctx, request)
+	return resp, err
+}
+
+func (s *serviceLoggingMiddleware) CheckNicknameAvailability(ctx context.Context, request NicknameAvailabilityRequest) (NicknameAvailabilityResponse, error) {
+	s.logger.WithFields(logrus.Fields{
+		"service":  "UserService",
+		"endpoint": "CheckNicknameAvailability",
+		"request":  request,
+	}).Debug("received request")
+	var resp NicknameAvailabilityResponse
+	var err error
+	defer func(start time.Time) {
+		logger := s.logger.WithFields(logrus.Fields{
+			"service":  "UserService",
+			"endpoint": "CheckNicknameAvailability",
+			"took":     time.Since(start).String(),
+		})
+		if err != nil {
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
+			return
+		}
+
+		logger.WithField("response", resp).Debug()
+	}(time.Now())
+	resp, err = s.next.CheckNicknameAvailability(ctx, request)
+	return resp, err
+}
+
+func (s *serviceLoggingMiddleware) Batch(ctx context.Context, request BatchUsersRequest) (BatchUsersResponse, error) {
+	s.logger.WithFields(logrus.Fields{
+		"service":  "UserService",
+		"endpoint": "Batch",
+		"request":  request.redacted(),
+	}).Debug("received request")
+	var resp BatchUsersResponse
+	var err error
+	defer func(start time.Time) {
+		logger := s.logger.WithFields(logrus.Fields{
+			"service":  "UserService",
+			"endpoint": "Batch",
+			"took":     time.Since(start).String(),
+		})
+		if err != nil {
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
+			return
+		}
+
+		logger.WithField("response", resp).Debug()
+	}(time.Now())
+	resp, err = s.next.Batch(ctx, request)
+	return resp, err
+}
+
+func (s *serviceLoggingMiddleware) Import(ctx context.Context, request ImportUsersRequest) (ImportJobResponse, error) {
+	s.logger.WithFields(logrus.Fields{
+		"service":  "UserService",
+		"endpoint": "Import",
+		"request":  request.redacted(),
+	}).Debug("received request")
+	var resp ImportJobResponse
+	var err error
+	defer func(start time.Time) {
+		logger := s.logger.WithFields(logrus.Fields{
+			"service":  "UserService",
+			"endpoint": "Import",
+			"took":     time.Since(start).String(),
+		})
+		if err != nil {
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
+			return
+		}
+
+		logger.WithField("response", resp).Debug()
+	}(time.Now())
+	resp, err = s.next.Import(ctx, request)
+	return resp, err
+}
+
+func (s *serviceLoggingMiddleware) GetImport(ctx context.Context, request GetImportRequest) (ImportJobResponse, error) {
+	s.logger.WithFields(logrus.Fields{
+		"service":  "UserService",
+		"endpoint": "GetImport",
+		"request":  request,
+	}).Debug("received request")
+	var resp ImportJobResponse
+	var err error
+	defer func(start time.Time) {
+		logger := s.logger.WithFields(logrus.Fields{
+			"service":  "UserService",
+			"endpoint": "GetImport",
+			"took":     time.Since(start).String(),
+		})
+		if err != nil {
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
+			return
+		}
+
+		logger.WithField("response", resp).Debug()
+	}(time.Now())
+	resp, err = s.next.GetImport(ctx, request)
+	return resp, err
+}
+
+func (s *serviceLoggingMiddleware) GetImportErrors(ctx context.Context, request GetImportRequest) (ImportErrorsResponse, error) {
+	s.logger.WithFields(logrus.Fields{
+		"service":  "UserService",
+		"endpoint": "GetImportErrors",
+		"request":  request,
+	}).Debug("received request")
+	var resp ImportErrorsResponse
+	var err error
+	defer func(start time.Time) {
+		logger := s.logger.WithFields(logrus.Fields{
+			"service":  "UserService",
+			"endpoint": "GetImportErrors",
+			"took":     time.Since(start).String(),
+		})
+		if err != nil {
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
+			return
+		}
+
+		logger.WithField("response", resp).Debug()
+	}(time.Now())
+	resp, err = s.next.GetImportErrors(ctx, request)
+	return resp, err
+}
+
+func (s *serviceLoggingMiddleware) Export(ctx context.Context, request ExportUsersRequest, w io.Writer) error {
+	s.logger.WithFields(logrus.Fields{
+		"service":  "UserService",
+		"endpoint": "Export",
+		"request":  request,
+	}).Debug("received request")
+	var err error
+	defer func(start time.Time) {
+		logger := s.logger.WithFields(logrus.Fields{
+			"service":  "UserService",
+			"endpoint": "Export",
+			"took":     time.Since(start).String(),
+		})
+		if err != nil {
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
+			return
+		}
+
+		logger.Debug()
+	}(time.Now())
+	err = s.next.Export(ctx, request, w)
+	return err
+}
+
+func (s *serviceLoggingMiddleware) ExportPersonalData(ctx context.Context, request GetPersonalDataRequest) (PersonalDataResponse, error) {
+	s.logger.WithFields(logrus.Fields{
+		"service":  "UserService",
+		"endpoint": "ExportPersonalData",
+		"request":  request,
+	}).Debug("received request")
+	var resp PersonalDataResponse
+	var err error
+	defer func(start time.Time) {
+		logger := s.logger.WithFields(logrus.Fields{
+			"service":  "UserService",
+			"endpoint": "ExportPersonalData",
+			"took":     time.Since(start).String(),
+		})
+		if err != nil {
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
+			return
+		}
+
+		// the response is the personal data of the user, so only how much of it is exported is logged.
+		logger.WithFields(logrus.Fields{
+			"history":    len(resp.History),
+			"deliveries": len(resp.Deliveries),
+		}).Debug()
+	}(time.Now())
+	resp, err = s.next.ExportPersonalData(ctx, request)
+	return resp, err
+}
+
+func (s *serviceLoggingMiddleware) Erase(ctx context.Context, request EraseUserRequest) (EraseUserResponse, error) {
+	s.logger.WithFields(logrus.Fields{
+		"service":  "UserService",
+		"endpoint": "Erase",
+		"request":  request,
+	}).Debug("received request")
+	var resp EraseUserResponse
+	var err error
+	defer func(start time.Time) {
+		logger := s.logger.WithFields(logrus.Fields{
+			"service":  "UserService",
+			"endpoint": "Erase",
+			"took":     time.Since(start).String(),
+		})
+		if err != nil {
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
+			return
+		}
+
+		logger.WithField("response", resp).Debug()
+	}(time.Now())
+	resp, err = s.next.Erase(ctx, request)
+	return resp, err
+}
+
+func (s *serviceLoggingMiddleware) GetById(ctx context.Context, request GetUserByIdRequest) (GetUserResponse, error) {
+	s.logger.WithFields(logrus.Fields{
+		"service":  "UserService",
+		"endpoint": "GetById",
+		"request":  request,
+	}).Debug("received request")
+	var resp GetUserResponse
+	var err error
+	defer func(start time.Time) {
+		logger := s.logger.WithFields(logrus.Fields{
+			"service":  "UserService",
+			"endpoint": "GetById",
+			"took":     time.Since(start).String(),
+		})
+		if err != nil {
+			logger.WithFields(errorFields(err)).Errorln("an error occurred")
+			return
+		}
+
+		logger.WithField("response", resp).Debug()
+	}(time.Now())
+	resp, err = s.next.GetById(ctx, request)
+	return resp, err
+}
+
+// errorFields are the log fields of the error, the cause of an api error is logged too
+// since it is not revealed by the message returned to the clients.
+func errorFields(err error) logrus.Fields {
+	fields := logrus.Fields{"error": err}
+	if cause := errors.Unwrap(err); cause != nil {
+		fields["cause"] = cause
+	}
+
+	return fields
+}
@@ -2,9 +2,13 @@ package user
 
 import (
 	"context"
+	"errors"
 	"faceit-backend-test/internal/apierr"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 )
@@ -26,7 +30,6 @@ func TestServiceLoggingMiddleware_Create(t *testing.T) {
 			FirstName: e.FirstName,
 			LastName:  e.LastName,
 			Nickname:  e.Nickname,
-			Password:  e.Password,
 			Email:     e.Email,
 			Country:   e.Country,
 			CreatedAt: e.CreatedAt,
@@ -85,7 +88,6 @@ func TestServiceLoggingMiddleware_Update(t *testing.T) {
 			FirstName: e.FirstName,
 			LastName:  e.LastName,
 			Nickname:  e.Nickname,
-			Password:  e.Password,
 			Email:     e.Email,
 			Country:   e.Country,
 			CreatedAt: e.CreatedAt,
@@ -126,6 +128,60 @@ func TestServiceLoggingMiddleware_Update(t *testing.T) {
 	})
 }
 
+func TestServiceLoggingMiddleware_Patch(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		serviceMock := &mockService{}
+
+		country := "DE"
+		req := PatchUserRequest{
+			Id:      e.Id,
+			Country: &country,
+		}
+		expected := UpdateUserResponse{User{
+			Id:        e.Id,
+			FirstName: e.FirstName,
+			LastName:  e.LastName,
+			Nickname:  e.Nickname,
+			Email:     e.Email,
+			Country:   country,
+			CreatedAt: e.CreatedAt,
+			UpdatedAt: e.UpdatedAt,
+		}}
+		serviceMock.patchMock = func(ctx context.Context, request PatchUserRequest) (UpdateUserResponse, error) {
+			assert.EqualValues(t, req, request)
+
+			return expected, nil
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		resp, err := loggingMiddleware.Patch(context.Background(), req)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, resp)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		serviceMock := &mockService{}
+		expected := apierr.BadRequest("")
+		req := PatchUserRequest{}
+
+		serviceMock.patchMock = func(ctx context.Context, request PatchUserRequest) (UpdateUserResponse, error) {
+			return UpdateUserResponse{}, expected
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		_, err := loggingMiddleware.Patch(context.Background(), req)
+		assert.Error(t, err)
+		assert.IsType(t, expected, err)
+
+		apiErr := err.(apierr.ApiError)
+		assert.EqualValues(t, expected, apiErr)
+	})
+}
+
 func TestServiceLoggingMiddleware_DeleteById(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		serviceMock := &mockService{}
@@ -171,6 +227,49 @@ func TestServiceLoggingMiddleware_DeleteById(t *testing.T) {
 	})
 }
 
+func TestServiceLoggingMiddleware_Restore(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		serviceMock := &mockService{}
+
+		req := RestoreUserRequest{
+			Id: e.Id,
+		}
+		expected := RestoreUserResponse{User{
+			Id:       e.Id,
+			Nickname: e.Nickname,
+		}}
+		serviceMock.restoreMock = func(ctx context.Context, request RestoreUserRequest) (RestoreUserResponse, error) {
+			assert.EqualValues(t, req, request)
+
+			return expected, nil
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		resp, err := loggingMiddleware.Restore(context.Background(), req)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, resp)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		serviceMock := &mockService{}
+		expected := userNotFoundError(e.Id)
+		req := RestoreUserRequest{Id: e.Id}
+
+		serviceMock.restoreMock = func(ctx context.Context, request RestoreUserRequest) (RestoreUserResponse, error) {
+			return RestoreUserResponse{}, expected
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		_, err := loggingMiddleware.Restore(context.Background(), req)
+		assert.Error(t, err)
+		assert.EqualValues(t, expected, err)
+	})
+}
+
 func TestServiceLoggingMiddleware_GetMany(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		serviceMock := &mockService{}
@@ -178,16 +277,8 @@ func TestServiceLoggingMiddleware_GetMany(t *testing.T) {
 		req := GetUsersManyRequest{
 			Page:    3,
 			PerPage: 25,
-			Filter: User{
-				Id:        "",
-				FirstName: "",
-				LastName:  "",
-				Nickname:  "",
-				Password:  "",
-				Email:     "",
-				Country:   "UK",
-				CreatedAt: time.Time{},
-				UpdatedAt: time.Time{},
+			Filter: []FilterCondition{
+				{Column: "country", Operator: FilterEq, Values: []interface{}{"UK"}},
 			},
 		}
 
@@ -199,7 +290,6 @@ func TestServiceLoggingMiddleware_GetMany(t *testing.T) {
 				FirstName: val.FirstName,
 				LastName:  val.LastName,
 				Nickname:  val.Nickname,
-				Password:  val.Password,
 				Email:     val.Email,
 				Country:   val.Country,
 				CreatedAt: val.CreatedAt,
@@ -241,3 +331,460 @@ func TestServiceLoggingMiddleware_GetMany(t *testing.T) {
 		assert.EqualValues(t, expected, apiErr)
 	})
 }
+
+func TestServiceLoggingMiddleware_Export(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		serviceMock := &mockService{}
+		req := ExportUsersRequest{Format: ExportFormatNDJSON}
+
+		serviceMock.exportMock = func(ctx context.Context, request ExportUsersRequest, w io.Writer) error {
+			assert.EqualValues(t, req, request)
+
+			_, err := w.Write([]byte("{}\n"))
+			return err
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		buf := &strings.Builder{}
+		err := loggingMiddleware.Export(context.Background(), req, buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "{}\n", buf.String())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		serviceMock := &mockService{}
+		expected := unsupportedExportFormatError("xml")
+
+		serviceMock.exportMock = func(ctx context.Context, request ExportUsersRequest, w io.Writer) error {
+			return expected
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		err := loggingMiddleware.Export(context.Background(), ExportUsersRequest{}, &strings.Builder{})
+		assert.EqualValues(t, expected, err)
+	})
+}
+
+func TestServiceLoggingMiddleware_Search(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		serviceMock := &mockService{}
+		req := SearchUsersRequest{Query: "firm", Page: 1, PerPage: 10}
+		expected := SearchUsersResponse{Users: []UserMatch{{User: User{Id: e.Id}, Rank: 0.5}}, Page: 1, PerPage: 10}
+
+		serviceMock.searchMock = func(ctx context.Context, request SearchUsersRequest) (SearchUsersResponse, error) {
+			assert.EqualValues(t, req, request)
+
+			return expected, nil
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		resp, err := loggingMiddleware.Search(context.Background(), req)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, resp)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		serviceMock := &mockService{}
+		expected := invalidSearchQueryError()
+
+		serviceMock.searchMock = func(ctx context.Context, request SearchUsersRequest) (SearchUsersResponse, error) {
+			return SearchUsersResponse{}, expected
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		_, err := loggingMiddleware.Search(context.Background(), SearchUsersRequest{})
+		assert.EqualValues(t, expected, err)
+	})
+}
+
+func TestServiceLoggingMiddleware_Batch(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		serviceMock := &mockService{}
+		req := BatchUsersRequest{Operations: []BatchOperation{{Op: BatchOpDelete, Id: e.Id}}}
+		expected := BatchUsersResponse{Committed: true, Results: []BatchOperationResult{{Status: http.StatusOK, Id: e.Id}}}
+
+		serviceMock.batchMock = func(ctx context.Context, request BatchUsersRequest) (BatchUsersResponse, error) {
+			assert.EqualValues(t, req, request)
+
+			return expected, nil
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		resp, err := loggingMiddleware.Batch(context.Background(), req)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, resp)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		serviceMock := &mockService{}
+		expected := passwordHashError()
+
+		serviceMock.batchMock = func(ctx context.Context, request BatchUsersRequest) (BatchUsersResponse, error) {
+			return BatchUsersResponse{}, expected
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		_, err := loggingMiddleware.Batch(context.Background(), BatchUsersRequest{})
+		assert.EqualValues(t, expected, err)
+	})
+}
+
+func TestServiceLoggingMiddleware_Import(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		serviceMock := &mockService{}
+		req := ImportUsersRequest{Format: ImportFormatCSV, File: strings.NewReader("first_name\n")}
+		expected := ImportJobResponse{ImportJob{Id: e.Id, Status: ImportStatusPending, Format: ImportFormatCSV}}
+
+		serviceMock.importMock = func(ctx context.Context, request ImportUsersRequest) (ImportJobResponse, error) {
+			assert.EqualValues(t, req, request)
+
+			return expected, nil
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		resp, err := loggingMiddleware.Import(context.Background(), req)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, resp)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		serviceMock := &mockService{}
+		expected := unsupportedImportFormatError("application/xml")
+
+		serviceMock.importMock = func(ctx context.Context, request ImportUsersRequest) (ImportJobResponse, error) {
+			return ImportJobResponse{}, expected
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		_, err := loggingMiddleware.Import(context.Background(), ImportUsersRequest{})
+		assert.EqualValues(t, expected, err)
+	})
+}
+
+func TestServiceLoggingMiddleware_GetImport(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		serviceMock := &mockService{}
+		req := GetImportRequest{Id: e.Id}
+		expected := ImportJobResponse{ImportJob{Id: e.Id, Status: ImportStatusCompleted, Processed: 2, Imported: 2}}
+
+		serviceMock.getImportMock = func(ctx context.Context, request GetImportRequest) (ImportJobResponse, error) {
+			assert.EqualValues(t, req, request)
+
+			return expected, nil
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		resp, err := loggingMiddleware.GetImport(context.Background(), req)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, resp)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		serviceMock := &mockService{}
+		expected := importJobNotFoundError()
+
+		serviceMock.getImportMock = func(ctx context.Context, request GetImportRequest) (ImportJobResponse, error) {
+			return ImportJobResponse{}, expected
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		_, err := loggingMiddleware.GetImport(context.Background(), GetImportRequest{})
+		assert.EqualValues(t, expected, err)
+	})
+}
+
+func TestServiceLoggingMiddleware_GetImportErrors(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		serviceMock := &mockService{}
+		req := GetImportRequest{Id: e.Id}
+		expected := ImportErrorsResponse{Errors: []ImportRowError{{Row: 2, Error: "duplicate nickname"}}}
+
+		serviceMock.importErrsMock = func(ctx context.Context, request GetImportRequest) (ImportErrorsResponse, error) {
+			assert.EqualValues(t, req, request)
+
+			return expected, nil
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		resp, err := loggingMiddleware.GetImportErrors(context.Background(), req)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, resp)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		serviceMock := &mockService{}
+		expected := importJobNotFoundError()
+
+		serviceMock.importErrsMock = func(ctx context.Context, request GetImportRequest) (ImportErrorsResponse, error) {
+			return ImportErrorsResponse{}, expected
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		_, err := loggingMiddleware.GetImportErrors(context.Background(), GetImportRequest{})
+		assert.EqualValues(t, expected, err)
+	})
+}
+
+func TestServiceLoggingMiddleware_GetById(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		serviceMock := &mockService{}
+
+		req := GetUserByIdRequest{
+			Id: e.Id,
+		}
+		expected := GetUserResponse{User{
+			Id:        e.Id,
+			FirstName: e.FirstName,
+			LastName:  e.LastName,
+			Nickname:  e.Nickname,
+			Email:     e.Email,
+			Country:   e.Country,
+			CreatedAt: e.CreatedAt,
+			UpdatedAt: e.UpdatedAt,
+		}}
+		serviceMock.getByIdMock = func(ctx context.Context, request GetUserByIdRequest) (GetUserResponse, error) {
+			assert.EqualValues(t, req, request)
+
+			return expected, nil
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		resp, err := loggingMiddleware.GetById(context.Background(), req)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, resp)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		serviceMock := &mockService{}
+		expected := userNotFoundError(e.Id)
+		req := GetUserByIdRequest{}
+
+		serviceMock.getByIdMock = func(ctx context.Context, request GetUserByIdRequest) (GetUserResponse, error) {
+			return GetUserResponse{}, expected
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		_, err := loggingMiddleware.GetById(context.Background(), req)
+		assert.Error(t, err)
+		assert.IsType(t, expected, err)
+
+		apiErr := err.(apierr.ApiError)
+		assert.EqualValues(t, expected, apiErr)
+	})
+}
+
+func TestServiceLoggingMiddleware_GetHistory(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		serviceMock := &mockService{}
+		req := GetUserHistoryRequest{Id: e.Id, Page: 1, PerPage: 10}
+		expected := UserHistoryResponse{Entries: []AuditEntry{{Id: 1, UserId: e.Id, Action: AuditActionCreated}}, Page: 1, PerPage: 10}
+
+		serviceMock.historyMock = func(ctx context.Context, request GetUserHistoryRequest) (UserHistoryResponse, error) {
+			assert.EqualValues(t, req, request)
+
+			return expected, nil
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		resp, err := loggingMiddleware.GetHistory(context.Background(), req)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, resp)
+	})
+}
+
+func TestServiceLoggingMiddleware_GetSnapshot(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		serviceMock := &mockService{}
+		expected := userSnapshotNotFoundError(e.Id)
+
+		serviceMock.snapshotMock = func(ctx context.Context, request GetUserSnapshotRequest) (UserSnapshotResponse, error) {
+			return UserSnapshotResponse{}, expected
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		_, err := loggingMiddleware.GetSnapshot(context.Background(), GetUserSnapshotRequest{Id: e.Id, At: time.Now()})
+		assert.Equal(t, expected, err)
+	})
+}
+
+func TestServiceLoggingMiddleware_ExportPersonalData(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		serviceMock := &mockService{}
+		req := GetPersonalDataRequest{Id: e.Id}
+		expected := PersonalDataResponse{User: User{Id: e.Id}, History: []AuditEntry{{Id: 1}}}
+
+		serviceMock.personalDataMock = func(ctx context.Context, request GetPersonalDataRequest) (PersonalDataResponse, error) {
+			assert.EqualValues(t, req, request)
+
+			return expected, nil
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		resp, err := loggingMiddleware.ExportPersonalData(context.Background(), req)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, resp)
+	})
+}
+
+func TestServiceLoggingMiddleware_Erase(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		serviceMock := &mockService{}
+		expected := userNotFoundError(e.Id)
+
+		serviceMock.eraseMock = func(ctx context.Context, request EraseUserRequest) (EraseUserResponse, error) {
+			return EraseUserResponse{}, expected
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		_, err := loggingMiddleware.Erase(context.Background(), EraseUserRequest{Id: e.Id})
+		assert.Equal(t, expected, err)
+	})
+}
+
+func TestServiceLoggingMiddleware_SendVerification(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		serviceMock := &mockService{}
+		req := SendVerificationRequest{Id: e.Id}
+		expected := VerificationResponse{Id: e.Id, Email: e.Email, ExpiresAt: time.Now()}
+
+		serviceMock.sendVerificationMock = func(ctx context.Context, request SendVerificationRequest) (VerificationResponse, error) {
+			assert.EqualValues(t, req, request)
+
+			return expected, nil
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		resp, err := loggingMiddleware.SendVerification(context.Background(), req)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, resp)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		serviceMock := &mockService{}
+		expected := verificationMailError(errors.New("mock error"))
+
+		serviceMock.sendVerificationMock = func(ctx context.Context, request SendVerificationRequest) (VerificationResponse, error) {
+			return VerificationResponse{}, expected
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		_, err := loggingMiddleware.SendVerification(context.Background(), SendVerificationRequest{Id: e.Id})
+		assert.Equal(t, expected, err)
+	})
+}
+
+func TestServiceLoggingMiddleware_VerifyEmail(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		serviceMock := &mockService{}
+		req := VerifyEmailRequest{Token: "token"}
+		expected := VerifyEmailResponse{User{Id: e.Id}}
+
+		serviceMock.verifyEmailMock = func(ctx context.Context, request VerifyEmailRequest) (VerifyEmailResponse, error) {
+			assert.EqualValues(t, req, request, "should not redact the token passed to the service")
+
+			return expected, nil
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		resp, err := loggingMiddleware.VerifyEmail(context.Background(), req)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, resp)
+	})
+}
+
+func TestServiceLoggingMiddleware_ConfirmPasswordReset(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		serviceMock := &mockService{}
+		req := ConfirmPasswordResetRequest{Token: "token", Password: e.Password}
+
+		serviceMock.confirmResetMock = func(ctx context.Context, request ConfirmPasswordResetRequest) error {
+			assert.EqualValues(t, req, request, "should not redact the request passed to the service")
+
+			return nil
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		err := loggingMiddleware.ConfirmPasswordReset(context.Background(), req)
+		assert.NoError(t, err)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		serviceMock := &mockService{}
+		expected := invalidPasswordResetTokenError()
+
+		serviceMock.confirmResetMock = func(ctx context.Context, request ConfirmPasswordResetRequest) error {
+			return expected
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		err := loggingMiddleware.ConfirmPasswordReset(context.Background(), ConfirmPasswordResetRequest{})
+		assert.Equal(t, expected, err)
+	})
+}
+
+func TestServiceLoggingMiddleware_CheckNicknameAvailability(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		serviceMock := &mockService{}
+		req := NicknameAvailabilityRequest{Nickname: e.Nickname}
+		expected := NicknameAvailabilityResponse{Nickname: e.Nickname, Available: true}
+
+		serviceMock.availabilityMock = func(ctx context.Context, request NicknameAvailabilityRequest) (NicknameAvailabilityResponse, error) {
+			assert.EqualValues(t, req, request)
+
+			return expected, nil
+		}
+
+		logger := logrus.New()
+		loggingMiddleware := NewServiceLoggingMiddleware(logger)(serviceMock)
+
+		resp, err := loggingMiddleware.CheckNicknameAvailability(context.Background(), req)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, resp)
+	})
+}
@@ -0,0 +1,63 @@
+package user
+
+import (
+	"golang.org/x/text/unicode/norm"
+	"strings"
+)
+
+// normalizer is a request whose fields are normalized before it is validated.
+type normalizer interface {
+	normalize()
+}
+
+// canonical returns the canonical form of a nickname or an email, which are compared regardless of their case
+// and of the unicode compatibility forms of their characters.
+func canonical(value string) string {
+	return strings.ToLower(strings.TrimSpace(norm.NFKC.String(value)))
+}
+
+func (r *CreateUserRequest) normalize() {
+	r.Nickname = canonical(r.Nickname)
+	r.Email = canonical(r.Email)
+}
+
+func (r *UpdateUserRequest) normalize() {
+	r.Nickname = canonical(r.Nickname)
+	r.Email = canonical(r.Email)
+}
+
+func (r *PatchUserRequest) normalize() {
+	if r.Nickname != nil {
+		nickname := canonical(*r.Nickname)
+		r.Nickname = &nickname
+	}
+	if r.Email != nil {
+		email := canonical(*r.Email)
+		r.Email = &email
+	}
+}
+
+func (r *BatchUsersRequest) normalize() {
+	for _, op := range r.Operations {
+		if op.User != nil {
+			op.User.normalize()
+		}
+	}
+}
+
+func (r *VerifyEmailRequest) normalize() {
+	r.Token = strings.TrimSpace(r.Token)
+}
+
+func (r *PasswordResetRequest) normalize() {
+	r.Email = canonical(r.Email)
+}
+
+// normalize keeps the password as it is, since it is compared byte by byte.
+func (r *ConfirmPasswordResetRequest) normalize() {
+	r.Token = strings.TrimSpace(r.Token)
+}
+
+func (r *NicknameAvailabilityRequest) normalize() {
+	r.Nickname = canonical(r.Nickname)
+}
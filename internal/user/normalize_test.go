@@ -0,0 +1,34 @@
+package user
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		cases := map[string]string{
+			"Player":                    "player",
+			"  bobby.firmino ":          "bobby.firmino",
+			"Ｐｌａｙｅｒ":                    "player",
+			"RobertoFirmino@LFC.co.uk":  "robertofirmino@lfc.co.uk",
+			"　robertofirmino@lfc.co.uk": "robertofirmino@lfc.co.uk",
+		}
+
+		for value, expected := range cases {
+			assert.Equal(t, expected, canonical(value), value)
+		}
+	})
+}
+
+func TestPatchUserRequest_Normalize(t *testing.T) {
+	t.Run("should normalize only the provided fields", func(t *testing.T) {
+		nickname := " Firmino"
+		request := PatchUserRequest{Nickname: &nickname}
+
+		request.normalize()
+
+		assert.Equal(t, "firmino", *request.Nickname)
+		assert.Nil(t, request.Email)
+	})
+}
@@ -0,0 +1,84 @@
+package user
+
+import (
+	"golang.org/x/crypto/bcrypt"
+	"runtime"
+	"sync"
+)
+
+// PasswordHasher hashes the user passwords before they are persisted
+// and verifies the plain text passwords against the stored hashes.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Compare(hash string, password string) error
+	NeedsRehash(hash string) bool
+}
+
+// bcryptHasher is a PasswordHasher using bcrypt with a configurable cost.
+type bcryptHasher struct {
+	cost int
+}
+
+var _ PasswordHasher = (*bcryptHasher)(nil)
+
+func NewBcryptHasher(cost int) *bcryptHasher {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+
+	return &bcryptHasher{
+		cost: cost,
+	}
+}
+
+func (h *bcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (h *bcryptHasher) Compare(hash string, password string) error {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+}
+
+// NeedsRehash reports whether the hash was created with parameters different from the current ones,
+// so that it can be replaced transparently the next time the plain text password is known.
+func (h *bcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true
+	}
+
+	return cost != h.cost
+}
+
+// hashPasswords hashes the passwords by a worker per cpu, since hashing them one by one would take seconds
+// for a few dozens of them. The hashes and the errors are returned in the order of the passwords.
+func hashPasswords(hasher PasswordHasher, passwords []string) ([]string, []error) {
+	hashes := make([]string, len(passwords))
+	errs := make([]error, len(passwords))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				hashes[i], errs[i] = hasher.Hash(passwords[i])
+			}
+		}()
+	}
+
+	for i := range passwords {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return hashes, errs
+}
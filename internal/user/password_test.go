@@ -0,0 +1,82 @@
+package user
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+)
+
+func TestBcryptHasher_Hash(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		h := NewBcryptHasher(bcrypt.MinCost)
+
+		hash, err := h.Hash(e.Password)
+		assert.NoError(t, err)
+		assert.NotEqual(t, e.Password, hash, "should not return the plain text password")
+		assert.NoError(t, h.Compare(hash, e.Password))
+	})
+
+	t.Run("should return error when password does not match", func(t *testing.T) {
+		h := NewBcryptHasher(bcrypt.MinCost)
+
+		hash, err := h.Hash(e.Password)
+		assert.NoError(t, err)
+		assert.Error(t, h.Compare(hash, "wrong password"))
+	})
+}
+
+func TestBcryptHasher_NeedsRehash(t *testing.T) {
+	t.Run("should not need rehash when cost is the same", func(t *testing.T) {
+		h := NewBcryptHasher(bcrypt.MinCost)
+
+		hash, err := h.Hash(e.Password)
+		assert.NoError(t, err)
+		assert.False(t, h.NeedsRehash(hash))
+	})
+
+	t.Run("should need rehash when cost is changed", func(t *testing.T) {
+		hash, err := NewBcryptHasher(bcrypt.MinCost).Hash(e.Password)
+		assert.NoError(t, err)
+
+		h := NewBcryptHasher(bcrypt.MinCost + 1)
+		assert.True(t, h.NeedsRehash(hash))
+	})
+
+	t.Run("should need rehash when the stored value is not a bcrypt hash", func(t *testing.T) {
+		h := NewBcryptHasher(bcrypt.MinCost)
+		assert.True(t, h.NeedsRehash(e.Password))
+	})
+}
+
+// failingHasher fails to hash the password it is given.
+type failingHasher struct {
+	PasswordHasher
+	password string
+}
+
+func (h *failingHasher) Hash(password string) (string, error) {
+	if password == h.password {
+		return "", fmt.Errorf("mock error")
+	}
+	return h.PasswordHasher.Hash(password)
+}
+
+func TestHashPasswords(t *testing.T) {
+	h := NewBcryptHasher(bcrypt.MinCost)
+	passwords := []string{"liverpool1", "liverpool2", "liverpool3", "liverpool4", "liverpool5"}
+
+	hashes, errs := hashPasswords(&failingHasher{PasswordHasher: h, password: "liverpool3"}, passwords)
+	assert.Len(t, hashes, len(passwords))
+	assert.Len(t, errs, len(passwords))
+
+	for i, password := range passwords {
+		if password == "liverpool3" {
+			assert.Error(t, errs[i], "should return the error of the password")
+			continue
+		}
+
+		assert.NoError(t, errs[i])
+		assert.NoError(t, h.Compare(hashes[i], password), "should return the hashes in the order of the passwords")
+	}
+}
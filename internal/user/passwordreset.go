@@ -0,0 +1,100 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"faceit-backend-test/internal/apierr"
+	"faceit-backend-test/internal/mail"
+	"fmt"
+	"time"
+)
+
+const defaultPasswordResetTTL = time.Hour
+
+// newPasswordResetToken returns a random password reset token together with its hash,
+// only the hash is stored so that the tokens cannot be used by whoever reads the database.
+func newPasswordResetToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashPasswordResetToken(token), nil
+}
+
+func hashPasswordResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestPasswordReset mails the user having the email a link to reset its password. It succeeds whether the email
+// belongs to a user or not and it does the same work in both cases, so that neither its response nor its duration
+// can be used to find out the registered emails.
+func (s *service) RequestPasswordReset(ctx context.Context, request PasswordResetRequest) error {
+	token, tokenHash, err := newPasswordResetToken()
+	if err != nil {
+		return apierr.InternalServerError()
+	}
+
+	entity, err := s.repo.CreatePasswordResetToken(ctx, request.Email, tokenHash, s.passwordResetTTL)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return repositoryError(err)
+	}
+
+	// the mail is sent in the background and its errors are not returned, otherwise they would reveal that the email exists.
+	mailCtx := detachedContext(ctx)
+	go func() {
+		_ = s.sendPasswordReset(mailCtx, entity, token)
+	}()
+
+	return nil
+}
+
+func (s *service) sendPasswordReset(ctx context.Context, entity Entity, token string) error {
+	if s.mailer == nil {
+		return errors.New("no mail sender")
+	}
+
+	link, err := tokenLink(s.passwordResetURL, token)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      entity.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nyou can reset your password by opening the link below in %s. "+
+			"You can ignore this mail if you have not asked for it.\n\n%s\n",
+			entity.FirstName, s.passwordResetTTL, link),
+	})
+}
+
+// ConfirmPasswordReset sets the password of the user the token is issued for,
+// the token and the other outstanding tokens of the user cannot be used afterwards.
+func (s *service) ConfirmPasswordReset(ctx context.Context, request ConfirmPasswordResetRequest) error {
+	passwordHash, err := s.hasher.Hash(request.Password)
+	if err != nil {
+		return passwordHashError()
+	}
+
+	_, err = s.audited(ctx, AuditActionPasswordReset, func(repo Repository) (EntityChange, error) {
+		entity, err := repo.ResetPassword(ctx, hashPasswordResetToken(request.Token), passwordHash)
+		return EntityChange{Entity: entity}, err
+	})
+	if errors.Is(err, ErrNotFound) {
+		return invalidPasswordResetTokenError()
+	}
+	if err != nil {
+		return repositoryError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package user
+
+import (
+	"context"
+	"faceit-backend-test/internal/mail"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestService_RequestPasswordReset(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		var storedHash string
+
+		mockRepo := &mockRepository{}
+		mockRepo.createResetMock = func(ctx context.Context, email string, tokenHash string, ttl time.Duration) (Entity, error) {
+			assert.Equal(t, e.Email, email, "should look the user up by the email")
+			assert.Equal(t, 30*time.Minute, ttl)
+			storedHash = tokenHash
+
+			return e, nil
+		}
+
+		sent := make(chan mail.Message, 1)
+		mockMailer := &mail.MockSender{}
+		mockMailer.SendMock = func(ctx context.Context, message mail.Message) error {
+			sent <- message
+			return nil
+		}
+
+		service := NewService(WithRepository(mockRepo), WithMailSender(mockMailer),
+			WithPasswordReset(30*time.Minute, "http://localhost/reset-password"))
+
+		err := service.RequestPasswordReset(context.Background(), PasswordResetRequest{Email: e.Email})
+		assert.NoError(t, err)
+
+		var message mail.Message
+		select {
+		case message = <-sent:
+		case <-time.After(time.Second):
+			assert.FailNow(t, "should send the mail in the background")
+		}
+		assert.Equal(t, e.Email, message.To)
+
+		link := message.Body[strings.Index(message.Body, "http://localhost/reset-password?token="):]
+		u, err := url.Parse(strings.TrimSpace(link))
+		assert.NoError(t, err)
+
+		token := u.Query().Get("token")
+		assert.NotEqual(t, token, storedHash, "should not store the plain text token")
+		assert.Equal(t, hashPasswordResetToken(token), storedHash)
+	})
+
+	t.Run("should not reveal that the email does not exist", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.createResetMock = func(ctx context.Context, email string, tokenHash string, ttl time.Duration) (Entity, error) {
+			return Entity{}, ErrNotFound
+		}
+
+		mockMailer := &mail.MockSender{}
+		mockMailer.SendMock = func(ctx context.Context, message mail.Message) error {
+			assert.Fail(t, "should not send any mail")
+			return nil
+		}
+
+		service := NewService(WithRepository(mockRepo), WithMailSender(mockMailer))
+
+		err := service.RequestPasswordReset(context.Background(), PasswordResetRequest{Email: e.Email})
+		assert.NoError(t, err)
+	})
+
+	t.Run("should not reveal that the mail cannot be sent", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.createResetMock = func(ctx context.Context, email string, tokenHash string, ttl time.Duration) (Entity, error) {
+			return e, nil
+		}
+
+		mockMailer := &mail.MockSender{}
+		mockMailer.SendMock = func(ctx context.Context, message mail.Message) error {
+			return fmt.Errorf("mock error")
+		}
+
+		service := NewService(WithRepository(mockRepo), WithMailSender(mockMailer))
+
+		err := service.RequestPasswordReset(context.Background(), PasswordResetRequest{Email: e.Email})
+		assert.NoError(t, err)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		expectedErr := fmt.Errorf("mock error")
+
+		mockRepo := &mockRepository{}
+		mockRepo.createResetMock = func(ctx context.Context, email string, tokenHash string, ttl time.Duration) (Entity, error) {
+			return Entity{}, expectedErr
+		}
+
+		service := NewService(WithRepository(mockRepo))
+
+		err := service.RequestPasswordReset(context.Background(), PasswordResetRequest{Email: e.Email})
+		assert.ErrorIs(t, err, expectedErr)
+	})
+}
+
+func TestService_ConfirmPasswordReset(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.resetPwdMock = func(ctx context.Context, tokenHash string, password string) (Entity, error) {
+			assert.Equal(t, hashPasswordResetToken("token"), tokenHash)
+			assert.NoError(t, hasher.Compare(password, e.Password))
+
+			return e, nil
+		}
+
+		service := NewService(WithRepository(mockRepo), WithPasswordHasher(hasher))
+
+		err := service.ConfirmPasswordReset(context.Background(), ConfirmPasswordResetRequest{Token: "token", Password: e.Password})
+		assert.NoError(t, err)
+	})
+
+	t.Run("should reject the used or expired tokens", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.resetPwdMock = func(ctx context.Context, tokenHash string, password string) (Entity, error) {
+			return Entity{}, ErrNotFound
+		}
+
+		service := NewService(WithRepository(mockRepo), WithPasswordHasher(hasher))
+
+		err := service.ConfirmPasswordReset(context.Background(), ConfirmPasswordResetRequest{Token: "token", Password: e.Password})
+		assert.Equal(t, invalidPasswordResetTokenError(), err)
+	})
+}
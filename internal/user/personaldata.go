@@ -0,0 +1,163 @@
+package user
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"faceit-backend-test/internal/notify"
+	"io"
+	"time"
+)
+
+const mimeZip = "application/zip"
+
+// DeliveryLog is the log of the notifications sent to the subscribers, the notifications about a user contain its data.
+type DeliveryLog interface {
+	Deliveries(subject string) []notify.Delivery
+	ForgetDeliveries(subject string)
+}
+
+// PersonalDataEntity is everything stored about a user.
+type PersonalDataEntity struct {
+	Entity
+	ErasedAt            *time.Time `db:"erased_at"`
+	History             []AuditEntity
+	PasswordResetTokens []PasswordResetTokenEntity
+}
+
+// PasswordResetTokenEntity is a password reset token of a user without its hash.
+type PasswordResetTokenEntity struct {
+	Id        string     `db:"id"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+// ExportPersonalData returns everything held about the user, including the notifications sent about it
+// and the subscriptions they are sent to. The deleted and the erased users are exported as well.
+func (s *service) ExportPersonalData(ctx context.Context, request GetPersonalDataRequest) (PersonalDataResponse, error) {
+	data, err := s.repo.GetPersonalData(ctx, request.Id)
+	if errors.Is(err, ErrNotFound) {
+		return PersonalDataResponse{}, userNotFoundError(request.Id)
+	}
+	if err != nil {
+		return PersonalDataResponse{}, repositoryError(err)
+	}
+
+	resp := PersonalDataResponse{
+		User: User{
+			Id:              data.Id,
+			FirstName:       data.FirstName,
+			LastName:        data.LastName,
+			Nickname:        data.Nickname,
+			Email:           data.Email,
+			Country:         data.Country,
+			Version:         data.Version,
+			CreatedAt:       data.CreatedAt,
+			UpdatedAt:       data.UpdatedAt,
+			DeletedAt:       data.DeletedAt,
+			EmailVerifiedAt: data.EmailVerifiedAt,
+		},
+		ErasedAt:            data.ErasedAt,
+		History:             make([]AuditEntry, len(data.History)),
+		PasswordResetTokens: make([]PasswordResetToken, len(data.PasswordResetTokens)),
+		Subscriptions:       []Subscription{},
+		Deliveries:          []notify.Delivery{},
+		ExportedAt:          time.Now(),
+	}
+
+	for i, entity := range data.History {
+		resp.History[i] = newAuditEntry(entity)
+	}
+
+	for i, token := range data.PasswordResetTokens {
+		resp.PasswordResetTokens[i] = PasswordResetToken{
+			Id:        token.Id,
+			ExpiresAt: token.ExpiresAt,
+			UsedAt:    token.UsedAt,
+			CreatedAt: token.CreatedAt,
+		}
+	}
+
+	if s.deliveries != nil {
+		resp.Deliveries = s.deliveries.Deliveries(request.Id)
+	}
+
+	// the subscriptions are the ones the notifications about the user are sent to.
+	subscribed := map[string]bool{}
+	for _, delivery := range resp.Deliveries {
+		if subscribed[delivery.SubscriptionId] {
+			continue
+		}
+		subscribed[delivery.SubscriptionId] = true
+
+		resp.Subscriptions = append(resp.Subscriptions, Subscription{
+			Id:          delivery.SubscriptionId,
+			Topic:       delivery.Topic,
+			CallbackUrl: delivery.CallbackUrl,
+		})
+	}
+
+	return resp, nil
+}
+
+// Erase anonymizes the user irreversibly. The row is kept deleted with its id, so that whatever refers to the user
+// stays valid, while its names, nickname, email, country and password are replaced. Its password reset tokens,
+// its change history and the notifications kept about it are removed, the erasure is recorded as the only change.
+func (s *service) Erase(ctx context.Context, request EraseUserRequest) (EraseUserResponse, error) {
+	change, err := s.audited(ctx, AuditActionErased, func(repo Repository) (EntityChange, error) {
+		return repo.Erase(ctx, request.Id)
+	})
+	if errors.Is(err, ErrNotFound) {
+		return EraseUserResponse{}, userNotFoundError(request.Id)
+	}
+	if err != nil {
+		return EraseUserResponse{}, repositoryError(err)
+	}
+
+	if s.deliveries != nil {
+		s.deliveries.ForgetDeliveries(request.Id)
+	}
+
+	// the erasure and the update of the user are made in the same transaction, so they are at the same time.
+	resp := EraseUserResponse{Id: change.Id, ErasedAt: change.UpdatedAt}
+	s.broker.Publish(UserErasedTopic, resp)
+
+	return resp, nil
+}
+
+// writePersonalDataArchive writes the personal data as a zip archive of JSON files.
+func writePersonalDataArchive(w io.Writer, data PersonalDataResponse) error {
+	archive := zip.NewWriter(w)
+
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{name: "user.json", content: struct {
+			User
+			ErasedAt   *time.Time `json:"erased_at,omitempty"`
+			ExportedAt time.Time  `json:"exported_at"`
+		}{User: data.User, ErasedAt: data.ErasedAt, ExportedAt: data.ExportedAt}},
+		{name: "history.json", content: data.History},
+		{name: "password_reset_tokens.json", content: data.PasswordResetTokens},
+		{name: "subscriptions.json", content: data.Subscriptions},
+		{name: "deliveries.json", content: data.Deliveries},
+	}
+
+	for _, file := range files {
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: data.ExportedAt})
+		if err != nil {
+			return err
+		}
+
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.content); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
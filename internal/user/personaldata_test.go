@@ -0,0 +1,188 @@
+package user
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"faceit-backend-test/internal/notify"
+	"faceit-backend-test/internal/pubsub"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+type mockDeliveryLog struct {
+	deliveriesMock func(string) []notify.Delivery
+	forgetMock     func(string)
+}
+
+func (m *mockDeliveryLog) Deliveries(subject string) []notify.Delivery {
+	return m.deliveriesMock(subject)
+}
+
+func (m *mockDeliveryLog) ForgetDeliveries(subject string) {
+	m.forgetMock(subject)
+}
+
+func TestService_ExportPersonalData(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		usedAt := time.Now()
+		mockRepo := &mockRepository{}
+		mockRepo.personalMock = func(ctx context.Context, id string) (PersonalDataEntity, error) {
+			assert.Equal(t, e.Id, id)
+
+			return PersonalDataEntity{
+				Entity: e,
+				History: []AuditEntity{
+					{Id: 1, UserId: e.Id, Action: AuditActionCreated, Snapshot: AuditSnapshot{User{Version: 1}}},
+				},
+				PasswordResetTokens: []PasswordResetTokenEntity{{Id: "token", UsedAt: &usedAt}},
+			}, nil
+		}
+
+		mockDeliveries := &mockDeliveryLog{}
+		mockDeliveries.deliveriesMock = func(subject string) []notify.Delivery {
+			assert.Equal(t, e.Id, subject)
+
+			return []notify.Delivery{
+				{MessageId: "1", SubscriptionId: "a", Topic: UserCreatedTopic, CallbackUrl: "http://a"},
+				{MessageId: "2", SubscriptionId: "b", Topic: UserChangeTopic, CallbackUrl: "http://b"},
+				{MessageId: "3", SubscriptionId: "a", Topic: UserCreatedTopic, CallbackUrl: "http://a"},
+			}
+		}
+
+		service := NewService(WithRepository(mockRepo), WithDeliveryLog(mockDeliveries))
+
+		actual, err := service.ExportPersonalData(context.Background(), GetPersonalDataRequest{Id: e.Id})
+		assert.NoError(t, err)
+		assert.Equal(t, e.Nickname, actual.User.Nickname)
+		assert.Len(t, actual.History, 1)
+		assert.Equal(t, AuditActionCreated, actual.History[0].Action)
+		assert.Equal(t, []PasswordResetToken{{Id: "token", UsedAt: &usedAt}}, actual.PasswordResetTokens)
+		assert.Len(t, actual.Deliveries, 3)
+		assert.Equal(t, []Subscription{
+			{Id: "a", Topic: UserCreatedTopic, CallbackUrl: "http://a"},
+			{Id: "b", Topic: UserChangeTopic, CallbackUrl: "http://b"},
+		}, actual.Subscriptions, "should list every subscription once")
+	})
+
+	t.Run("should return not found when the user does not exist", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.personalMock = func(ctx context.Context, id string) (PersonalDataEntity, error) {
+			return PersonalDataEntity{}, ErrNotFound
+		}
+
+		service := NewService(WithRepository(mockRepo))
+
+		_, err := service.ExportPersonalData(context.Background(), GetPersonalDataRequest{Id: e.Id})
+		assert.Equal(t, userNotFoundError(e.Id), err)
+	})
+}
+
+func TestService_Erase(t *testing.T) {
+	erased := e
+	erased.FirstName = ""
+	erased.LastName = ""
+	erased.Nickname = "_erased_1"
+	erased.Email = "_erased_1@erased.invalid"
+	erased.Password = ""
+	erased.Version = e.Version + 1
+	erased.UpdatedAt = time.Now()
+	erased.DeletedAt = &erased.UpdatedAt
+
+	t.Run("success", func(t *testing.T) {
+		var audited []AuditEntity
+
+		mockRepo := &mockRepository{}
+		mockRepo.eraseMock = func(ctx context.Context, id string) (EntityChange, error) {
+			assert.Equal(t, e.Id, id)
+
+			return EntityChange{Entity: erased, Previous: e}, nil
+		}
+		mockRepo.appendAuditMock = func(ctx context.Context, entities ...AuditEntity) error {
+			audited = append(audited, entities...)
+			return nil
+		}
+
+		forgotten := ""
+		mockDeliveries := &mockDeliveryLog{}
+		mockDeliveries.forgetMock = func(subject string) {
+			forgotten = subject
+		}
+
+		expected := EraseUserResponse{Id: e.Id, ErasedAt: erased.UpdatedAt}
+
+		mockBroker := &pubsub.MockBroker{}
+		mockBroker.PublishMock = func(s string, i interface{}) {
+			assert.Equal(t, UserErasedTopic, s)
+			assert.Equal(t, expected, i)
+		}
+
+		service := NewService(WithRepository(mockRepo), WithBroker(mockBroker), WithDeliveryLog(mockDeliveries))
+
+		actual, err := service.Erase(context.Background(), EraseUserRequest{Id: e.Id})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+		assert.Equal(t, e.Id, forgotten, "should forget the notifications about the user")
+
+		assert.Len(t, audited, 1)
+		assert.Equal(t, AuditActionErased, audited[0].Action)
+		for field, change := range audited[0].Changes {
+			assert.Equal(t, FieldChange{}, change, "should not record the erased value of %s", field)
+		}
+		assert.Contains(t, audited[0].Changes, "email")
+		assert.Equal(t, erased.Nickname, audited[0].Snapshot.Nickname)
+	})
+
+	t.Run("should return not found when the user does not exist or is already erased", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.eraseMock = func(ctx context.Context, id string) (EntityChange, error) {
+			return EntityChange{}, ErrNotFound
+		}
+
+		service := NewService(WithRepository(mockRepo))
+
+		_, err := service.Erase(context.Background(), EraseUserRequest{Id: e.Id})
+		assert.Equal(t, userNotFoundError(e.Id), err)
+	})
+}
+
+func TestWritePersonalDataArchive(t *testing.T) {
+	data := PersonalDataResponse{
+		User:                User{Id: e.Id, Nickname: e.Nickname},
+		History:             []AuditEntry{{Id: 1, UserId: e.Id, Action: AuditActionCreated}},
+		PasswordResetTokens: []PasswordResetToken{},
+		Subscriptions:       []Subscription{},
+		Deliveries:          []notify.Delivery{},
+		ExportedAt:          time.Now(),
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, writePersonalDataArchive(&buf, data))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+
+	files := map[string][]byte{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		assert.NoError(t, err)
+		files[f.Name], err = io.ReadAll(r)
+		assert.NoError(t, err)
+	}
+
+	assert.Len(t, files, 5)
+	assert.Contains(t, files, "password_reset_tokens.json")
+	assert.Contains(t, files, "subscriptions.json")
+	assert.Contains(t, files, "deliveries.json")
+
+	var user User
+	assert.NoError(t, json.Unmarshal(files["user.json"], &user))
+	assert.Equal(t, e.Nickname, user.Nickname)
+
+	var history []AuditEntry
+	assert.NoError(t, json.Unmarshal(files["history.json"], &history))
+	assert.Len(t, history, 1)
+}
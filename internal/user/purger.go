@@ -0,0 +1,89 @@
+package user
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// purger permanently deletes the users regularly
+// once they have been soft deleted for longer than the retention period.
+type purger struct {
+	repo      Repository
+	retention time.Duration
+	interval  time.Duration
+	logger    *logrus.Logger
+}
+
+type PurgerOpts func(*purger)
+
+func NewPurger(opts ...PurgerOpts) *purger {
+	p := &purger{}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+func WithPurgerRepository(repo Repository) PurgerOpts {
+	return func(p *purger) {
+		p.repo = repo
+	}
+}
+
+func WithPurgerLogger(logger *logrus.Logger) PurgerOpts {
+	return func(p *purger) {
+		p.logger = logger
+	}
+}
+
+// WithRetention sets how long the soft deleted users are kept before they are purged.
+func WithRetention(retention time.Duration) PurgerOpts {
+	return func(p *purger) {
+		p.retention = retention
+	}
+}
+
+// WithPurgeInterval sets how often the soft deleted users are checked.
+func WithPurgeInterval(interval time.Duration) PurgerOpts {
+	return func(p *purger) {
+		p.interval = interval
+	}
+}
+
+// Start runs the purge async on every interval until the context is done.
+func (p *purger) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.purge(ctx)
+			}
+		}
+	}()
+}
+
+func (p *purger) purge(ctx context.Context) {
+	deletedBefore := time.Now().Add(-p.retention)
+
+	purged, err := p.repo.PurgeDeleted(ctx, deletedBefore)
+	if err != nil {
+		p.logger.WithFields(logrus.Fields{
+			"deletedBefore": deletedBefore,
+			"error":         err.Error(),
+		}).Error("cannot purge the deleted users")
+		return
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"deletedBefore": deletedBefore,
+		"purged":        purged,
+	}).Debug("purged the deleted users")
+}
@@ -0,0 +1,75 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPurger_Start(t *testing.T) {
+	t.Run("should purge the users deleted before the retention period", func(t *testing.T) {
+		purged := make(chan time.Time, 1)
+
+		mockRepo := &mockRepository{}
+		mockRepo.purgeMock = func(ctx context.Context, before time.Time) (int64, error) {
+			select {
+			case purged <- before:
+			default:
+			}
+
+			return 1, nil
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		NewPurger(
+			WithPurgerRepository(mockRepo),
+			WithPurgerLogger(logrus.New()),
+			WithRetention(time.Hour),
+			WithPurgeInterval(time.Millisecond),
+		).Start(ctx)
+
+		select {
+		case before := <-purged:
+			assert.WithinDuration(t, time.Now().Add(-time.Hour), before, time.Second)
+		case <-time.After(time.Second):
+			t.Fatal("should purge the deleted users on every interval")
+		}
+	})
+
+	t.Run("should keep running when the repository fails", func(t *testing.T) {
+		calls := make(chan struct{}, 2)
+
+		mockRepo := &mockRepository{}
+		mockRepo.purgeMock = func(ctx context.Context, before time.Time) (int64, error) {
+			select {
+			case calls <- struct{}{}:
+			default:
+			}
+
+			return 0, fmt.Errorf("mock error")
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		NewPurger(
+			WithPurgerRepository(mockRepo),
+			WithPurgerLogger(logrus.New()),
+			WithRetention(time.Hour),
+			WithPurgeInterval(time.Millisecond),
+		).Start(ctx)
+
+		for i := 0; i < 2; i++ {
+			select {
+			case <-calls:
+			case <-time.After(time.Second):
+				t.Fatal("should purge again after an error")
+			}
+		}
+	})
+}
@@ -2,30 +2,238 @@ package user
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
-	"reflect"
+	"github.com/lib/pq"
+	"sort"
 	"strings"
+	"time"
 )
 
 const insertUserQuery = `INSERT INTO users(first_name, last_name, nickname, password, email, country) 
 							values(:first_name, :last_name, :nickname, :password, :email, :country)
-							RETURNING id, created_at, updated_at;`
+							RETURNING id, version, created_at, updated_at;`
+
+// copyUserColumns are the columns of the users inserted by COPY.
+var copyUserColumns = []string{"id", "first_name", "last_name", "nickname", "password", "email", "country",
+	"version", "created_at", "updated_at"}
+
+// the state of the user prior to an update is selected and locked by previousUserFrom
+// so that it can be returned together with the updated state by changeReturning.
+const previousUserFrom = `FROM (SELECT id, first_name, last_name, nickname, password, email, country, 
+						version, created_at, updated_at, deleted_at, email_verified_at FROM users WHERE id=:id FOR UPDATE) previous`
+const changeReturning = `RETURNING users.id, users.first_name, users.last_name, users.nickname, 
+						users.password, users.email, users.country, users.version, 
+						users.created_at, users.updated_at, users.deleted_at, users.email_verified_at, 
+						previous.id AS "previous.id", previous.first_name AS "previous.first_name", 
+						previous.last_name AS "previous.last_name", previous.nickname AS "previous.nickname", 
+						previous.password AS "previous.password", previous.email AS "previous.email", 
+						previous.country AS "previous.country", previous.version AS "previous.version", 
+						previous.created_at AS "previous.created_at", previous.updated_at AS "previous.updated_at", 
+						previous.deleted_at AS "previous.deleted_at", previous.email_verified_at AS "previous.email_verified_at";`
+
+// the verification of the email is kept only if the email is not changed by the update.
+const emailVerifiedAtAssignment = `CASE WHEN users.email=:email THEN users.email_verified_at END`
+
+// the version condition is skipped when the expected version is 0.
 const updateUserQuery = `UPDATE users SET first_name=:first_name, last_name=:last_name, 
-						nickname=:nickname, password=:password, email=:email, country=:country 
-						where id=:id
-						RETURNING created_at, updated_at;`
-const deleteUserByIdQuery = `DELETE FROM users WHERE id=:id;`
-const selectUsersQuery = `SELECT id, first_name, last_name, nickname, 
-							password, email, country, created_at, updated_at FROM users WHERE 1 = 1`
+						nickname=:nickname, password=:password, email=:email, country=:country, 
+						email_verified_at=` + emailVerifiedAtAssignment + `, version=users.version + 1 ` + previousUserFrom + ` 
+						WHERE users.id=previous.id AND users.deleted_at IS NULL 
+						AND (:version = 0 OR users.version=:version) ` + changeReturning
+const patchUserQuery = `UPDATE users SET %s, version=users.version + 1 ` + previousUserFrom + ` 
+						WHERE users.id=previous.id AND users.deleted_at IS NULL 
+						AND (:version = 0 OR users.version=:version) ` + changeReturning
+const deleteUserByIdQuery = `UPDATE users SET deleted_at=current_timestamp, version=users.version + 1 ` + previousUserFrom + ` 
+						WHERE users.id=previous.id AND users.deleted_at IS NULL 
+						AND (:version = 0 OR users.version=:version) ` + changeReturning
+const restoreUserQuery = `UPDATE users SET deleted_at=NULL, version=users.version + 1 ` + previousUserFrom + ` 
+						WHERE users.id=previous.id AND users.deleted_at IS NOT NULL AND users.erased_at IS NULL ` + changeReturning
+
+// purgeDeletedUsersQuery deletes the users together with their password reset tokens and their audit entries in the same
+// statement, since the snapshots of the entries contain the personal data. The purge itself is not recorded then.
+// The erased users are kept, so that they can still be referred to by their ids.
+const purgeDeletedUsersQuery = `WITH purged AS (
+							DELETE FROM users WHERE deleted_at IS NOT NULL AND deleted_at < :deleted_before 
+							AND erased_at IS NULL RETURNING id
+						), tokens AS (
+							DELETE FROM password_reset_tokens WHERE user_id IN (SELECT id FROM purged)
+						), audit AS (
+							DELETE FROM user_audit WHERE user_id IN (SELECT id FROM purged)
+						) SELECT count(*) FROM purged;`
+const selectUsersQuery = `SELECT %s FROM users WHERE 1 = 1`
+const countUsersQuery = `SELECT COUNT(*) FROM users WHERE 1 = 1`
+
+// the exported users are fetched from a server-side cursor in batches of exportFetchSize.
+const declareExportCursorQuery = `DECLARE users_export NO SCROLL CURSOR FOR %s`
+const fetchExportCursorQuery = `FETCH %d FROM users_export;`
+const exportFetchSize = 1000
+
+// searchDocument is the text the users are searched in, the indexes on it are created by init_db.sql
+// so it has to be the same as the indexed expression.
+const searchDocument = `(first_name || ' ' || last_name || ' ' || nickname || ' ' || email)`
+
+// the users either containing words starting with the terms or having words similar to the query are found,
+// the trigram similarity finds the misspelled names which the full-text search cannot.
+const searchUsersQuery = `SELECT id, first_name, last_name, nickname, 
+							password, email, country, version, created_at, updated_at, deleted_at, email_verified_at, 
+							ts_rank(to_tsvector('simple', ` + searchDocument + `), to_tsquery('simple', :terms)) 
+							+ word_similarity(:query, ` + searchDocument + `) AS rank FROM users 
+							WHERE deleted_at IS NULL AND (to_tsvector('simple', ` + searchDocument + `) @@ to_tsquery('simple', :terms) 
+							OR :query <% ` + searchDocument + `) 
+							ORDER BY rank DESC, created_at ASC, id ASC LIMIT :limit OFFSET :offset;`
+const selectUserByIdQuery = `SELECT id, first_name, last_name, nickname, 
+							password, email, country, version, created_at, updated_at, deleted_at, email_verified_at FROM users 
+							WHERE id=:id AND deleted_at IS NULL;`
+const selectUserByLoginQuery = `SELECT id, first_name, last_name, nickname, 
+							password, email, country, version, created_at, updated_at, deleted_at, email_verified_at FROM users 
+							WHERE (lower(nickname)=lower(:login) OR lower(email)=lower(:login)) AND deleted_at IS NULL LIMIT 1;`
+const selectTakenNicknamesQuery = `SELECT lower(nickname) FROM users WHERE lower(nickname) = ANY(:nicknames);`
+const verifyEmailQuery = `UPDATE users SET email_verified_at=current_timestamp, version=users.version + 1 ` + previousUserFrom + ` 
+						WHERE users.id=previous.id AND users.deleted_at IS NULL AND users.email=:email 
+						AND users.email_verified_at IS NULL ` + changeReturning
+const updateUserPasswordQuery = `UPDATE users SET password=:password WHERE id=:id;`
+
+// the tokens are revoked when the user is deleted or its password is reset after they are issued. The issue times
+// of the tokens are truncated to seconds, so a token issued in the same second as the reset is not revoked.
+const tokenRevokedQuery = `SELECT NOT EXISTS (SELECT 1 FROM users WHERE id=:id AND deleted_at IS NULL 
+						AND (password_changed_at IS NULL OR password_changed_at < :issued_at + interval '1 second'));`
+
+// createPasswordResetTokenQuery looks the user up by the email using its unique index and stores the token in the
+// same statement, so that the request takes about as long whether the email belongs to a user or not.
+const createPasswordResetTokenQuery = `WITH target AS (
+							SELECT id, first_name, last_name, nickname, password, email, country, version, 
+							created_at, updated_at, deleted_at, email_verified_at FROM users 
+							WHERE lower(email)=lower(:email) AND deleted_at IS NULL
+						), token AS (
+							INSERT INTO password_reset_tokens (user_id, token_hash, expires_at) 
+							SELECT id, :token_hash, current_timestamp + make_interval(secs => :ttl) FROM target 
+							RETURNING user_id
+						) SELECT target.id, first_name, last_name, nickname, password, email, country, version, 
+						created_at, updated_at, deleted_at, email_verified_at FROM target JOIN token ON token.user_id=target.id;`
+
+// the audit entries are copied like the imported users, so that the entries of an import are appended at once.
+var copyAuditColumns = []string{"user_id", "action", "actor", "request_id", "changes", "snapshot"}
+
+const selectHistoryQuery = `SELECT id, user_id, action, actor, request_id, changes, snapshot, created_at FROM user_audit 
+						WHERE user_id=:user_id ORDER BY id DESC LIMIT :limit OFFSET :offset;`
+const selectSnapshotQuery = `SELECT id, user_id, action, actor, request_id, changes, snapshot, created_at FROM user_audit 
+						WHERE user_id=:user_id AND created_at <= :at ORDER BY id DESC LIMIT 1;`
+
+// eraseUserQuery anonymizes the user and deletes its password reset tokens, its audit entries and the responses
+// stored for its idempotency keys in the same statement. The row is kept deleted, so that the user can still be
+// referred to by its id, and the nickname and the email are replaced by unique values no user can register,
+// since the nicknames cannot start with an underscore.
+const eraseUserQuery = `WITH tokens AS (
+							DELETE FROM password_reset_tokens WHERE user_id=:id
+						), audit AS (
+							DELETE FROM user_audit WHERE user_id=:id
+						), idempotency AS (
+							DELETE FROM idempotency_keys WHERE client=:id
+						)
+						UPDATE users SET first_name='', last_name='', password='', country='', 
+						nickname='_erased_' || substr(replace(CAST(users.id AS text), '-', ''), 1, 22), 
+						email='_erased_' || replace(CAST(users.id AS text), '-', '') || '@erased.invalid', 
+						email_verified_at=NULL, deleted_at=coalesce(users.deleted_at, current_timestamp), 
+						erased_at=current_timestamp, version=users.version + 1 ` + previousUserFrom + ` 
+						WHERE users.id=previous.id AND users.erased_at IS NULL ` + changeReturning
+
+// the personal data of a user is selected regardless of whether the user is deleted or erased.
+const selectPersonalDataQuery = `SELECT id, first_name, last_name, nickname, password, email, country, version, 
+						created_at, updated_at, deleted_at, email_verified_at, erased_at FROM users WHERE id=:id;`
+const selectAllHistoryQuery = `SELECT id, user_id, action, actor, request_id, changes, snapshot, created_at FROM user_audit 
+						WHERE user_id=:user_id ORDER BY id;`
+const selectPasswordResetTokensQuery = `SELECT id, expires_at, used_at, created_at FROM password_reset_tokens 
+						WHERE user_id=:user_id ORDER BY created_at;`
+
+// resetPasswordQuery uses the token and invalidates the other outstanding tokens of the user in the same statement,
+// so that a token cannot be used twice even by concurrent requests.
+const resetPasswordQuery = `WITH token AS (
+							UPDATE password_reset_tokens SET used_at=current_timestamp 
+							WHERE token_hash=:token_hash AND used_at IS NULL AND expires_at > current_timestamp 
+							RETURNING user_id
+						), outstanding AS (
+							UPDATE password_reset_tokens SET used_at=current_timestamp FROM token 
+							WHERE password_reset_tokens.user_id=token.user_id AND password_reset_tokens.used_at IS NULL 
+							AND password_reset_tokens.token_hash<>:token_hash
+						)
+						UPDATE users SET password=:password, password_changed_at=current_timestamp, 
+						version=users.version + 1 FROM token 
+						WHERE users.id=token.user_id AND users.deleted_at IS NULL 
+						RETURNING users.id, users.first_name, users.last_name, users.nickname, users.password, users.email, 
+						users.country, users.version, users.created_at, users.updated_at, users.deleted_at, users.email_verified_at;`
+
+// userColumns are the columns selected by the users listing unless only some of the fields are requested.
+var userColumns = []string{"id", "first_name", "last_name", "nickname", "password", "email", "country",
+	"version", "created_at", "updated_at", "deleted_at", "email_verified_at"}
+
+// patchableColumns are the columns that can be changed by a partial update.
+var patchableColumns = map[string]bool{
+	"first_name": true,
+	"last_name":  true,
+	"nickname":   true,
+	"password":   true,
+	"email":      true,
+	"country":    true,
+}
 
 type repository struct {
 	db *sqlx.DB
+	// tx is the transaction the repository runs the queries in, if there is one.
+	tx *sqlx.Tx
+	// statements are prepared once in the transaction and reused by the queries in it.
+	statements map[string]*sqlx.NamedStmt
+	savepoints int
 }
 
 var _ Repository = (*repository)(nil)
 
+// ErrNotFound is returned by the repository when there is no user to read or to write,
+// the conditional writes return it also when the user doesn't have the expected version.
+var ErrNotFound = errors.New("user not found")
+
+// ConflictError is returned by the repository when a write violates a unique constraint of the users.
+type ConflictError struct {
+	Constraint string
+	// Field is the field of the user which has to be unique.
+	Field string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s violates the unique constraint %s", e.Field, e.Constraint)
+}
+
+// uniqueConstraints are the fields of the unique constraints created by init_db.sql.
+var uniqueConstraints = map[string]string{
+	"users_pkey":         "id",
+	"users_nickname_key": "nickname",
+	"users_email_key":    "email",
+}
+
+// pqUniqueViolation is the code of the unique_violation errors of Postgres.
+const pqUniqueViolation = "23505"
+
+// dbError translates the errors of the database into the errors of the repository.
+func dbError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation {
+		field, ok := uniqueConstraints[pqErr.Constraint]
+		if !ok {
+			field = pqErr.Constraint
+		}
+
+		return &ConflictError{Constraint: pqErr.Constraint, Field: field}
+	}
+
+	return err
+}
+
 type RepositoryOpts func(*repository)
 
 func NewRepository(opts ...RepositoryOpts) *repository {
@@ -44,51 +252,434 @@ func WithDb(db *sqlx.DB) RepositoryOpts {
 	}
 }
 
+// Transaction runs fn with a repository running the queries in a transaction, which is committed unless fn
+// returns an error. The transactions started by the repository of a transaction are its savepoints, so that
+// only the queries of the failed ones are rolled back.
+func (r *repository) Transaction(ctx context.Context, fn func(repo Repository) error) error {
+	if r.tx != nil {
+		return r.savepoint(ctx, fn)
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	txRepo := &repository{db: r.db, tx: tx, statements: map[string]*sqlx.NamedStmt{}}
+	defer txRepo.closeStatements()
+
+	err = fn(txRepo)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *repository) savepoint(ctx context.Context, fn func(repo Repository) error) error {
+	r.savepoints++
+	name := fmt.Sprintf("savepoint_%d", r.savepoints)
+
+	_, err := r.tx.ExecContext(ctx, "SAVEPOINT "+name)
+	if err != nil {
+		return err
+	}
+
+	err = fn(r)
+	if err != nil {
+		// the error of fn is returned even if rolling back fails, since the transaction cannot be committed then.
+		_, _ = r.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		return err
+	}
+
+	_, err = r.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+// prepare returns the prepared statement of the query, the statements of a transaction are cached.
+func (r *repository) prepare(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	if r.tx == nil {
+		return r.db.PrepareNamedContext(ctx, query)
+	}
+
+	if stmt, ok := r.statements[query]; ok {
+		return stmt, nil
+	}
+
+	stmt, err := r.tx.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	r.statements[query] = stmt
+	return stmt, nil
+}
+
+// release closes the statement unless it is cached by the transaction.
+func (r *repository) release(stmt *sqlx.NamedStmt) {
+	if r.tx == nil {
+		stmt.Close()
+	}
+}
+
+func (r *repository) closeStatements() {
+	for _, stmt := range r.statements {
+		stmt.Close()
+	}
+}
+
 func (r *repository) Create(ctx context.Context, entity Entity) (Entity, error) {
-	stmt, err := r.db.PrepareNamedContext(ctx, insertUserQuery)
+	stmt, err := r.prepare(ctx, insertUserQuery)
 	if err != nil {
 		return Entity{}, err
 	}
+	defer r.release(stmt)
 
-	err = stmt.QueryRowxContext(ctx, entity).Scan(&entity.Id, &entity.CreatedAt, &entity.UpdatedAt)
+	err = stmt.QueryRowxContext(ctx, entity).Scan(&entity.Id, &entity.Version, &entity.CreatedAt, &entity.UpdatedAt)
 	if err != nil {
-		return Entity{}, err
+		return Entity{}, dbError(err)
 	}
 
 	return entity, nil
 }
 
-func (r *repository) Update(ctx context.Context, entity Entity) (Entity, error) {
-	stmt, err := r.db.PrepareNamedContext(ctx, updateUserQuery)
+// CreateMany inserts the users by COPY, which is much faster than inserting them one by one.
+// Since COPY doesn't return anything, the ids, the versions and the timestamps of the entities are inserted as they are.
+func (r *repository) CreateMany(ctx context.Context, entities []Entity) error {
+	if r.tx == nil {
+		// COPY can only be run in a transaction.
+		return r.Transaction(ctx, func(repo Repository) error {
+			return repo.CreateMany(ctx, entities)
+		})
+	}
+
+	stmt, err := r.tx.PrepareContext(ctx, pq.CopyIn("users", copyUserColumns...))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, e := range entities {
+		_, err = stmt.ExecContext(ctx, e.Id, e.FirstName, e.LastName, e.Nickname, e.Password, e.Email, e.Country,
+			e.Version, e.CreatedAt, e.UpdatedAt)
+		if err != nil {
+			return dbError(err)
+		}
+	}
+
+	// the rows are flushed by the exec without arguments.
+	_, err = stmt.ExecContext(ctx)
+	return dbError(err)
+}
+
+// Update replaces the user if its version is equal to the version of the entity and returns the user
+// together with its previous state, ErrNotFound is returned when the user does not exist or the versions don't match.
+func (r *repository) Update(ctx context.Context, entity Entity) (EntityChange, error) {
+	stmt, err := r.prepare(ctx, updateUserQuery)
+	if err != nil {
+		return EntityChange{}, err
+	}
+	defer r.release(stmt)
+
+	var change EntityChange
+	err = stmt.QueryRowxContext(ctx, entity).StructScan(&change)
+	return change, dbError(err)
+}
+
+// Patch updates only the given columns of the user if its version is equal to the given version
+// and returns the updated user together with its previous state.
+func (r *repository) Patch(ctx context.Context, id string, version int, changes map[string]interface{}) (EntityChange, error) {
+	query, err := r.createPatchQuery(changes)
+	if err != nil {
+		return EntityChange{}, err
+	}
+
+	stmt, err := r.prepare(ctx, query)
+	if err != nil {
+		return EntityChange{}, err
+	}
+	defer r.release(stmt)
+
+	args := map[string]interface{}{"id": id, "version": version}
+	for column, value := range changes {
+		args[column] = value
+	}
+
+	var change EntityChange
+	err = stmt.QueryRowxContext(ctx, args).StructScan(&change)
+	return change, dbError(err)
+}
+
+// DeleteById soft deletes the user if its version is equal to the given version,
+// ErrNotFound is returned when no user is deleted.
+func (r *repository) DeleteById(ctx context.Context, id string, version int) (EntityChange, error) {
+	stmt, err := r.prepare(ctx, deleteUserByIdQuery)
+	if err != nil {
+		return EntityChange{}, err
+	}
+	defer r.release(stmt)
+
+	var change EntityChange
+	err = stmt.QueryRowxContext(ctx, map[string]interface{}{"id": id, "version": version}).StructScan(&change)
+	return change, dbError(err)
+}
+
+// Restore brings back the soft deleted user and returns it together with its previous state,
+// ErrNotFound is returned when there is no deleted user with the id.
+func (r *repository) Restore(ctx context.Context, id string) (EntityChange, error) {
+	stmt, err := r.prepare(ctx, restoreUserQuery)
+	if err != nil {
+		return EntityChange{}, err
+	}
+	defer r.release(stmt)
+
+	var change EntityChange
+	err = stmt.QueryRowxContext(ctx, map[string]interface{}{"id": id}).StructScan(&change)
+	return change, dbError(err)
+}
+
+// PurgeDeleted permanently deletes the users soft deleted before the given time together with their history
+// and returns how many users are deleted.
+func (r *repository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	stmt, err := r.prepare(ctx, purgeDeletedUsersQuery)
+	if err != nil {
+		return 0, err
+	}
+	defer r.release(stmt)
+
+	var purged int64
+	err = stmt.QueryRowxContext(ctx, map[string]interface{}{"deleted_before": before}).Scan(&purged)
+	return purged, err
+}
+
+func (r *repository) GetById(ctx context.Context, id string) (Entity, error) {
+	stmt, err := r.prepare(ctx, selectUserByIdQuery)
+	if err != nil {
+		return Entity{}, err
+	}
+	defer r.release(stmt)
+
+	var entity Entity
+	err = stmt.QueryRowxContext(ctx, map[string]interface{}{"id": id}).StructScan(&entity)
+	return entity, dbError(err)
+}
+
+func (r *repository) GetByLogin(ctx context.Context, login string) (Entity, error) {
+	stmt, err := r.prepare(ctx, selectUserByLoginQuery)
 	if err != nil {
 		return Entity{}, err
 	}
+	defer r.release(stmt)
+
+	var entity Entity
+	err = stmt.QueryRowxContext(ctx, map[string]interface{}{"login": login}).StructScan(&entity)
+	return entity, dbError(err)
+}
+
+// VerifyEmail marks the email of the user as verified if it is still the email of the user and it is not verified yet,
+// and returns the user together with its previous state. ErrNotFound is returned when no user is verified.
+func (r *repository) VerifyEmail(ctx context.Context, id string, email string) (EntityChange, error) {
+	stmt, err := r.prepare(ctx, verifyEmailQuery)
+	if err != nil {
+		return EntityChange{}, err
+	}
+	defer r.release(stmt)
+
+	var change EntityChange
+	err = stmt.QueryRowxContext(ctx, map[string]interface{}{"id": id, "email": email}).StructScan(&change)
+	return change, dbError(err)
+}
+
+// TakenNicknames returns the nicknames used by the users, including the deleted ones, regardless of their case.
+func (r *repository) TakenNicknames(ctx context.Context, nicknames []string) ([]string, error) {
+	stmt, err := r.prepare(ctx, selectTakenNicknamesQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer r.release(stmt)
 
-	err = stmt.QueryRowContext(ctx, entity).Scan(&entity.CreatedAt, &entity.UpdatedAt)
-	return entity, err
+	var taken []string
+	err = stmt.SelectContext(ctx, &taken, map[string]interface{}{"nicknames": pq.Array(nicknames)})
+	return taken, err
 }
 
-func (r *repository) DeleteById(ctx context.Context, id string) error {
-	stmt, err := r.db.PrepareNamedContext(ctx, deleteUserByIdQuery)
+func (r *repository) UpdatePassword(ctx context.Context, id string, password string) error {
+	stmt, err := r.prepare(ctx, updateUserPasswordQuery)
 	if err != nil {
 		return err
 	}
+	defer r.release(stmt)
 
-	_, err = stmt.ExecContext(ctx, map[string]interface{}{"id": id})
+	_, err = stmt.ExecContext(ctx, map[string]interface{}{"id": id, "password": password})
+	return err
+}
+
+// TokenRevoked reports whether the tokens of the user issued at the time are revoked,
+// the time is given in UTC since it is compared with the timestamps without time zone.
+func (r *repository) TokenRevoked(ctx context.Context, id string, issuedAt time.Time) (bool, error) {
+	stmt, err := r.prepare(ctx, tokenRevokedQuery)
+	if err != nil {
+		return false, err
+	}
+	defer r.release(stmt)
+
+	var revoked bool
+	err = stmt.QueryRowxContext(ctx, map[string]interface{}{"id": id, "issued_at": issuedAt.UTC()}).Scan(&revoked)
+	return revoked, err
+}
+
+// CreatePasswordResetToken stores the hash of a password reset token of the user having the email, which expires
+// after the ttl, and returns the user. ErrNotFound is returned when no user has the email.
+func (r *repository) CreatePasswordResetToken(ctx context.Context, email string, tokenHash string, ttl time.Duration) (Entity, error) {
+	stmt, err := r.prepare(ctx, createPasswordResetTokenQuery)
+	if err != nil {
+		return Entity{}, err
+	}
+	defer r.release(stmt)
+
+	var entity Entity
+	err = stmt.QueryRowxContext(ctx, map[string]interface{}{"email": email, "token_hash": tokenHash, "ttl": ttl.Seconds()}).StructScan(&entity)
+	return entity, dbError(err)
+}
+
+// ResetPassword changes the password of the user the token is issued for and returns the user.
+// ErrNotFound is returned when the token is unknown, used or expired.
+func (r *repository) ResetPassword(ctx context.Context, tokenHash string, password string) (Entity, error) {
+	stmt, err := r.prepare(ctx, resetPasswordQuery)
+	if err != nil {
+		return Entity{}, err
+	}
+	defer r.release(stmt)
+
+	var entity Entity
+	err = stmt.QueryRowxContext(ctx, map[string]interface{}{"token_hash": tokenHash, "password": password}).StructScan(&entity)
+	return entity, dbError(err)
+}
+
+// AppendAudit appends the entries to the change history of the users.
+func (r *repository) AppendAudit(ctx context.Context, entities ...AuditEntity) error {
+	if r.tx == nil {
+		// COPY can only be run in a transaction.
+		return r.Transaction(ctx, func(repo Repository) error {
+			return repo.AppendAudit(ctx, entities...)
+		})
+	}
+
+	stmt, err := r.tx.PrepareContext(ctx, pq.CopyIn("user_audit", copyAuditColumns...))
+	if err != nil {
+		return err
+	}
 	defer stmt.Close()
+
+	for _, e := range entities {
+		_, err = stmt.ExecContext(ctx, e.UserId, e.Action, e.Actor, e.RequestId, e.Changes, e.Snapshot)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = stmt.ExecContext(ctx)
 	return err
 }
 
+// GetHistory returns the audit entries of the user, the latest first.
+func (r *repository) GetHistory(ctx context.Context, userId string, limit int, offset int) ([]AuditEntity, error) {
+	stmt, err := r.prepare(ctx, selectHistoryQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer r.release(stmt)
+
+	entities := []AuditEntity{}
+	err = stmt.SelectContext(ctx, &entities, map[string]interface{}{"user_id": userId, "limit": limit, "offset": offset})
+	return entities, err
+}
+
+// GetSnapshot returns the latest audit entry of the user appended until the time,
+// ErrNotFound is returned when there is none.
+func (r *repository) GetSnapshot(ctx context.Context, userId string, at time.Time) (AuditEntity, error) {
+	stmt, err := r.prepare(ctx, selectSnapshotQuery)
+	if err != nil {
+		return AuditEntity{}, err
+	}
+	defer r.release(stmt)
+
+	var entity AuditEntity
+	err = stmt.QueryRowxContext(ctx, map[string]interface{}{"user_id": userId, "at": at}).StructScan(&entity)
+	return entity, dbError(err)
+}
+
+// Erase anonymizes the user irreversibly together with its password reset tokens and its change history,
+// and returns it together with its previous state. ErrNotFound is returned when there is no user with the id
+// or it is already erased.
+func (r *repository) Erase(ctx context.Context, id string) (EntityChange, error) {
+	stmt, err := r.prepare(ctx, eraseUserQuery)
+	if err != nil {
+		return EntityChange{}, err
+	}
+	defer r.release(stmt)
+
+	var change EntityChange
+	err = stmt.QueryRowxContext(ctx, map[string]interface{}{"id": id}).StructScan(&change)
+	return change, dbError(err)
+}
+
+// GetPersonalData returns everything stored about the user even if it is deleted or erased,
+// ErrNotFound is returned when there is no user with the id.
+func (r *repository) GetPersonalData(ctx context.Context, id string) (PersonalDataEntity, error) {
+	stmt, err := r.prepare(ctx, selectPersonalDataQuery)
+	if err != nil {
+		return PersonalDataEntity{}, err
+	}
+	defer r.release(stmt)
+
+	var data PersonalDataEntity
+	err = stmt.QueryRowxContext(ctx, map[string]interface{}{"id": id}).StructScan(&data)
+	if err != nil {
+		return PersonalDataEntity{}, dbError(err)
+	}
+
+	historyStmt, err := r.prepare(ctx, selectAllHistoryQuery)
+	if err != nil {
+		return PersonalDataEntity{}, err
+	}
+	defer r.release(historyStmt)
+
+	data.History = []AuditEntity{}
+	err = historyStmt.SelectContext(ctx, &data.History, map[string]interface{}{"user_id": id})
+	if err != nil {
+		return PersonalDataEntity{}, err
+	}
+
+	tokensStmt, err := r.prepare(ctx, selectPasswordResetTokensQuery)
+	if err != nil {
+		return PersonalDataEntity{}, err
+	}
+	defer r.release(tokensStmt)
+
+	data.PasswordResetTokens = []PasswordResetTokenEntity{}
+	err = tokensStmt.SelectContext(ctx, &data.PasswordResetTokens, map[string]interface{}{"user_id": id})
+	return data, err
+}
+
+// GetMany returns the users matching the filter ordered by created_at and id,
+// either the users at the offset or the users after (or before) the cursor are returned.
 func (r *repository) GetMany(ctx context.Context, parameters GetManyParameters) ([]Entity, error) {
 	var entities []Entity
 
-	query := r.createGetManyQuery(parameters, selectUsersQuery)
-	stmt, err := r.db.PrepareNamedContext(ctx, query)
+	query, args, err := r.createGetManyQuery(parameters, fmt.Sprintf(selectUsersQuery, r.createSelectColumns(parameters)))
+	if err != nil {
+		return entities, err
+	}
+
+	stmt, err := r.prepare(ctx, query)
 	if err != nil {
 		return entities, err
 	}
+	defer r.release(stmt)
 
-	rows, err := stmt.QueryxContext(ctx, parameters.Filter)
+	rows, err := stmt.QueryxContext(ctx, args)
 	if err != nil {
 		return entities, err
 	}
@@ -104,42 +695,323 @@ func (r *repository) GetMany(ctx context.Context, parameters GetManyParameters)
 		entities = append(entities, entity)
 	}
 
-	return entities, nil
+	// the users before a backward cursor are selected in descending order.
+	if parameters.Cursor != nil && parameters.Cursor.Backward {
+		for i, j := 0, len(entities)-1; i < j; i, j = i+1, j-1 {
+			entities[i], entities[j] = entities[j], entities[i]
+		}
+	}
+
+	return entities, rows.Err()
+}
+
+// Export calls fn with every user matching the filter of the parameters in order, the pagination of the parameters is ignored.
+// The users are fetched from a cursor in a read only transaction, so only a batch of them is kept in memory at a time.
+func (r *repository) Export(ctx context.Context, parameters GetManyParameters, fn func(entity Entity) error) error {
+	parameters.Limit, parameters.Offset, parameters.Cursor = 0, 0, nil
+
+	query, args, err := r.createGetManyQuery(parameters, fmt.Sprintf(selectUsersQuery, r.createSelectColumns(parameters)))
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	// nothing is changed by the export, so the transaction is always rolled back, which closes the cursor as well.
+	defer tx.Rollback()
+
+	query, namedArgs, err := tx.BindNamed(fmt.Sprintf(declareExportCursorQuery, query), args)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query, namedArgs...)
+	if err != nil {
+		return err
+	}
+
+	fetch := fmt.Sprintf(fetchExportCursorQuery, exportFetchSize)
+	for {
+		rows, err := tx.QueryxContext(ctx, fetch)
+		if err != nil {
+			return err
+		}
+
+		fetched := 0
+		for rows.Next() {
+			fetched++
+
+			var entity Entity
+			err = rows.StructScan(&entity)
+			if err == nil {
+				err = fn(entity)
+			}
+			if err != nil {
+				rows.Close()
+				return err
+			}
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		if fetched < exportFetchSize {
+			return nil
+		}
+	}
 }
 
-func (r *repository) createGetManyQuery(parameters GetManyParameters, query string) string {
+// Count returns the number of the users matching the filter, the pagination of the parameters is ignored.
+func (r *repository) Count(ctx context.Context, parameters GetManyParameters) (int, error) {
+	query, args, err := r.createCountQuery(parameters, countUsersQuery)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := r.prepare(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer r.release(stmt)
+
+	var count int
+	err = stmt.QueryRowxContext(ctx, args).Scan(&count)
+	return count, err
+}
+
+// Search returns the users matching the search query ordered by their relevance.
+func (r *repository) Search(ctx context.Context, parameters SearchParameters) ([]EntityMatch, error) {
+	var matches []EntityMatch
+
+	stmt, err := r.prepare(ctx, searchUsersQuery)
+	if err != nil {
+		return matches, err
+	}
+	defer r.release(stmt)
+
+	args := map[string]interface{}{
+		"query":  parameters.Query,
+		"terms":  prefixQuery(parameters.Terms),
+		"limit":  parameters.Limit,
+		"offset": parameters.Offset,
+	}
+
+	err = stmt.SelectContext(ctx, &matches, args)
+	return matches, err
+}
+
+func (r *repository) createGetManyQuery(parameters GetManyParameters, query string) (string, map[string]interface{}, error) {
 	queryBuilder := strings.Builder{}
 	queryBuilder.WriteString(query)
 
-	filterQuery := r.createFilterQuery(parameters.Filter)
-	paginationQuery := r.createPaginationQuery(parameters.Page, parameters.PerPage)
+	args := map[string]interface{}{}
+	filterQuery, err := r.createFilterQuery(parameters.Filter, args)
+	if err != nil {
+		return "", nil, err
+	}
+	if !parameters.IncludeDeleted {
+		queryBuilder.WriteString(" AND deleted_at IS NULL")
+	}
+	queryBuilder.WriteString(filterQuery)
+
+	sortQuery, err := r.createSortQuery(parameters, args)
+	if err != nil {
+		return "", nil, err
+	}
+	queryBuilder.WriteString(sortQuery)
+	// the users are not limited by the export.
+	if parameters.Limit > 0 {
+		queryBuilder.WriteString(r.createPaginationQuery(parameters.Limit, parameters.Offset))
+	}
+
+	return queryBuilder.String(), args, nil
+}
+
+// createSortQuery creates the order by clause, and the condition selecting the users after (or before) the cursor
+// which are added to args as the named parameters cursor_<column index>. The users before a backward cursor
+// are selected in the reverse order, so that the closest ones are limited.
+func (r *repository) createSortQuery(parameters GetManyParameters, args map[string]interface{}) (string, error) {
+	sort := normalizeSort(parameters.Sort)
+	backward := parameters.Cursor != nil && parameters.Cursor.Backward
+
+	sameDirection := true
+	columns := make([]string, len(sort))
+	orders := make([]string, len(sort))
+	for i, field := range sort {
+		// the columns are written into the query, so only the whitelisted ones are accepted.
+		if _, ok := sortableColumns[field.Column]; !ok {
+			return "", fmt.Errorf("users cannot be sorted by %s", field.Column)
+		}
+
+		columns[i] = field.Column
+		if field.Descending != backward {
+			orders[i] = field.Column + " DESC"
+		} else {
+			orders[i] = field.Column + " ASC"
+		}
+		sameDirection = sameDirection && field.Descending == sort[0].Descending
+	}
+
+	queryBuilder := strings.Builder{}
+	if parameters.Cursor != nil {
+		if len(parameters.Cursor.Values) != len(sort) {
+			return "", fmt.Errorf("cursor has %d values for %d sorted columns", len(parameters.Cursor.Values), len(sort))
+		}
+
+		names := make([]string, len(sort))
+		for i, value := range parameters.Cursor.Values {
+			names[i] = fmt.Sprintf(":cursor_%d", i)
+			args[fmt.Sprintf("cursor_%d", i)] = value
+		}
+
+		operator := func(field SortField) string {
+			if field.Descending != backward {
+				return "<"
+			}
+			return ">"
+		}
 
+		if sameDirection {
+			// the row comparison can use the index on the columns.
+			queryBuilder.WriteString(fmt.Sprintf(" AND (%s) %s (%s)",
+				strings.Join(columns, ", "), operator(sort[0]), strings.Join(names, ", ")))
+		} else {
+			// the users after the cursor have a greater value on a column and the same values on the columns before it,
+			// the greater values are the smaller ones on the columns sorted in descending order.
+			conditions := make([]string, len(sort))
+			for i, field := range sort {
+				terms := make([]string, 0, i+1)
+				for j := 0; j < i; j++ {
+					terms = append(terms, fmt.Sprintf("%s = %s", columns[j], names[j]))
+				}
+				terms = append(terms, fmt.Sprintf("%s %s %s", columns[i], operator(field), names[i]))
+
+				conditions[i] = "(" + strings.Join(terms, " AND ") + ")"
+			}
+			queryBuilder.WriteString(" AND (" + strings.Join(conditions, " OR ") + ")")
+		}
+	}
+
+	queryBuilder.WriteString(" ORDER BY " + strings.Join(orders, ", "))
+
+	return queryBuilder.String(), nil
+}
+
+func (r *repository) createCountQuery(parameters GetManyParameters, query string) (string, map[string]interface{}, error) {
+	queryBuilder := strings.Builder{}
+	queryBuilder.WriteString(query)
+
+	args := map[string]interface{}{}
+	filterQuery, err := r.createFilterQuery(parameters.Filter, args)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if !parameters.IncludeDeleted {
+		queryBuilder.WriteString(" AND deleted_at IS NULL")
+	}
 	queryBuilder.WriteString(filterQuery)
-	queryBuilder.WriteString(" ORDER BY created_at ASC")
-	queryBuilder.WriteString(paginationQuery)
+	queryBuilder.WriteString(";")
+
+	return queryBuilder.String(), args, nil
+}
+
+// createSelectColumns returns the columns of the requested fields together with id and the sorted columns,
+// which are needed by the cursors. All the columns are selected when no fields are requested.
+func (r *repository) createSelectColumns(parameters GetManyParameters) string {
+	if len(parameters.Fields) == 0 {
+		return strings.Join(userColumns, ", ")
+	}
+
+	selected := map[string]bool{"id": true}
+	for _, field := range parameters.Fields {
+		selected[field] = true
+	}
+	for _, field := range normalizeSort(parameters.Sort) {
+		selected[field.Column] = true
+	}
+
+	// the columns are written into the query, so only the known ones are selected in their usual order.
+	columns := make([]string, 0, len(selected))
+	for _, column := range userColumns {
+		if selected[column] && column != "password" {
+			columns = append(columns, column)
+		}
+	}
 
-	return queryBuilder.String()
+	return strings.Join(columns, ", ")
 }
 
-func (r *repository) createFilterQuery(filter Entity) string {
+// createFilterQuery compiles the conditions into the where clause, the values are added to args
+// as the named parameters filter_<condition> (filter_<condition>_<value> for the in operator).
+// Only the whitelisted columns and operators are accepted since they are written into the query.
+func (r *repository) createFilterQuery(filter []FilterCondition, args map[string]interface{}) (string, error) {
 	filterQuery := strings.Builder{}
 
-	t := reflect.TypeOf(&filter).Elem()
-	v := reflect.ValueOf(filter)
-	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		if !field.IsZero() {
-			typeField := t.Field(i)
-			fieldColumnName := typeField.Tag.Get("db")
-			filterQuery.WriteString(fmt.Sprintf(" AND %[1]s=:%[1]s", fieldColumnName))
+	for i, condition := range filter {
+		column, ok := filterColumns[condition.Column]
+		if !ok || !column.operators[condition.Operator] || len(condition.Values) == 0 {
+			return "", fmt.Errorf("invalid filter on column %s", condition.Column)
 		}
+
+		name := fmt.Sprintf("filter_%d", i)
+		operator := filterOperators[condition.Operator]
+
+		switch condition.Operator {
+		case FilterIn:
+			names := make([]string, len(condition.Values))
+			for j, value := range condition.Values {
+				names[j] = fmt.Sprintf(":%s_%d", name, j)
+				args[fmt.Sprintf("%s_%d", name, j)] = value
+			}
+			filterQuery.WriteString(fmt.Sprintf(" AND %s %s (%s)", condition.Column, operator, strings.Join(names, ", ")))
+		case FilterPrefix:
+			args[name] = escapeLike(fmt.Sprint(condition.Values[0])) + "%"
+			filterQuery.WriteString(fmt.Sprintf(" AND %s %s :%s", condition.Column, operator, name))
+		case FilterIlike:
+			args[name] = "%" + escapeLike(fmt.Sprint(condition.Values[0])) + "%"
+			filterQuery.WriteString(fmt.Sprintf(" AND %s %s :%s", condition.Column, operator, name))
+		default:
+			args[name] = condition.Values[0]
+			filterQuery.WriteString(fmt.Sprintf(" AND %s %s :%s", condition.Column, operator, name))
+		}
+	}
+
+	return filterQuery.String(), nil
+}
+
+func (r *repository) createPatchQuery(changes map[string]interface{}) (string, error) {
+	if len(changes) == 0 {
+		return "", fmt.Errorf("no columns to update")
+	}
+
+	columns := make([]string, 0, len(changes))
+	for column := range changes {
+		if !patchableColumns[column] {
+			return "", fmt.Errorf("column %s cannot be updated", column)
+		}
+
+		columns = append(columns, column)
+	}
+	// the columns are sorted to keep the query and the order of its arguments deterministic.
+	sort.Strings(columns)
+
+	assignments := make([]string, len(columns))
+	for i, column := range columns {
+		assignments[i] = fmt.Sprintf("%[1]s=:%[1]s", column)
+	}
+	if _, ok := changes["email"]; ok {
+		assignments = append(assignments, "email_verified_at="+emailVerifiedAtAssignment)
 	}
 
-	return filterQuery.String()
+	return fmt.Sprintf(patchUserQuery, strings.Join(assignments, ", ")), nil
 }
 
-func (r *repository) createPaginationQuery(page int, perPage int) string {
-	offset := perPage * (page - 1)
-	limit := perPage
+func (r *repository) createPaginationQuery(limit int, offset int) string {
 	return fmt.Sprintf(" LIMIT %d OFFSET %d;", limit, offset)
 }
@@ -2,9 +2,14 @@ package user
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	_ "github.com/stretchr/testify/assert"
@@ -131,11 +136,33 @@ var e = Entity{
 	Nickname:  "bobby.firmino",
 	Password:  "liverpool321",
 	Email:     "robertofirmino@lfc.co.uk",
-	Country:   "UK",
+	Country:   "GB",
+	Version:   1,
 	CreatedAt: time.Now(),
 	UpdatedAt: time.Now(),
 }
 
+// entityColumns are the columns returned by the queries returning the user.
+var entityColumns = []string{"id", "first_name", "last_name", "nickname", "password", "email", "country", "version", "created_at", "updated_at",
+	"deleted_at", "email_verified_at"}
+
+func entityRow(entity Entity) []driver.Value {
+	return []driver.Value{entity.Id, entity.FirstName, entity.LastName, entity.Nickname, entity.Password, entity.Email, entity.Country,
+		entity.Version, entity.CreatedAt, entity.UpdatedAt, entity.DeletedAt, entity.EmailVerifiedAt}
+}
+
+// changeColumns are the columns returned by the queries returning the user together with its previous state.
+var changeColumns = []string{"id", "first_name", "last_name", "nickname", "password", "email", "country", "version", "created_at", "updated_at", "deleted_at",
+	"email_verified_at", "previous.id", "previous.first_name", "previous.last_name", "previous.nickname", "previous.password", "previous.email",
+	"previous.country", "previous.version", "previous.created_at", "previous.updated_at", "previous.deleted_at", "previous.email_verified_at"}
+
+func changeRow(current Entity, previous Entity) []driver.Value {
+	return []driver.Value{current.Id, current.FirstName, current.LastName, current.Nickname, current.Password, current.Email, current.Country,
+		current.Version, current.CreatedAt, current.UpdatedAt, current.DeletedAt, current.EmailVerifiedAt,
+		previous.Id, previous.FirstName, previous.LastName, previous.Nickname, previous.Password, previous.Email, previous.Country,
+		previous.Version, previous.CreatedAt, previous.UpdatedAt, previous.DeletedAt, previous.EmailVerifiedAt}
+}
+
 func NewMock() (*sqlx.DB, sqlmock.Sqlmock) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -146,6 +173,93 @@ func NewMock() (*sqlx.DB, sqlmock.Sqlmock) {
 	return sqlxDb, mock
 }
 
+func TestRepository_Transaction(t *testing.T) {
+	t.Run("commit", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		mock.ExpectBegin()
+		prep := mock.ExpectPrepare("UPDATE users SET deleted_at=current_timestamp")
+		prep.ExpectQuery().
+			WithArgs(entities[0].Id, 0, 0).
+			WillReturnRows(sqlmock.NewRows(changeColumns).AddRow(changeRow(entities[0], entities[0])...))
+		prep.ExpectQuery().
+			WithArgs(entities[1].Id, 0, 0).
+			WillReturnRows(sqlmock.NewRows(changeColumns).AddRow(changeRow(entities[1], entities[1])...))
+		mock.ExpectCommit()
+
+		err := repo.Transaction(context.Background(), func(repo Repository) error {
+			_, err := repo.DeleteById(context.Background(), entities[0].Id, 0)
+			if err != nil {
+				return err
+			}
+			_, err = repo.DeleteById(context.Background(), entities[1].Id, 0)
+			return err
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet(), "should prepare the statement once in the transaction")
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		mock.ExpectBegin()
+		prep := mock.ExpectPrepare("UPDATE users SET deleted_at=current_timestamp")
+		prep.ExpectQuery().
+			WithArgs(entities[0].Id, 0, 0).
+			WillReturnRows(sqlmock.NewRows(changeColumns))
+		mock.ExpectRollback()
+
+		err := repo.Transaction(context.Background(), func(repo Repository) error {
+			_, err := repo.DeleteById(context.Background(), entities[0].Id, 0)
+			return err
+		})
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("savepoint", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		prep := mock.ExpectPrepare("UPDATE users SET deleted_at=current_timestamp")
+		prep.ExpectQuery().
+			WithArgs(entities[0].Id, 0, 0).
+			WillReturnRows(sqlmock.NewRows(changeColumns))
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT savepoint_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SAVEPOINT savepoint_2").WillReturnResult(sqlmock.NewResult(0, 0))
+		prep.ExpectQuery().
+			WithArgs(entities[1].Id, 0, 0).
+			WillReturnRows(sqlmock.NewRows(changeColumns).AddRow(changeRow(entities[1], entities[1])...))
+		mock.ExpectExec("RELEASE SAVEPOINT savepoint_2").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := repo.Transaction(context.Background(), func(repo Repository) error {
+			err := repo.Transaction(context.Background(), func(repo Repository) error {
+				_, err := repo.DeleteById(context.Background(), entities[0].Id, 0)
+				return err
+			})
+			assert.ErrorIs(t, err, ErrNotFound, "should roll back to the savepoint")
+
+			return repo.Transaction(context.Background(), func(repo Repository) error {
+				_, err := repo.DeleteById(context.Background(), entities[1].Id, 0)
+				return err
+			})
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 func TestRepository_Create(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		db, mock := NewMock()
@@ -154,7 +268,7 @@ func TestRepository_Create(t *testing.T) {
 		)
 
 		rows := sqlmock.
-			NewRows([]string{"id", "created_at", "updated_at"}).AddRow(e.Id, e.CreatedAt, e.UpdatedAt)
+			NewRows([]string{"id", "version", "created_at", "updated_at"}).AddRow(e.Id, e.Version, e.CreatedAt, e.UpdatedAt)
 
 		insertQuery := "INSERT INTO users"
 		prep := mock.ExpectPrepare(insertQuery)
@@ -181,6 +295,65 @@ func TestRepository_Create(t *testing.T) {
 		_, err := repo.Create(context.Background(), e)
 		assert.Error(t, err, "should return error when there is a missing argument")
 	})
+
+	t.Run("should return conflict when the nickname is taken", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		insertQuery := "INSERT INTO users"
+		prep := mock.ExpectPrepare(insertQuery)
+		prep.ExpectQuery().
+			WithArgs(e.FirstName, e.LastName, e.Nickname, e.Password, e.Email, e.Country).
+			WillReturnError(&pq.Error{Code: pqUniqueViolation, Constraint: "users_nickname_key"})
+
+		_, err := repo.Create(context.Background(), e)
+
+		var conflict *ConflictError
+		assert.True(t, errors.As(err, &conflict))
+		assert.Equal(t, "nickname", conflict.Field)
+	})
+}
+
+func TestRepository_CreateMany(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		mock.ExpectBegin()
+		prep := mock.ExpectPrepare("COPY \"users\"")
+		for _, entity := range entities[:2] {
+			prep.ExpectExec().
+				WithArgs(entity.Id, entity.FirstName, entity.LastName, entity.Nickname, entity.Password, entity.Email, entity.Country,
+					entity.Version, entity.CreatedAt, entity.UpdatedAt).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		prep.ExpectExec().WithArgs().WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		err := repo.CreateMany(context.Background(), entities[:2])
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet(), "should copy the users in a transaction")
+	})
+
+	t.Run("copy error", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		mock.ExpectBegin()
+		prep := mock.ExpectPrepare("COPY \"users\"")
+		prep.ExpectExec().WillReturnError(fmt.Errorf("mock error"))
+		mock.ExpectRollback()
+
+		err := repo.CreateMany(context.Background(), entities[:1])
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 func TestRepository_Update(t *testing.T) {
@@ -190,18 +363,93 @@ func TestRepository_Update(t *testing.T) {
 			WithDb(db),
 		)
 
-		rows := sqlmock.
-			NewRows([]string{"created_at", "updated_at"}).AddRow(e.CreatedAt, e.UpdatedAt)
+		previous := e
+		previous.Country = "NL"
+		updated := e
+		updated.Version = e.Version + 1
 
-		query := "UPDATE users"
+		rows := sqlmock.NewRows(changeColumns).AddRow(changeRow(updated, previous)...)
+
+		query := "UPDATE users SET (.+) FROM \\(SELECT (.+) FOR UPDATE\\) previous"
 		prep := mock.ExpectPrepare(query)
 		prep.ExpectQuery().
-			WithArgs(e.FirstName, e.LastName, e.Nickname, e.Password, e.Email, e.Country, e.Id).
+			WithArgs(e.FirstName, e.LastName, e.Nickname, e.Password, e.Email, e.Country, e.Email, e.Id, e.Version, e.Version).
 			WillReturnRows(rows)
 
 		actual, err := repo.Update(context.Background(), e)
 		assert.NoError(t, err)
-		assert.EqualValues(t, e, actual, "should return updated_at field matching with the database on update query")
+		assert.EqualValues(t, updated, actual.Entity, "should return the user matching with the database on update query")
+		assert.EqualValues(t, previous, actual.Previous, "should return the state of the user before the update")
+	})
+
+	t.Run("version mismatch", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		rows := sqlmock.NewRows(changeColumns)
+
+		query := "UPDATE users"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().
+			WithArgs(e.FirstName, e.LastName, e.Nickname, e.Password, e.Email, e.Country, e.Email, e.Id, e.Version, e.Version).
+			WillReturnRows(rows)
+
+		_, err := repo.Update(context.Background(), e)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestRepository_Patch(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		previous := e
+		previous.Nickname = "firmino"
+		patched := e
+		patched.Version = e.Version + 1
+
+		rows := sqlmock.NewRows(changeColumns).AddRow(changeRow(patched, previous)...)
+
+		query := "UPDATE users SET country=\\?, nickname=\\?, version=users.version \\+ 1 FROM (.+) WHERE users.id=previous.id AND users.deleted_at IS NULL AND \\(\\? = 0 OR users.version=\\?\\)"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().
+			WithArgs(e.Country, e.Nickname, e.Id, e.Version, e.Version).
+			WillReturnRows(rows)
+
+		actual, err := repo.Patch(context.Background(), e.Id, e.Version, map[string]interface{}{
+			"nickname": e.Nickname,
+			"country":  e.Country,
+		})
+		assert.NoError(t, err)
+		assert.EqualValues(t, patched, actual.Entity)
+		assert.EqualValues(t, previous, actual.Previous)
+	})
+
+	t.Run("should return error when a column cannot be updated", func(t *testing.T) {
+		db, _ := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		_, err := repo.Patch(context.Background(), e.Id, 0, map[string]interface{}{
+			"created_at": e.CreatedAt,
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("should return error when there is nothing to update", func(t *testing.T) {
+		db, _ := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		_, err := repo.Patch(context.Background(), e.Id, 0, map[string]interface{}{})
+		assert.Error(t, err)
 	})
 }
 
@@ -212,14 +460,120 @@ func TestRepository_DeleteById(t *testing.T) {
 			WithDb(db),
 		)
 
-		query := "DELETE FROM users"
+		deletedAt := time.Now()
+		deleted := e
+		deleted.DeletedAt = &deletedAt
+		deleted.Version = e.Version + 1
+
+		rows := sqlmock.NewRows(changeColumns).AddRow(changeRow(deleted, e)...)
+
+		query := "UPDATE users SET deleted_at=current_timestamp"
 		prep := mock.ExpectPrepare(query)
-		prep.ExpectExec().
-			WithArgs(e.Id).
-			WillReturnResult(sqlmock.NewResult(0, 1))
+		prep.ExpectQuery().
+			WithArgs(e.Id, e.Version, e.Version).
+			WillReturnRows(rows)
+
+		actual, err := repo.DeleteById(context.Background(), e.Id, e.Version)
+		assert.NoError(t, err)
+		assert.EqualValues(t, deleted, actual.Entity)
+		assert.EqualValues(t, e, actual.Previous)
+	})
+
+	t.Run("no rows", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		query := "UPDATE users SET deleted_at=current_timestamp"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().
+			WithArgs(e.Id, e.Version, e.Version).
+			WillReturnRows(sqlmock.NewRows(changeColumns))
+
+		_, err := repo.DeleteById(context.Background(), e.Id, e.Version)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestRepository_Restore(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		deletedAt := time.Now()
+		previous := e
+		previous.DeletedAt = &deletedAt
+
+		rows := sqlmock.NewRows(changeColumns).AddRow(changeRow(e, previous)...)
+
+		query := "UPDATE users SET deleted_at=NULL"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs(e.Id).WillReturnRows(rows)
+
+		actual, err := repo.Restore(context.Background(), e.Id)
+		assert.NoError(t, err)
+		assert.EqualValues(t, e, actual.Entity)
+		assert.EqualValues(t, previous, actual.Previous)
+	})
+
+	t.Run("no rows", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		rows := sqlmock.NewRows(changeColumns)
+
+		query := "UPDATE users SET deleted_at=NULL"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs(e.Id).WillReturnRows(rows)
+
+		_, err := repo.Restore(context.Background(), e.Id)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestRepository_PurgeDeleted(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		before := time.Now()
+
+		query := "DELETE FROM users WHERE deleted_at IS NOT NULL(.+)DELETE FROM password_reset_tokens(.+)DELETE FROM user_audit"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().
+			WithArgs(before).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+		purged, err := repo.PurgeDeleted(context.Background(), before)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), purged)
+	})
+
+	t.Run("should keep the erased users", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		before := time.Now()
+
+		query := "DELETE FROM users WHERE deleted_at IS NOT NULL AND deleted_at < \\? AND erased_at IS NULL RETURNING id"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().
+			WithArgs(before).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 
-		err := repo.DeleteById(context.Background(), e.Id)
+		purged, err := repo.PurgeDeleted(context.Background(), before)
 		assert.NoError(t, err)
+		assert.Equal(t, int64(0), purged)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
@@ -230,20 +584,20 @@ func TestRepository_GetMany(t *testing.T) {
 			WithDb(db),
 		)
 
-		rows := sqlmock.NewRows([]string{"id", "first_name", "last_name", "nickname", "password", "email", "country", "created_at", "updated_at"})
+		rows := sqlmock.NewRows([]string{"id", "first_name", "last_name", "nickname", "password", "email", "country", "version", "created_at", "updated_at"})
 		for _, e := range entities {
-			rows.AddRow(e.Id, e.FirstName, e.LastName, e.Nickname, e.Password, e.Email, e.Country, e.CreatedAt, e.UpdatedAt)
+			rows.AddRow(e.Id, e.FirstName, e.LastName, e.Nickname, e.Password, e.Email, e.Country, e.Version, e.CreatedAt, e.UpdatedAt)
 		}
 
 		params := GetManyParameters{
-			Page:    1,
-			PerPage: 3,
-			Filter: Entity{
-				Country: "UK",
+			Limit:  3,
+			Offset: 0,
+			Filter: []FilterCondition{
+				{Column: "country", Operator: FilterEq, Values: []interface{}{"UK"}},
 			},
 		}
 
-		query := "SELECT (.+) FROM users WHERE"
+		query := "SELECT (.+) FROM users WHERE (.+) ORDER BY created_at ASC, id ASC LIMIT 3 OFFSET 0"
 		prep := mock.ExpectPrepare(query)
 		prep.ExpectQuery().WillReturnRows(rows)
 
@@ -252,19 +606,39 @@ func TestRepository_GetMany(t *testing.T) {
 		assert.EqualValues(t, entities, actual)
 	})
 
+	t.Run("should select only the requested columns", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		params := GetManyParameters{
+			Limit:  3,
+			Fields: []string{"nickname", "id"},
+			Sort:   []SortField{{Column: "country"}},
+		}
+
+		query := "SELECT id, nickname, country FROM users WHERE 1 = 1 AND deleted_at IS NULL ORDER BY country ASC, id ASC LIMIT 3 OFFSET 0"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"id", "nickname", "country"}).AddRow(e.Id, e.Nickname, e.Country))
+
+		actual, err := repo.GetMany(context.Background(), params)
+		assert.NoError(t, err)
+		assert.EqualValues(t, []Entity{{Id: e.Id, Nickname: e.Nickname, Country: e.Country}}, actual)
+	})
+
 	t.Run("argument mismatch", func(t *testing.T) {
 		db, mock := NewMock()
 		repo := NewRepository(
 			WithDb(db),
 		)
 
-		rows := sqlmock.NewRows([]string{"id", "first_name", "last_name", "nickname", "password", "email", "country", "created_at", "updated_at"}).
-			AddRow(e.Id, e.FirstName, e.LastName, e.Nickname, e.Password, e.Email, e.Country, e.CreatedAt, e.UpdatedAt)
+		rows := sqlmock.NewRows([]string{"id", "first_name", "last_name", "nickname", "password", "email", "country", "version", "created_at", "updated_at"}).
+			AddRow(e.Id, e.FirstName, e.LastName, e.Nickname, e.Password, e.Email, e.Country, e.Version, e.CreatedAt, e.UpdatedAt)
 
 		params := GetManyParameters{
-			Page:    1,
-			PerPage: 3,
-			Filter:  Entity{},
+			Limit:  3,
+			Offset: 0,
 		}
 
 		query := "SELECT (.+) FROM users WHERE"
@@ -274,4 +648,732 @@ func TestRepository_GetMany(t *testing.T) {
 		_, err := repo.GetMany(context.Background(), params)
 		assert.Error(t, err)
 	})
+
+	t.Run("success with filter operators", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		createdAfter := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+		params := GetManyParameters{
+			Limit: 3,
+			Filter: []FilterCondition{
+				{Column: "country", Operator: FilterIn, Values: []interface{}{"UK", "DE"}},
+				{Column: "created_at", Operator: FilterGte, Values: []interface{}{createdAfter}},
+				{Column: "nickname", Operator: FilterPrefix, Values: []interface{}{"ali_"}},
+				{Column: "email", Operator: FilterIlike, Values: []interface{}{"Mail"}},
+				{Column: "first_name", Operator: FilterNeq, Values: []interface{}{"Bob"}},
+			},
+		}
+
+		query := "SELECT (.+) FROM users WHERE 1 = 1 AND deleted_at IS NULL AND country IN \\(\\?, \\?\\) " +
+			"AND created_at >= \\? AND nickname LIKE \\? AND email ILIKE \\? AND first_name <> \\? " +
+			"ORDER BY created_at ASC, id ASC LIMIT 3 OFFSET 0"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs("UK", "DE", createdAfter, `ali\_%`, "%Mail%", "Bob").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := repo.GetMany(context.Background(), params)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should reject the columns that are not whitelisted", func(t *testing.T) {
+		db, _ := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		params := GetManyParameters{
+			Limit: 3,
+			Filter: []FilterCondition{
+				{Column: "password", Operator: FilterEq, Values: []interface{}{"secret"}},
+			},
+		}
+
+		_, err := repo.GetMany(context.Background(), params)
+		assert.Error(t, err)
+	})
+
+	t.Run("success with cursor", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		rows := sqlmock.NewRows([]string{"id", "first_name", "last_name", "nickname", "password", "email", "country", "version", "created_at", "updated_at"})
+		for _, e := range entities[1:3] {
+			rows.AddRow(e.Id, e.FirstName, e.LastName, e.Nickname, e.Password, e.Email, e.Country, e.Version, e.CreatedAt, e.UpdatedAt)
+		}
+
+		cursor := after(entities[0], normalizeSort(nil))
+		params := GetManyParameters{
+			Limit:  3,
+			Cursor: &cursor,
+		}
+
+		query := "SELECT (.+) FROM users WHERE (.+) AND \\(created_at, id\\) > \\(\\?, \\?\\) ORDER BY created_at ASC, id ASC LIMIT 3 OFFSET 0"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs(cursor.Values[0], cursor.Values[1]).WillReturnRows(rows)
+
+		actual, err := repo.GetMany(context.Background(), params)
+		assert.NoError(t, err)
+		assert.EqualValues(t, entities[1:3], actual)
+	})
+
+	t.Run("success with mixed sort directions", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		sort := []SortField{{Column: "updated_at", Descending: true}, {Column: "nickname"}}
+		cursor := after(entities[0], normalizeSort(sort))
+		params := GetManyParameters{
+			Limit:  3,
+			Sort:   sort,
+			Cursor: &cursor,
+		}
+
+		query := "SELECT (.+) FROM users WHERE 1 = 1 AND deleted_at IS NULL " +
+			"AND \\(\\(updated_at < \\?\\) OR \\(updated_at = \\? AND nickname > \\?\\) " +
+			"OR \\(updated_at = \\? AND nickname = \\? AND id > \\?\\)\\) " +
+			"ORDER BY updated_at DESC, nickname ASC, id ASC LIMIT 3 OFFSET 0"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().
+			WithArgs(entities[0].UpdatedAt, entities[0].UpdatedAt, entities[0].Nickname,
+				entities[0].UpdatedAt, entities[0].Nickname, entities[0].Id).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := repo.GetMany(context.Background(), params)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should reverse the sort directions with backward cursor", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		sort := []SortField{{Column: "nickname", Descending: true}}
+		cursor := before(entities[0], normalizeSort(sort))
+		params := GetManyParameters{
+			Limit:  3,
+			Sort:   sort,
+			Cursor: &cursor,
+		}
+
+		query := "SELECT (.+) FROM users WHERE (.+) AND \\(\\(nickname > \\?\\) OR \\(nickname = \\? AND id < \\?\\)\\) " +
+			"ORDER BY nickname ASC, id DESC LIMIT 3 OFFSET 0"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := repo.GetMany(context.Background(), params)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return the users in ascending order with backward cursor", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		rows := sqlmock.NewRows([]string{"id", "first_name", "last_name", "nickname", "password", "email", "country", "version", "created_at", "updated_at"})
+		for _, e := range []Entity{entities[2], entities[1], entities[0]} {
+			rows.AddRow(e.Id, e.FirstName, e.LastName, e.Nickname, e.Password, e.Email, e.Country, e.Version, e.CreatedAt, e.UpdatedAt)
+		}
+
+		cursor := before(entities[3], normalizeSort(nil))
+		params := GetManyParameters{
+			Limit:  3,
+			Cursor: &cursor,
+		}
+
+		query := "SELECT (.+) FROM users WHERE (.+) AND \\(created_at, id\\) < \\(\\?, \\?\\) ORDER BY created_at DESC, id DESC LIMIT 3 OFFSET 0"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs(cursor.Values[0], cursor.Values[1]).WillReturnRows(rows)
+
+		actual, err := repo.GetMany(context.Background(), params)
+		assert.NoError(t, err)
+		assert.EqualValues(t, entities[0:3], actual)
+	})
+}
+
+func TestRepository_Count(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		params := GetManyParameters{
+			Limit:  3,
+			Offset: 6,
+			Filter: []FilterCondition{
+				{Column: "country", Operator: FilterEq, Values: []interface{}{"UK"}},
+			},
+		}
+
+		query := "SELECT COUNT\\(\\*\\) FROM users WHERE 1 = 1 AND deleted_at IS NULL AND country = \\?;"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs("UK").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(25))
+
+		actual, err := repo.Count(context.Background(), params)
+		assert.NoError(t, err)
+		assert.Equal(t, 25, actual)
+	})
+
+	t.Run("should count the deleted users when they are included", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		query := "SELECT COUNT\\(\\*\\) FROM users WHERE 1 = 1;"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(30))
+
+		actual, err := repo.Count(context.Background(), GetManyParameters{IncludeDeleted: true})
+		assert.NoError(t, err)
+		assert.Equal(t, 30, actual)
+	})
+}
+
+func TestRepository_Export(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		params := GetManyParameters{
+			Limit: 3,
+			Filter: []FilterCondition{
+				{Column: "country", Operator: FilterEq, Values: []interface{}{"UK"}},
+			},
+		}
+
+		rows := sqlmock.NewRows([]string{"id", "first_name", "last_name", "nickname", "email", "country", "version", "created_at", "updated_at"})
+		for _, entity := range entities[:2] {
+			rows.AddRow(entity.Id, entity.FirstName, entity.LastName, entity.Nickname, entity.Email, entity.Country,
+				entity.Version, entity.CreatedAt, entity.UpdatedAt)
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DECLARE users_export NO SCROLL CURSOR FOR SELECT .+ FROM users WHERE 1 = 1 AND deleted_at IS NULL AND country = \\? ORDER BY [^;]+$").
+			WithArgs("UK").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("FETCH 1000 FROM users_export").WillReturnRows(rows)
+		mock.ExpectRollback()
+
+		var actual []string
+		err := repo.Export(context.Background(), params, func(entity Entity) error {
+			actual = append(actual, entity.Id)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{entities[0].Id, entities[1].Id}, actual)
+		assert.NoError(t, mock.ExpectationsWereMet(), "should not limit the users")
+	})
+
+	t.Run("should stop when fn fails", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		rows := sqlmock.NewRows([]string{"id"}).AddRow(entities[0].Id).AddRow(entities[1].Id)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DECLARE users_export").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("FETCH 1000 FROM users_export").WillReturnRows(rows)
+		mock.ExpectRollback()
+
+		calls := 0
+		err := repo.Export(context.Background(), GetManyParameters{}, func(entity Entity) error {
+			calls++
+			return fmt.Errorf("mock error")
+		})
+		assert.Error(t, err)
+		assert.Equal(t, 1, calls)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRepository_Search(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		rows := sqlmock.NewRows([]string{"id", "first_name", "last_name", "nickname", "password", "email", "country", "version", "created_at", "updated_at", "rank"}).
+			AddRow(e.Id, e.FirstName, e.LastName, e.Nickname, e.Password, e.Email, e.Country, e.Version, e.CreatedAt, e.UpdatedAt, 0.7)
+
+		params := SearchParameters{
+			Query:  "firm bob",
+			Terms:  []string{"firm", "bob"},
+			Limit:  10,
+			Offset: 20,
+		}
+
+		query := "SELECT (.+) AS rank FROM users WHERE deleted_at IS NULL AND (.+) ORDER BY rank DESC, created_at ASC, id ASC LIMIT \\? OFFSET \\?"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().
+			WithArgs("firm:* & bob:*", params.Query, "firm:* & bob:*", params.Query, params.Limit, params.Offset).
+			WillReturnRows(rows)
+
+		actual, err := repo.Search(context.Background(), params)
+		assert.NoError(t, err)
+		assert.EqualValues(t, []EntityMatch{{Entity: e, Rank: 0.7}}, actual)
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		prep := mock.ExpectPrepare("SELECT (.+) FROM users")
+		prep.ExpectQuery().WillReturnError(sql.ErrConnDone)
+
+		_, err := repo.Search(context.Background(), SearchParameters{Query: "firm", Terms: []string{"firm"}, Limit: 10})
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+	})
+}
+
+func TestRepository_GetById(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		rows := sqlmock.NewRows([]string{"id", "first_name", "last_name", "nickname", "password", "email", "country", "version", "created_at", "updated_at"}).
+			AddRow(e.Id, e.FirstName, e.LastName, e.Nickname, e.Password, e.Email, e.Country, e.Version, e.CreatedAt, e.UpdatedAt)
+
+		query := "SELECT (.+) FROM users WHERE id"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs(e.Id).WillReturnRows(rows)
+
+		actual, err := repo.GetById(context.Background(), e.Id)
+		assert.NoError(t, err)
+		assert.EqualValues(t, e, actual)
+	})
+
+	t.Run("no rows", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		rows := sqlmock.NewRows([]string{"id", "first_name", "last_name", "nickname", "password", "email", "country", "version", "created_at", "updated_at"})
+
+		query := "SELECT (.+) FROM users WHERE id"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs(e.Id).WillReturnRows(rows)
+
+		_, err := repo.GetById(context.Background(), e.Id)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestRepository_GetByLogin(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		rows := sqlmock.NewRows([]string{"id", "first_name", "last_name", "nickname", "password", "email", "country", "version", "created_at", "updated_at"}).
+			AddRow(e.Id, e.FirstName, e.LastName, e.Nickname, e.Password, e.Email, e.Country, e.Version, e.CreatedAt, e.UpdatedAt)
+
+		query := "SELECT (.+) FROM users WHERE \\(lower\\(nickname\\)=lower\\(\\?\\) OR lower\\(email\\)=lower\\(\\?\\)\\)"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs(e.Nickname, e.Nickname).WillReturnRows(rows)
+
+		actual, err := repo.GetByLogin(context.Background(), e.Nickname)
+		assert.NoError(t, err)
+		assert.EqualValues(t, e, actual)
+	})
+
+	t.Run("no rows", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		rows := sqlmock.NewRows([]string{"id", "first_name", "last_name", "nickname", "password", "email", "country", "version", "created_at", "updated_at"})
+
+		query := "SELECT (.+) FROM users WHERE \\(lower\\(nickname\\)=lower\\(\\?\\) OR lower\\(email\\)=lower\\(\\?\\)\\)"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs(e.Email, e.Email).WillReturnRows(rows)
+
+		_, err := repo.GetByLogin(context.Background(), e.Email)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestRepository_UpdatePassword(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		query := "UPDATE users SET password"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectExec().
+			WithArgs(e.Password, e.Id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.UpdatePassword(context.Background(), e.Id, e.Password)
+		assert.NoError(t, err)
+	})
+}
+
+func TestRepository_TokenRevoked(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		issuedAt := time.Date(2022, 9, 5, 18, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+		query := "SELECT NOT EXISTS \\(SELECT 1 FROM users WHERE id=\\? AND deleted_at IS NULL"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().
+			WithArgs(e.Id, issuedAt.UTC()).
+			WillReturnRows(sqlmock.NewRows([]string{"revoked"}).AddRow(true))
+
+		revoked, err := repo.TokenRevoked(context.Background(), e.Id, issuedAt)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+	})
+}
+
+func TestRepository_VerifyEmail(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		verifiedAt := time.Now()
+		verified := e
+		verified.EmailVerifiedAt = &verifiedAt
+
+		rows := sqlmock.NewRows(changeColumns).AddRow(changeRow(verified, e)...)
+
+		query := "UPDATE users SET email_verified_at=current_timestamp"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs(e.Id, e.Email).WillReturnRows(rows)
+
+		actual, err := repo.VerifyEmail(context.Background(), e.Id, e.Email)
+		assert.NoError(t, err)
+		assert.EqualValues(t, verified, actual.Entity)
+		assert.EqualValues(t, e, actual.Previous)
+	})
+
+	t.Run("no rows", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		rows := sqlmock.NewRows(changeColumns)
+
+		query := "UPDATE users SET email_verified_at=current_timestamp"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs(e.Id, e.Email).WillReturnRows(rows)
+
+		_, err := repo.VerifyEmail(context.Background(), e.Id, e.Email)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestRepository_CreatePasswordResetToken(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		rows := sqlmock.NewRows(entityColumns).AddRow(entityRow(e)...)
+
+		query := "WHERE lower\\(email\\)=lower\\(\\?\\) AND deleted_at IS NULL(.+)INSERT INTO password_reset_tokens"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().
+			WithArgs(e.Email, "hash", float64(3600)).
+			WillReturnRows(rows)
+
+		actual, err := repo.CreatePasswordResetToken(context.Background(), e.Email, "hash", time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, e, actual)
+	})
+
+	t.Run("should return not found when no user has the email", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		query := "INSERT INTO password_reset_tokens"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().
+			WithArgs(e.Email, "hash", float64(3600)).
+			WillReturnRows(sqlmock.NewRows(entityColumns))
+
+		_, err := repo.CreatePasswordResetToken(context.Background(), e.Email, "hash", time.Hour)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestRepository_ResetPassword(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		rows := sqlmock.NewRows(entityColumns).AddRow(entityRow(e)...)
+
+		query := "WITH token AS \\( UPDATE password_reset_tokens (.+) UPDATE users SET password"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs("hash", "hash", e.Password).WillReturnRows(rows)
+
+		actual, err := repo.ResetPassword(context.Background(), "hash", e.Password)
+		assert.NoError(t, err)
+		assert.EqualValues(t, e, actual)
+	})
+
+	t.Run("no rows", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		rows := sqlmock.NewRows(entityColumns)
+
+		query := "UPDATE users SET password"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs("hash", "hash", e.Password).WillReturnRows(rows)
+
+		_, err := repo.ResetPassword(context.Background(), "hash", e.Password)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestRepository_AppendAudit(t *testing.T) {
+	entity := newAuditEntity(context.Background(), AuditActionCreated, e, createdChanges(e))
+
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		mock.ExpectBegin()
+		prep := mock.ExpectPrepare("COPY \"user_audit\"")
+		prep.ExpectExec().
+			WithArgs(e.Id, AuditActionCreated, "", "", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		prep.ExpectExec().WithArgs().WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.AppendAudit(context.Background(), entity)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet(), "should copy the entries in a transaction")
+	})
+
+	t.Run("copy error", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		mock.ExpectBegin()
+		prep := mock.ExpectPrepare("COPY \"user_audit\"")
+		prep.ExpectExec().WillReturnError(fmt.Errorf("mock error"))
+		mock.ExpectRollback()
+
+		err := repo.AppendAudit(context.Background(), entity)
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// auditColumns are the columns returned by the queries of the change history.
+var auditColumns = []string{"id", "user_id", "action", "actor", "request_id", "changes", "snapshot", "created_at"}
+
+func TestRepository_GetHistory(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		createdAt := time.Now()
+		rows := sqlmock.NewRows(auditColumns).
+			AddRow(2, e.Id, AuditActionUpdated, "admin", "request", []byte(`{"country":{"old":"NL","new":"GB"}}`),
+				[]byte(`{"id":"`+e.Id+`","version":2}`), createdAt).
+			AddRow(1, e.Id, AuditActionCreated, "", "", []byte(`{"country":{"new":"NL"}}`),
+				[]byte(`{"id":"`+e.Id+`","version":1}`), createdAt)
+
+		query := "SELECT (.+) FROM user_audit WHERE user_id=\\? ORDER BY id DESC"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs(e.Id, 10, 20).WillReturnRows(rows)
+
+		actual, err := repo.GetHistory(context.Background(), e.Id, 10, 20)
+		assert.NoError(t, err)
+		assert.Len(t, actual, 2)
+		assert.Equal(t, int64(2), actual[0].Id)
+		assert.Equal(t, "admin", actual[0].Actor)
+		assert.Equal(t, AuditChanges{"country": {Old: "NL", New: "GB"}}, actual[0].Changes)
+		assert.Equal(t, 2, actual[0].Snapshot.Version)
+		assert.Equal(t, AuditActionCreated, actual[1].Action)
+	})
+}
+
+func TestRepository_GetSnapshot(t *testing.T) {
+	at := time.Now()
+
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		rows := sqlmock.NewRows(auditColumns).
+			AddRow(1, e.Id, AuditActionCreated, "", "", []byte(`{}`), []byte(`{"id":"`+e.Id+`","nickname":"`+e.Nickname+`"}`), at)
+
+		query := "SELECT (.+) FROM user_audit WHERE user_id=\\? AND created_at <= \\?"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs(e.Id, at).WillReturnRows(rows)
+
+		actual, err := repo.GetSnapshot(context.Background(), e.Id, at)
+		assert.NoError(t, err)
+		assert.Equal(t, e.Nickname, actual.Snapshot.Nickname)
+		assert.Equal(t, at, actual.CreatedAt)
+	})
+
+	t.Run("no rows", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		query := "SELECT (.+) FROM user_audit"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs(e.Id, at).WillReturnRows(sqlmock.NewRows(auditColumns))
+
+		_, err := repo.GetSnapshot(context.Background(), e.Id, at)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestRepository_Erase(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		erased := e
+		erased.FirstName = ""
+		erased.Nickname = "_erased_1"
+		erased.Version = e.Version + 1
+
+		rows := sqlmock.NewRows(changeColumns).AddRow(changeRow(erased, e)...)
+
+		query := "WITH tokens AS \\( DELETE FROM password_reset_tokens (.+) DELETE FROM user_audit (.+) DELETE FROM idempotency_keys (.+) UPDATE users SET first_name=''"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs(e.Id, e.Id, e.Id, e.Id).WillReturnRows(rows)
+
+		actual, err := repo.Erase(context.Background(), e.Id)
+		assert.NoError(t, err)
+		assert.EqualValues(t, erased, actual.Entity)
+		assert.EqualValues(t, e, actual.Previous)
+	})
+
+	t.Run("no rows", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		query := "UPDATE users SET first_name=''"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs(e.Id, e.Id, e.Id, e.Id).WillReturnRows(sqlmock.NewRows(changeColumns))
+
+		_, err := repo.Erase(context.Background(), e.Id)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestRepository_GetPersonalData(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		erasedAt := time.Now()
+		userRows := sqlmock.NewRows(append(entityColumns, "erased_at")).AddRow(append(entityRow(e), &erasedAt)...)
+		historyRows := sqlmock.NewRows(auditColumns).
+			AddRow(1, e.Id, AuditActionCreated, "", "", []byte(`{}`), []byte(`{"id":"`+e.Id+`"}`), e.CreatedAt)
+		tokenRows := sqlmock.NewRows([]string{"id", "expires_at", "used_at", "created_at"}).
+			AddRow("token", e.CreatedAt, nil, e.CreatedAt)
+
+		mock.ExpectPrepare("SELECT (.+) erased_at FROM users WHERE id=\\?").
+			ExpectQuery().WithArgs(e.Id).WillReturnRows(userRows)
+		mock.ExpectPrepare("SELECT (.+) FROM user_audit WHERE user_id=\\? ORDER BY id;").
+			ExpectQuery().WithArgs(e.Id).WillReturnRows(historyRows)
+		mock.ExpectPrepare("SELECT (.+) FROM password_reset_tokens WHERE user_id=\\?").
+			ExpectQuery().WithArgs(e.Id).WillReturnRows(tokenRows)
+
+		actual, err := repo.GetPersonalData(context.Background(), e.Id)
+		assert.NoError(t, err)
+		assert.EqualValues(t, e, actual.Entity)
+		assert.Equal(t, &erasedAt, actual.ErasedAt)
+		assert.Len(t, actual.History, 1)
+		assert.Equal(t, []PasswordResetTokenEntity{{Id: "token", ExpiresAt: e.CreatedAt, CreatedAt: e.CreatedAt}}, actual.PasswordResetTokens)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no rows", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		mock.ExpectPrepare("SELECT (.+) erased_at FROM users").
+			ExpectQuery().WithArgs(e.Id).WillReturnRows(sqlmock.NewRows(append(entityColumns, "erased_at")))
+
+		_, err := repo.GetPersonalData(context.Background(), e.Id)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestRepository_TakenNicknames(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := NewMock()
+		repo := NewRepository(
+			WithDb(db),
+		)
+
+		rows := sqlmock.NewRows([]string{"lower"}).AddRow(e.Nickname)
+
+		query := "SELECT lower\\(nickname\\) FROM users WHERE lower\\(nickname\\) = ANY"
+		prep := mock.ExpectPrepare(query)
+		prep.ExpectQuery().WithArgs("{\"bobby.firmino\",\"bobby.firmino1\"}").WillReturnRows(rows)
+
+		actual, err := repo.TakenNicknames(context.Background(), []string{e.Nickname, e.Nickname + "1"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{e.Nickname}, actual)
+	})
 }
@@ -1,32 +1,243 @@
 package user
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
 // CreateUserRequest create user endpoint request model contains the user details
 // @Description create user endpoint request model
 type CreateUserRequest struct {
-	FirstName string `json:"first_name" binding:"required"`
-	LastName  string `json:"last_name" binding:"required"`
-	Nickname  string `json:"nickname" binding:"required"`
-	Password  string `json:"password" binding:"required"`
-	Email     string `json:"email" binding:"required"`
-	Country   string `json:"country" binding:"required"`
+	FirstName string `json:"first_name" binding:"required,max=50"`
+	LastName  string `json:"last_name" binding:"required,max=50"`
+	Nickname  string `json:"nickname" binding:"required,min=3,max=30,nickname"`
+	Password  string `json:"password" binding:"required,password"`
+	Email     string `json:"email" binding:"required,max=100,email"`
+	Country   string `json:"country" binding:"required,iso3166_1_alpha2"`
 }
 
 type UpdateUserRequest struct {
 	Id        string `json:"-"`
-	FirstName string `json:"first_name" binding:"required"`
-	LastName  string `json:"last_name" binding:"required"`
-	Nickname  string `json:"nickname" binding:"required"`
-	Password  string `json:"password" binding:"required"`
-	Email     string `json:"email" binding:"required"`
-	Country   string `json:"country" binding:"required"`
+	Version   int    `json:"-"`
+	FirstName string `json:"first_name" binding:"required,max=50"`
+	LastName  string `json:"last_name" binding:"required,max=50"`
+	Nickname  string `json:"nickname" binding:"required,min=3,max=30,nickname"`
+	Password  string `json:"password" binding:"required,password"`
+	Email     string `json:"email" binding:"required,max=100,email"`
+	Country   string `json:"country" binding:"required,iso3166_1_alpha2"`
+}
+
+// PatchUserRequest patch user endpoint request model, it is a JSON merge patch (RFC 7396)
+// only the fields provided in the request are changed.
+// @Description patch user endpoint request model, only the provided fields are changed
+type PatchUserRequest struct {
+	Id        string  `json:"-"`
+	Version   int     `json:"-"`
+	FirstName *string `json:"first_name,omitempty" binding:"omitempty,min=1,max=50"`
+	LastName  *string `json:"last_name,omitempty" binding:"omitempty,min=1,max=50"`
+	Nickname  *string `json:"nickname,omitempty" binding:"omitempty,min=3,max=30,nickname"`
+	Password  *string `json:"password,omitempty" binding:"omitempty,min=1,password"`
+	Email     *string `json:"email,omitempty" binding:"omitempty,max=100,email"`
+	Country   *string `json:"country,omitempty" binding:"omitempty,iso3166_1_alpha2"`
+}
+
+// UnmarshalJSON decodes the merge patch document. A null member means removing the field in a merge patch,
+// since none of the user fields can be removed, null members are rejected.
+func (r *PatchUserRequest) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	err := json.Unmarshal(data, &members)
+	if err != nil {
+		return err
+	}
+
+	for name, value := range members {
+		if bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+			return fmt.Errorf("field %s cannot be removed", name)
+		}
+	}
+
+	type patchUserRequest PatchUserRequest
+	return json.Unmarshal(data, (*patchUserRequest)(r))
+}
+
+const (
+	BatchModeAtomic  = "atomic"
+	BatchModePartial = "partial"
+)
+
+const (
+	BatchOpCreate = "create"
+	BatchOpUpdate = "update"
+	BatchOpDelete = "delete"
+)
+
+// BatchUsersRequest batch users endpoint request model, the operations are executed in a transaction.
+// In the atomic mode none of the operations are applied if any of them fails, in the partial mode
+// only the failed ones are not applied.
+// @Description batch users endpoint request model
+type BatchUsersRequest struct {
+	Mode       string           `json:"mode" binding:"omitempty,oneof=atomic partial" enums:"atomic,partial" default:"atomic"`
+	Operations []BatchOperation `json:"operations" binding:"required,min=1,max=500,dive"`
+}
+
+// BatchOperation is an operation of a batch, the user is required by create and update,
+// the id by update and delete. The operation fails when the version is given and the user has been modified.
+// @Description operation of a batch
+type BatchOperation struct {
+	Op      string             `json:"op" binding:"required,oneof=create update delete" enums:"create,update,delete"`
+	Id      string             `json:"id,omitempty" binding:"required_unless=Op create,omitempty,uuid"`
+	Version int                `json:"version,omitempty" binding:"min=0"`
+	User    *CreateUserRequest `json:"user,omitempty" binding:"required_unless=Op delete"`
+}
+
+// ImportUsersRequest is an upload of users in the csv or ndjson format.
+type ImportUsersRequest struct {
+	Format string
+	File   io.Reader
+}
+
+type GetImportRequest struct {
+	Id string `uri:"id" binding:"required,uuid"`
+}
+
+type SendVerificationRequest struct {
+	Id string `uri:"id" binding:"required,uuid"`
+}
+
+// VerifyEmailRequest verify email endpoint request model containing the token of the verification mail
+// @Description verify email endpoint request model
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// PasswordResetRequest password reset endpoint request model containing the email of the user
+// @Description password reset endpoint request model
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required,max=100,email"`
+}
+
+// ConfirmPasswordResetRequest confirm password reset endpoint request model containing the token of the password reset mail
+// and the new password
+// @Description confirm password reset endpoint request model
+type ConfirmPasswordResetRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,password"`
+}
+
+type GetUserHistoryRequest struct {
+	Id      string `uri:"id" binding:"required,uuid"`
+	Page    int    `uri:"-"`
+	PerPage int    `uri:"-"`
+}
+
+type GetUserSnapshotRequest struct {
+	Id string    `uri:"id" binding:"required,uuid"`
+	At time.Time `uri:"-"`
 }
 
 type DeleteUserByIdRequest struct {
+	Id      string `uri:"id" binding:"required,uuid"`
+	Version int    `uri:"-"`
+}
+
+type RestoreUserRequest struct {
+	Id string `uri:"id" binding:"required,uuid"`
+}
+
+type GetPersonalDataRequest struct {
+	Id string `uri:"id" binding:"required,uuid"`
+}
+
+type EraseUserRequest struct {
+	Id string `uri:"id" binding:"required,uuid"`
+}
+
+type GetUserByIdRequest struct {
 	Id string `uri:"id" binding:"required,uuid"`
 }
 
+// NicknameAvailabilityRequest is the nickname whose availability is checked.
+type NicknameAvailabilityRequest struct {
+	Nickname string `form:"nickname" binding:"required,min=3,max=30,nickname"`
+}
+
+type SearchUsersRequest struct {
+	Query   string `form:"q" binding:"required"`
+	Page    int    `form:"-"`
+	PerPage int    `form:"-"`
+}
+
 type GetUsersManyRequest struct {
-	Page    int  `uri:"page"`
-	PerPage int  `uri:"perPage"`
-	Filter  User `uri:"filter"`
+	Page           int               `uri:"page"`
+	PerPage        int               `uri:"perPage"`
+	Cursor         string            `uri:"cursor"`
+	Sort           []SortField       `uri:"sort"`
+	Fields         []string          `uri:"fields"`
+	Filter         []FilterCondition `uri:"filter"`
+	IncludeDeleted bool              `uri:"includeDeleted"`
+	SkipTotal      bool              `uri:"skipTotal"`
+}
+
+// ExportUsersRequest are the users exported in the format, the users are filtered and sorted the same as the listing.
+type ExportUsersRequest struct {
+	Format         string
+	Sort           []SortField
+	Fields         []string
+	Filter         []FilterCondition
+	IncludeDeleted bool
+}
+
+// redacted returns a copy of the request without the password so that it can be logged.
+func (r CreateUserRequest) redacted() CreateUserRequest {
+	r.Password = ""
+	return r
+}
+
+// redacted returns a copy of the request without the password so that it can be logged.
+func (r UpdateUserRequest) redacted() UpdateUserRequest {
+	r.Password = ""
+	return r
+}
+
+// redacted returns a copy of the request without the password so that it can be logged.
+func (r PatchUserRequest) redacted() PatchUserRequest {
+	r.Password = nil
+	return r
+}
+
+// redacted returns a copy of the request without the file containing the passwords so that it can be logged.
+func (r ImportUsersRequest) redacted() ImportUsersRequest {
+	r.File = nil
+	return r
+}
+
+// redacted returns a copy of the request without the passwords so that it can be logged.
+func (r BatchUsersRequest) redacted() BatchUsersRequest {
+	operations := make([]BatchOperation, len(r.Operations))
+	for i, op := range r.Operations {
+		if op.User != nil {
+			user := op.User.redacted()
+			op.User = &user
+		}
+		operations[i] = op
+	}
+
+	r.Operations = operations
+	return r
+}
+
+// redacted returns a copy of the request without the token so that it can be logged.
+func (r VerifyEmailRequest) redacted() VerifyEmailRequest {
+	r.Token = ""
+	return r
+}
+
+// redacted returns a copy of the request without the token and the password so that it can be logged.
+func (r ConfirmPasswordResetRequest) redacted() ConfirmPasswordResetRequest {
+	r.Token = ""
+	r.Password = ""
+	return r
 }
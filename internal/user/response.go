@@ -1,19 +1,26 @@
 package user
 
-import "time"
+import (
+	"faceit-backend-test/internal/apierr"
+	"faceit-backend-test/internal/notify"
+	"time"
+)
 
 // User represents user api model
 // @Description user model
 type User struct {
-	Id        string    `json:"id"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Nickname  string    `json:"nickname"`
-	Password  string    `json:"password"`
-	Email     string    `json:"email"`
-	Country   string    `json:"country"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	Id        string     `json:"id"`
+	FirstName string     `json:"first_name"`
+	LastName  string     `json:"last_name"`
+	Nickname  string     `json:"nickname"`
+	Email     string     `json:"email"`
+	Country   string     `json:"country"`
+	Version   int        `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// EmailVerifiedAt is when the email was verified, it is not returned until the email is verified
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
 }
 
 // CreateUserResponse create user endpoint response model containing the user information
@@ -28,14 +35,189 @@ type UpdateUserResponse struct {
 	User
 }
 
+// GetUserResponse get user endpoint response model containing the user information
+// @Description get user endpoint response model containing the user information
+type GetUserResponse struct {
+	User
+}
+
+// VerificationResponse send verification endpoint response model, the link in the mail expires at expires_at
+// @Description send verification endpoint response model
+type VerificationResponse struct {
+	Id        string    `json:"id"`
+	Email     string    `json:"email"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// VerifyEmailResponse verify email endpoint response model containing the verified user
+// @Description verify email endpoint response model containing the verified user
+type VerifyEmailResponse struct {
+	User
+}
+
+// AuditEntry is a recorded change of a user, the actor is the subject of the access token the change is made with
+// and the version is the version of the user after the change
+// @Description recorded change of a user
+type AuditEntry struct {
+	Id        int64        `json:"id"`
+	UserId    string       `json:"user_id"`
+	Action    string       `json:"action" enums:"created,updated,deleted,restored,email_verified,password_reset,erased"`
+	Actor     string       `json:"actor,omitempty"`
+	RequestId string       `json:"request_id,omitempty"`
+	Changes   AuditChanges `json:"changes"`
+	Version   int          `json:"version"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// UserHistoryResponse user history endpoint response model containing the changes of the user, the latest first
+// @Description user history endpoint response model
+type UserHistoryResponse struct {
+	Entries []AuditEntry `json:"entries"`
+	Page    int          `json:"page"`
+	PerPage int          `json:"perPage"`
+}
+
+// UserSnapshotResponse user snapshot endpoint response model containing the user as of the requested time,
+// changed_at is when the user is changed to this state
+// @Description user snapshot endpoint response model
+type UserSnapshotResponse struct {
+	User
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// PersonalDataResponse is everything held about a user, it is downloaded as a zip archive of JSON files
+// @Description everything held about a user
+type PersonalDataResponse struct {
+	User                User                 `json:"user"`
+	ErasedAt            *time.Time           `json:"erased_at,omitempty"`
+	History             []AuditEntry         `json:"history"`
+	PasswordResetTokens []PasswordResetToken `json:"password_reset_tokens"`
+	Subscriptions       []Subscription       `json:"subscriptions"`
+	Deliveries          []notify.Delivery    `json:"deliveries"`
+	ExportedAt          time.Time            `json:"exported_at"`
+}
+
+// PasswordResetToken is a password reset token issued for a user, the token itself is never stored
+// @Description password reset token issued for a user
+type PasswordResetToken struct {
+	Id        string     `json:"id"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Subscription is a notification subscription the data of a user is sent to
+// @Description notification subscription the data of a user is sent to
+type Subscription struct {
+	Id          string `json:"id"`
+	Topic       string `json:"topic"`
+	CallbackUrl string `json:"callback_url"`
+}
+
+// EraseUserResponse erase user endpoint response model containing the id of the erased user
+// @Description erase user endpoint response model containing the id of the erased user
+type EraseUserResponse struct {
+	Id       string    `json:"id"`
+	ErasedAt time.Time `json:"erased_at"`
+}
+
 // DeleteUserResponse delete user response model containing the id of deleted user
 // @Description delete user response model containing the id of deleted user
 type DeleteUserResponse struct {
 	Id string `json:"id"`
 }
 
+// RestoreUserResponse restore user endpoint response model containing the restored user information
+// @Description restore user endpoint response model containing the restored user information
+type RestoreUserResponse struct {
+	User
+}
+
+// NicknameAvailabilityResponse nickname availability endpoint response model, the suggestions are available nicknames
+// similar to the requested one, they are returned only when it is taken.
+// @Description nickname availability endpoint response model
+type NicknameAvailabilityResponse struct {
+	Nickname    string   `json:"nickname"`
+	Available   bool     `json:"available"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
 // GetUsersManyResponse get users response model that contains the users returned
 // @Description get users response model that contains the users returned
 type GetUsersManyResponse struct {
 	Users []User `json:"users"`
+	// Next is the cursor of the next page, it is empty on the last page
+	Next string `json:"next,omitempty"`
+	// Prev is the cursor of the previous page, it is empty on the first page
+	Prev string `json:"prev,omitempty"`
+	// Page is the number of the page, it is not returned when the page is requested by a cursor
+	Page    int `json:"page,omitempty"`
+	PerPage int `json:"perPage"`
+	// Total is the number of the users matching the filter, it is not returned when counting is skipped
+	Total      *int `json:"total,omitempty"`
+	TotalPages *int `json:"totalPages,omitempty"`
+}
+
+// UserMatch is a user found by a search, the highlights contain the matching parts of the fields
+// wrapped with <em> tags, the rest of the values are html escaped.
+// @Description user found by a search together with its relevance
+type UserMatch struct {
+	User
+	Rank       float64           `json:"rank"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// SearchUsersResponse search users endpoint response model containing the users ordered by relevance
+// @Description search users endpoint response model containing the users ordered by relevance
+type SearchUsersResponse struct {
+	Users   []UserMatch `json:"users"`
+	Page    int         `json:"page"`
+	PerPage int         `json:"perPage"`
+}
+
+// BatchOperationResult is the result of an operation of a batch, the user is returned by create and update.
+// @Description result of an operation of a batch
+type BatchOperationResult struct {
+	Status int              `json:"status"`
+	Id     string           `json:"id,omitempty"`
+	User   *User            `json:"user,omitempty"`
+	Error  *apierr.ApiError `json:"error,omitempty"`
+}
+
+// BatchUsersResponse batch users endpoint response model containing the results in the order of the operations
+// @Description batch users endpoint response model containing the results in the order of the operations
+type BatchUsersResponse struct {
+	Committed bool                   `json:"committed"`
+	Results   []BatchOperationResult `json:"results"`
+}
+
+// ImportJob is an import of users running in the background.
+// @Description import of users running in the background
+type ImportJob struct {
+	Id         string     `json:"id"`
+	Status     string     `json:"status" enums:"pending,running,completed,failed"`
+	Format     string     `json:"format" enums:"csv,ndjson"`
+	Processed  int        `json:"processed"`
+	Imported   int        `json:"imported"`
+	Failed     int        `json:"failed"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// ImportJobResponse import endpoints response model containing the progress of the import
+// @Description import endpoints response model containing the progress of the import
+type ImportJobResponse struct {
+	ImportJob
+}
+
+// ImportRowError is a row of an upload that has not been imported.
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// ImportErrorsResponse contains the rows of an upload that have not been imported.
+type ImportErrorsResponse struct {
+	Errors []ImportRowError `json:"errors"`
 }
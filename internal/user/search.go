@@ -0,0 +1,82 @@
+package user
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	highlightStart = "<em>"
+	highlightEnd   = "</em>"
+)
+
+// searchTerms splits the search query into the lower case words, the characters other than
+// letters and digits are separators so that the terms can be written into a tsquery safely.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// prefixQuery creates the tsquery matching the documents that contain words starting with all the terms.
+func prefixQuery(terms []string) string {
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+
+	return strings.Join(prefixes, " & ")
+}
+
+// highlight html escapes the value and wraps the parts of it matching any of the terms with the highlight tags,
+// it reports whether there is a match.
+func highlight(value string, terms []string) (string, bool) {
+	if len(terms) == 0 {
+		return html.EscapeString(value), false
+	}
+
+	patterns := make([]string, len(terms))
+	for i, term := range terms {
+		patterns[i] = regexp.QuoteMeta(term)
+	}
+	matcher := regexp.MustCompile("(?i)" + strings.Join(patterns, "|"))
+
+	matches := matcher.FindAllStringIndex(value, -1)
+	if len(matches) == 0 {
+		return html.EscapeString(value), false
+	}
+
+	highlighted := strings.Builder{}
+	last := 0
+	for _, match := range matches {
+		highlighted.WriteString(html.EscapeString(value[last:match[0]]))
+		highlighted.WriteString(highlightStart)
+		highlighted.WriteString(html.EscapeString(value[match[0]:match[1]]))
+		highlighted.WriteString(highlightEnd)
+		last = match[1]
+	}
+	highlighted.WriteString(html.EscapeString(value[last:]))
+
+	return highlighted.String(), true
+}
+
+// highlights returns the highlighted values of the searched fields matching any of the terms by their json names.
+func highlights(user User, terms []string) map[string]string {
+	fields := map[string]string{
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+		"nickname":   user.Nickname,
+		"email":      user.Email,
+	}
+
+	result := map[string]string{}
+	for name, value := range fields {
+		if highlighted, ok := highlight(value, terms); ok {
+			result[name] = highlighted
+		}
+	}
+
+	return result
+}
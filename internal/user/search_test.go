@@ -0,0 +1,29 @@
+package user
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSearchTerms(t *testing.T) {
+	assert.Equal(t, []string{"bobby", "firmino", "lfc"}, searchTerms(" Bobby.Firmino & lfc:*"))
+	assert.Empty(t, searchTerms("!@ &"))
+}
+
+func TestPrefixQuery(t *testing.T) {
+	assert.Equal(t, "bobby:* & firmino:*", prefixQuery([]string{"bobby", "firmino"}))
+}
+
+func TestHighlight(t *testing.T) {
+	t.Run("should wrap the matches", func(t *testing.T) {
+		actual, ok := highlight("Firmino <b>", []string{"firm", "b"})
+		assert.True(t, ok)
+		assert.Equal(t, "<em>Firm</em>ino &lt;<em>b</em>&gt;", actual)
+	})
+
+	t.Run("should report when there is no match", func(t *testing.T) {
+		actual, ok := highlight("Firmino", []string{"salah"})
+		assert.False(t, ok)
+		assert.Equal(t, "Firmino", actual)
+	})
+}
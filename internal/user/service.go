@@ -2,35 +2,115 @@ package user
 
 import (
 	"context"
+	"errors"
+	"faceit-backend-test/internal/auth"
+	"faceit-backend-test/internal/mail"
 	"faceit-backend-test/internal/pubsub"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"sync"
+	"time"
 )
 
-const UserChangeTopic = "user.update"
+const (
+	UserCreatedTopic = "user.created"
+	UserChangeTopic  = "user.update"
+	UserDeletedTopic = "user.deleted"
+	// UserEmailVerifiedTopic is published with the user whose email is verified.
+	UserEmailVerifiedTopic = "user.email_verified"
+	// UserErasedTopic is published with only the id of the user whose personal data is erased.
+	UserErasedTopic = "user.erased"
+)
 
+// GetManyParameters are the parameters of a user listing, the users are skipped
+// until the offset unless a cursor is given.
 type GetManyParameters struct {
-	Page    int
-	PerPage int
-	Filter  Entity
+	Limit          int
+	Offset         int
+	Sort           []SortField
+	Fields         []string
+	Cursor         *Cursor
+	Filter         []FilterCondition
+	IncludeDeleted bool
+}
+
+// SearchParameters are the parameters of a user search, the terms are the words of the query.
+type SearchParameters struct {
+	Query  string
+	Terms  []string
+	Limit  int
+	Offset int
 }
 
 type Repository interface {
+	Transaction(ctx context.Context, fn func(repo Repository) error) error
 	Create(ctx context.Context, entity Entity) (Entity, error)
-	Update(ctx context.Context, entity Entity) (Entity, error)
-	DeleteById(ctx context.Context, id string) error
+	CreateMany(ctx context.Context, entities []Entity) error
+	Update(ctx context.Context, entity Entity) (EntityChange, error)
+	Patch(ctx context.Context, id string, version int, changes map[string]interface{}) (EntityChange, error)
+	DeleteById(ctx context.Context, id string, version int) (EntityChange, error)
+	Restore(ctx context.Context, id string) (EntityChange, error)
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
 	GetMany(ctx context.Context, parameters GetManyParameters) ([]Entity, error)
+	Count(ctx context.Context, parameters GetManyParameters) (int, error)
+	Export(ctx context.Context, parameters GetManyParameters, fn func(entity Entity) error) error
+	Search(ctx context.Context, parameters SearchParameters) ([]EntityMatch, error)
+	GetById(ctx context.Context, id string) (Entity, error)
+	GetByLogin(ctx context.Context, login string) (Entity, error)
+	TakenNicknames(ctx context.Context, nicknames []string) ([]string, error)
+	VerifyEmail(ctx context.Context, id string, email string) (EntityChange, error)
+	UpdatePassword(ctx context.Context, id string, password string) error
+	TokenRevoked(ctx context.Context, id string, issuedAt time.Time) (bool, error)
+	CreatePasswordResetToken(ctx context.Context, email string, tokenHash string, ttl time.Duration) (Entity, error)
+	ResetPassword(ctx context.Context, tokenHash string, password string) (Entity, error)
+	AppendAudit(ctx context.Context, entities ...AuditEntity) error
+	GetHistory(ctx context.Context, userId string, limit int, offset int) ([]AuditEntity, error)
+	GetSnapshot(ctx context.Context, userId string, at time.Time) (AuditEntity, error)
+	Erase(ctx context.Context, id string) (EntityChange, error)
+	GetPersonalData(ctx context.Context, id string) (PersonalDataEntity, error)
 }
 
 type service struct {
-	repo   Repository
-	broker pubsub.Broker
+	repo            Repository
+	broker          pubsub.Broker
+	hasher          PasswordHasher
+	imports         *importJobs
+	importBatchSize int
+	// mailer sends the verification mails, they are not sent without it.
+	mailer               mail.Sender
+	verificationSecret   []byte
+	verificationTTL      time.Duration
+	verificationURL      string
+	requireVerifiedEmail bool
+	passwordResetTTL     time.Duration
+	passwordResetURL     string
+	// deliveries are the notifications sent about the users, they are not exported nor erased without it.
+	deliveries DeliveryLog
+	// dummyHash is compared with the passwords of the unknown logins.
+	dummyHash *dummyHash
+}
+
+// dummyHash is created on the first use, since hashing is slow on purpose.
+type dummyHash struct {
+	once sync.Once
+	hash string
 }
 
 var _ Service = (*service)(nil)
+var _ auth.Authenticator = (*service)(nil)
+var _ auth.Revocation = (*service)(nil)
 
 type ServiceOpts func(*service)
 
 func NewService(opts ...ServiceOpts) *service {
-	s := &service{}
+	s := &service{
+		hasher:           NewBcryptHasher(bcrypt.DefaultCost),
+		imports:          newImportJobs(),
+		importBatchSize:  defaultImportBatchSize,
+		verificationTTL:  defaultVerificationTTL,
+		passwordResetTTL: defaultPasswordResetTTL,
+		dummyHash:        &dummyHash{},
+	}
 
 	for _, opt := range opts {
 		opt(s)
@@ -51,91 +131,303 @@ func WithBroker(broker pubsub.Broker) ServiceOpts {
 	}
 }
 
+func WithPasswordHasher(hasher PasswordHasher) ServiceOpts {
+	return func(s *service) {
+		s.hasher = hasher
+	}
+}
+
+// WithImportBatchSize sets how many users are inserted at once by the imports.
+func WithImportBatchSize(size int) ServiceOpts {
+	return func(s *service) {
+		s.importBatchSize = size
+	}
+}
+
+func WithMailSender(mailer mail.Sender) ServiceOpts {
+	return func(s *service) {
+		s.mailer = mailer
+	}
+}
+
+// WithVerification sets the secret the email verification tokens are signed with, how long they are valid
+// and the url of the page verifying the emails, the tokens are added to it as the token query parameter.
+func WithVerification(secret []byte, ttl time.Duration, url string) ServiceOpts {
+	return func(s *service) {
+		s.verificationSecret = secret
+		s.verificationTTL = ttl
+		s.verificationURL = url
+	}
+}
+
+// WithRequireVerifiedEmail sets whether the users can log in before their emails are verified.
+func WithRequireVerifiedEmail(require bool) ServiceOpts {
+	return func(s *service) {
+		s.requireVerifiedEmail = require
+	}
+}
+
+// WithPasswordReset sets how long the password reset tokens are valid and the url of the page resetting the passwords,
+// the tokens are added to it as the token query parameter. The tokens are mailed by the mail sender.
+func WithPasswordReset(ttl time.Duration, url string) ServiceOpts {
+	return func(s *service) {
+		s.passwordResetTTL = ttl
+		s.passwordResetURL = url
+	}
+}
+
+// WithDeliveryLog sets the log of the notifications sent about the users.
+func WithDeliveryLog(deliveries DeliveryLog) ServiceOpts {
+	return func(s *service) {
+		s.deliveries = deliveries
+	}
+}
+
 func (s *service) Create(ctx context.Context, request CreateUserRequest) (CreateUserResponse, error) {
-	entity, err := s.repo.Create(ctx, Entity{
+	passwordHash, err := s.hasher.Hash(request.Password)
+	if err != nil {
+		return CreateUserResponse{}, passwordHashError()
+	}
+
+	return s.create(ctx, Entity{
 		FirstName: request.FirstName,
 		LastName:  request.LastName,
 		Nickname:  request.Nickname,
-		Password:  request.Password,
+		Password:  passwordHash,
 		Email:     request.Email,
 		Country:   request.Country,
 	})
+}
+
+// create stores the user whose password is already hashed.
+func (s *service) create(ctx context.Context, entity Entity) (CreateUserResponse, error) {
+	change, err := s.audited(ctx, AuditActionCreated, func(repo Repository) (EntityChange, error) {
+		created, err := repo.Create(ctx, entity)
+		return EntityChange{Entity: created}, err
+	})
 	if err != nil {
 		return CreateUserResponse{}, repositoryError(err)
 	}
+	entity = change.Entity
+	createdUser := User{
+		Id:              entity.Id,
+		FirstName:       entity.FirstName,
+		LastName:        entity.LastName,
+		Nickname:        entity.Nickname,
+		Email:           entity.Email,
+		Country:         entity.Country,
+		Version:         entity.Version,
+		CreatedAt:       entity.CreatedAt,
+		UpdatedAt:       entity.UpdatedAt,
+		EmailVerifiedAt: entity.EmailVerifiedAt,
+	}
+	s.broker.Publish(UserCreatedTopic, createdUser)
+	// the user is created even if the mail cannot be sent, it can ask for another one.
+	_, _ = s.sendVerification(ctx, createdUser)
 
 	return CreateUserResponse{
-		User{
-			Id:        entity.Id,
-			FirstName: entity.FirstName,
-			LastName:  entity.LastName,
-			Nickname:  entity.Nickname,
-			Password:  entity.Password,
-			Email:     entity.Email,
-			Country:   entity.Country,
-			CreatedAt: entity.CreatedAt,
-			UpdatedAt: entity.UpdatedAt,
-		},
+		User: createdUser,
 	}, nil
 }
 
 func (s *service) Update(ctx context.Context, request UpdateUserRequest) (UpdateUserResponse, error) {
-	entity, err := s.repo.Update(ctx, Entity{
+	passwordHash, err := s.hasher.Hash(request.Password)
+	if err != nil {
+		return UpdateUserResponse{}, passwordHashError()
+	}
+
+	return s.update(ctx, Entity{
 		Id:        request.Id,
 		FirstName: request.FirstName,
 		LastName:  request.LastName,
 		Nickname:  request.Nickname,
-		Password:  request.Password,
+		Password:  passwordHash,
 		Email:     request.Email,
 		Country:   request.Country,
+		Version:   request.Version,
 	})
+}
+
+// update replaces the user with the entity whose password is already hashed.
+func (s *service) update(ctx context.Context, entity Entity) (UpdateUserResponse, error) {
+	change, err := s.audited(ctx, AuditActionUpdated, func(repo Repository) (EntityChange, error) {
+		return repo.Update(ctx, entity)
+	})
+	if errors.Is(err, ErrNotFound) {
+		return UpdateUserResponse{}, s.conditionalWriteError(ctx, entity.Id)
+	}
 	if err != nil {
 		return UpdateUserResponse{}, repositoryError(err)
 	}
-	updatedUser := User{
-		Id:        entity.Id,
-		FirstName: entity.FirstName,
-		LastName:  entity.LastName,
-		Nickname:  entity.Nickname,
-		Password:  entity.Password,
-		Email:     entity.Email,
-		Country:   entity.Country,
-		CreatedAt: entity.CreatedAt,
-		UpdatedAt: entity.UpdatedAt,
+	event := newUserChangeEvent(change)
+	s.broker.Publish(UserChangeTopic, event)
+	if change.Email != change.Previous.Email {
+		// the changed email is not verified, the user can ask for another mail if this one cannot be sent.
+		_, _ = s.sendVerification(ctx, event.User)
 	}
-	s.broker.Publish(UserChangeTopic, updatedUser)
 
 	return UpdateUserResponse{
-		User: updatedUser,
+		User: User{
+			Id:              change.Id,
+			FirstName:       change.FirstName,
+			LastName:        change.LastName,
+			Nickname:        change.Nickname,
+			Email:           change.Email,
+			Country:         change.Country,
+			Version:         change.Version,
+			CreatedAt:       change.CreatedAt,
+			UpdatedAt:       change.UpdatedAt,
+			EmailVerifiedAt: change.EmailVerifiedAt,
+		},
+	}, nil
+}
+
+func (s *service) Patch(ctx context.Context, request PatchUserRequest) (UpdateUserResponse, error) {
+	changes := map[string]interface{}{}
+	if request.FirstName != nil {
+		changes["first_name"] = *request.FirstName
+	}
+	if request.LastName != nil {
+		changes["last_name"] = *request.LastName
+	}
+	if request.Nickname != nil {
+		changes["nickname"] = *request.Nickname
+	}
+	if request.Email != nil {
+		changes["email"] = *request.Email
+	}
+	if request.Country != nil {
+		changes["country"] = *request.Country
+	}
+	if request.Password != nil {
+		passwordHash, err := s.hasher.Hash(*request.Password)
+		if err != nil {
+			return UpdateUserResponse{}, passwordHashError()
+		}
+
+		changes["password"] = passwordHash
+	}
+
+	// an empty merge patch does not change anything, the current state of the user is returned.
+	if len(changes) == 0 {
+		resp, err := s.GetById(ctx, GetUserByIdRequest{Id: request.Id})
+		if err != nil {
+			return UpdateUserResponse{}, err
+		}
+
+		if request.Version != 0 && request.Version != resp.Version {
+			return UpdateUserResponse{}, versionMismatchError(request.Id)
+		}
+
+		return UpdateUserResponse{User: resp.User}, nil
+	}
+
+	change, err := s.audited(ctx, AuditActionUpdated, func(repo Repository) (EntityChange, error) {
+		return repo.Patch(ctx, request.Id, request.Version, changes)
+	})
+	if errors.Is(err, ErrNotFound) {
+		return UpdateUserResponse{}, s.conditionalWriteError(ctx, request.Id)
+	}
+	if err != nil {
+		return UpdateUserResponse{}, repositoryError(err)
+	}
+	event := newUserChangeEvent(change)
+	s.broker.Publish(UserChangeTopic, event)
+	if change.Email != change.Previous.Email {
+		// the changed email is not verified, the user can ask for another mail if this one cannot be sent.
+		_, _ = s.sendVerification(ctx, event.User)
+	}
+
+	return UpdateUserResponse{
+		User: User{
+			Id:              change.Id,
+			FirstName:       change.FirstName,
+			LastName:        change.LastName,
+			Nickname:        change.Nickname,
+			Email:           change.Email,
+			Country:         change.Country,
+			Version:         change.Version,
+			CreatedAt:       change.CreatedAt,
+			UpdatedAt:       change.UpdatedAt,
+			EmailVerifiedAt: change.EmailVerifiedAt,
+		},
 	}, nil
 }
 
 func (s *service) DeleteById(ctx context.Context, request DeleteUserByIdRequest) (DeleteUserResponse, error) {
-	err := s.repo.DeleteById(ctx, request.Id)
+	resp := DeleteUserResponse{
+		Id: request.Id,
+	}
+
+	_, err := s.audited(ctx, AuditActionDeleted, func(repo Repository) (EntityChange, error) {
+		return repo.DeleteById(ctx, request.Id, request.Version)
+	})
+	if errors.Is(err, ErrNotFound) {
+		return DeleteUserResponse{}, s.conditionalWriteError(ctx, request.Id)
+	}
 	if err != nil {
 		return DeleteUserResponse{}, repositoryError(err)
 	}
+	s.broker.Publish(UserDeletedTopic, resp)
 
-	return DeleteUserResponse{
-		Id: request.Id,
+	return resp, nil
+}
+
+// Restore brings back a soft deleted user, restoring a user that is not deleted doesn't change it.
+func (s *service) Restore(ctx context.Context, request RestoreUserRequest) (RestoreUserResponse, error) {
+	change, err := s.audited(ctx, AuditActionRestored, func(repo Repository) (EntityChange, error) {
+		return repo.Restore(ctx, request.Id)
+	})
+	if errors.Is(err, ErrNotFound) {
+		resp, err := s.GetById(ctx, GetUserByIdRequest{Id: request.Id})
+		if err != nil {
+			return RestoreUserResponse{}, err
+		}
+
+		return RestoreUserResponse{User: resp.User}, nil
+	}
+	if err != nil {
+		return RestoreUserResponse{}, repositoryError(err)
+	}
+	event := newUserChangeEvent(change)
+	s.broker.Publish(UserChangeTopic, event)
+
+	return RestoreUserResponse{
+		User: User{
+			Id:              change.Id,
+			FirstName:       change.FirstName,
+			LastName:        change.LastName,
+			Nickname:        change.Nickname,
+			Email:           change.Email,
+			Country:         change.Country,
+			Version:         change.Version,
+			CreatedAt:       change.CreatedAt,
+			UpdatedAt:       change.UpdatedAt,
+			EmailVerifiedAt: change.EmailVerifiedAt,
+		},
 	}, nil
 }
 
+// GetMany returns a page of the users either by the page number or by the cursor,
+// one more user than the page size is fetched to know whether there is a next page.
 func (s *service) GetMany(ctx context.Context, request GetUsersManyRequest) (GetUsersManyResponse, error) {
 	params := GetManyParameters{
-		Page:    request.Page,
-		PerPage: request.PerPage,
-		Filter: Entity{
-			Id:        request.Filter.Id,
-			FirstName: request.Filter.FirstName,
-			LastName:  request.Filter.LastName,
-			Nickname:  request.Filter.Nickname,
-			Password:  request.Filter.Password,
-			Email:     request.Filter.Email,
-			Country:   request.Filter.Country,
-			CreatedAt: request.Filter.CreatedAt,
-			UpdatedAt: request.Filter.UpdatedAt,
-		},
+		Limit:          request.PerPage + 1,
+		Offset:         request.PerPage * (request.Page - 1),
+		Sort:           normalizeSort(request.Sort),
+		Fields:         request.Fields,
+		IncludeDeleted: request.IncludeDeleted,
+		Filter:         request.Filter,
+	}
+
+	if request.Cursor != "" {
+		cursor, err := decodeCursor(request.Cursor, params.Sort)
+		if err != nil {
+			return GetUsersManyResponse{}, err
+		}
+
+		params.Cursor = &cursor
+		params.Offset = 0
 	}
 
 	entities, err := s.repo.GetMany(ctx, params)
@@ -143,20 +435,203 @@ func (s *service) GetMany(ctx context.Context, request GetUsersManyRequest) (Get
 		return GetUsersManyResponse{}, repositoryError(err)
 	}
 
+	backward := params.Cursor != nil && params.Cursor.Backward
+	hasMore := len(entities) > request.PerPage
+	if hasMore && backward {
+		entities = entities[1:]
+	} else if hasMore {
+		entities = entities[:request.PerPage]
+	}
+
 	users := make([]User, len(entities))
 	for i, entity := range entities {
 		users[i] = User{
-			Id:        entity.Id,
-			FirstName: entity.FirstName,
-			LastName:  entity.LastName,
-			Nickname:  entity.Nickname,
-			Password:  entity.Password,
-			Email:     entity.Email,
-			Country:   entity.Country,
-			CreatedAt: entity.CreatedAt,
-			UpdatedAt: entity.UpdatedAt,
+			Id:              entity.Id,
+			FirstName:       entity.FirstName,
+			LastName:        entity.LastName,
+			Nickname:        entity.Nickname,
+			Email:           entity.Email,
+			Country:         entity.Country,
+			Version:         entity.Version,
+			CreatedAt:       entity.CreatedAt,
+			UpdatedAt:       entity.UpdatedAt,
+			EmailVerifiedAt: entity.EmailVerifiedAt,
+			DeletedAt:       entity.DeletedAt,
+		}
+	}
+
+	resp := GetUsersManyResponse{Users: users, PerPage: request.PerPage}
+	if params.Cursor == nil {
+		resp.Page = request.Page
+	}
+
+	if !request.SkipTotal {
+		total, err := s.repo.Count(ctx, params)
+		if err != nil {
+			return GetUsersManyResponse{}, repositoryError(err)
+		}
+
+		totalPages := (total + request.PerPage - 1) / request.PerPage
+		resp.Total = &total
+		resp.TotalPages = &totalPages
+	}
+
+	if len(entities) == 0 {
+		return resp, nil
+	}
+
+	// there are users after the page when it is reached backward, and before the page when it is not the first one.
+	hasNext := hasMore || backward
+	hasPrev := (hasMore && backward) || (!backward && (params.Cursor != nil || params.Offset > 0))
+	if hasNext {
+		resp.Next = encodeCursor(after(entities[len(entities)-1], params.Sort))
+	}
+	if hasPrev {
+		resp.Prev = encodeCursor(before(entities[0], params.Sort))
+	}
+
+	return resp, nil
+}
+
+// Search returns a page of the users matching the query ordered by their relevance
+// together with the highlighted parts of the fields matching the query.
+func (s *service) Search(ctx context.Context, request SearchUsersRequest) (SearchUsersResponse, error) {
+	terms := searchTerms(request.Query)
+	if len(terms) == 0 {
+		return SearchUsersResponse{}, invalidSearchQueryError()
+	}
+
+	matches, err := s.repo.Search(ctx, SearchParameters{
+		Query:  request.Query,
+		Terms:  terms,
+		Limit:  request.PerPage,
+		Offset: request.PerPage * (request.Page - 1),
+	})
+	if err != nil {
+		return SearchUsersResponse{}, repositoryError(err)
+	}
+
+	users := make([]UserMatch, len(matches))
+	for i, match := range matches {
+		user := User{
+			Id:              match.Id,
+			FirstName:       match.FirstName,
+			LastName:        match.LastName,
+			Nickname:        match.Nickname,
+			Email:           match.Email,
+			Country:         match.Country,
+			Version:         match.Version,
+			CreatedAt:       match.CreatedAt,
+			UpdatedAt:       match.UpdatedAt,
+			EmailVerifiedAt: match.EmailVerifiedAt,
+			DeletedAt:       match.DeletedAt,
+		}
+
+		users[i] = UserMatch{
+			User:       user,
+			Rank:       match.Rank,
+			Highlights: highlights(user, terms),
 		}
 	}
 
-	return GetUsersManyResponse{Users: users}, nil
+	return SearchUsersResponse{Users: users, Page: request.Page, PerPage: request.PerPage}, nil
+}
+
+func (s *service) GetById(ctx context.Context, request GetUserByIdRequest) (GetUserResponse, error) {
+	entity, err := s.repo.GetById(ctx, request.Id)
+	if errors.Is(err, ErrNotFound) {
+		return GetUserResponse{}, userNotFoundError(request.Id)
+	}
+	if err != nil {
+		return GetUserResponse{}, repositoryError(err)
+	}
+
+	return GetUserResponse{
+		User{
+			Id:              entity.Id,
+			FirstName:       entity.FirstName,
+			LastName:        entity.LastName,
+			Nickname:        entity.Nickname,
+			Email:           entity.Email,
+			Country:         entity.Country,
+			Version:         entity.Version,
+			CreatedAt:       entity.CreatedAt,
+			UpdatedAt:       entity.UpdatedAt,
+			EmailVerifiedAt: entity.EmailVerifiedAt,
+		},
+	}, nil
+}
+
+// Authenticate verifies the password of the user having the nickname or the email given as login,
+// the login is compared by its canonical form.
+// The password hash is replaced transparently when it was created with outdated parameters.
+func (s *service) Authenticate(ctx context.Context, login string, password string) (string, error) {
+	entity, err := s.repo.GetByLogin(ctx, canonical(login))
+	if errors.Is(err, ErrNotFound) {
+		// the password is compared anyway, so that the registered logins cannot be told apart by the response time.
+		_ = s.hasher.Compare(s.dummyPasswordHash(), password)
+		return "", invalidCredentialsError()
+	}
+	if err != nil {
+		return "", repositoryError(err)
+	}
+
+	err = s.hasher.Compare(entity.Password, password)
+	if err != nil {
+		return "", invalidCredentialsError()
+	}
+
+	if s.requireVerifiedEmail && entity.EmailVerifiedAt == nil {
+		return "", emailNotVerifiedError()
+	}
+
+	// the rehash is not recorded in the history nor does it change the version, since the password stays the same
+	// and the user is not changed, only the way its password is stored.
+	if s.hasher.NeedsRehash(entity.Password) {
+		passwordHash, err := s.hasher.Hash(password)
+		if err != nil {
+			return "", passwordHashError()
+		}
+
+		err = s.repo.UpdatePassword(ctx, entity.Id, passwordHash)
+		if err != nil {
+			return "", repositoryError(err)
+		}
+	}
+
+	return entity.Id, nil
+}
+
+// dummyPasswordHash returns a hash created by the hasher which no password is compared successfully with.
+func (s *service) dummyPasswordHash() string {
+	s.dummyHash.once.Do(func() {
+		s.dummyHash.hash, _ = s.hasher.Hash(uuid.New().String())
+	})
+
+	return s.dummyHash.hash
+}
+
+// Revoked reports whether the access tokens of the user issued at the time are revoked,
+// they are revoked once the user is deleted or its password is reset.
+func (s *service) Revoked(ctx context.Context, subject string, issuedAt time.Time) (bool, error) {
+	revoked, err := s.repo.TokenRevoked(ctx, subject, issuedAt)
+	if err != nil {
+		return false, repositoryError(err)
+	}
+
+	return revoked, nil
+}
+
+// conditionalWriteError resolves why a write did not affect any user,
+// either the user does not exist or its version is different from the expected one.
+func (s *service) conditionalWriteError(ctx context.Context, id string) error {
+	_, err := s.repo.GetById(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return userNotFoundError(id)
+	}
+	if err != nil {
+		return repositoryError(err)
+	}
+
+	return versionMismatchError(id)
 }
@@ -6,33 +6,157 @@ import (
 	"faceit-backend-test/internal/pubsub"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
+var hasher = NewBcryptHasher(bcrypt.MinCost)
+
 type mockRepository struct {
-	createMock     func(context.Context, Entity) (Entity, error)
-	updateMock     func(context.Context, Entity) (Entity, error)
-	deleteByIdMock func(context.Context, string) error
-	getManyMock    func(context.Context, GetManyParameters) ([]Entity, error)
+	transactionMock func(context.Context, func(Repository) error) error
+	createMock      func(context.Context, Entity) (Entity, error)
+	createManyMock  func(context.Context, []Entity) error
+	updateMock      func(context.Context, Entity) (EntityChange, error)
+	patchMock       func(context.Context, string, int, map[string]interface{}) (EntityChange, error)
+	deleteByIdMock  func(context.Context, string, int) (EntityChange, error)
+	restoreMock     func(context.Context, string) (EntityChange, error)
+	purgeMock       func(context.Context, time.Time) (int64, error)
+	getManyMock     func(context.Context, GetManyParameters) ([]Entity, error)
+	countMock       func(context.Context, GetManyParameters) (int, error)
+	exportMock      func(context.Context, GetManyParameters, func(Entity) error) error
+	searchMock      func(context.Context, SearchParameters) ([]EntityMatch, error)
+	takenMock       func(context.Context, []string) ([]string, error)
+	verifyEmailMock func(context.Context, string, string) (EntityChange, error)
+	getByIdMock     func(context.Context, string) (Entity, error)
+	getByLoginMock  func(context.Context, string) (Entity, error)
+	updatePwdMock   func(context.Context, string, string) error
+	revokedMock     func(context.Context, string, time.Time) (bool, error)
+	createResetMock func(context.Context, string, string, time.Duration) (Entity, error)
+	resetPwdMock    func(context.Context, string, string) (Entity, error)
+	appendAuditMock func(context.Context, ...AuditEntity) error
+	historyMock     func(context.Context, string, int, int) ([]AuditEntity, error)
+	snapshotMock    func(context.Context, string, time.Time) (AuditEntity, error)
+	eraseMock       func(context.Context, string) (EntityChange, error)
+	personalMock    func(context.Context, string) (PersonalDataEntity, error)
+}
+
+// Transaction runs fn by the mock itself unless the transaction is mocked,
+// since every change runs in a transaction together with its audit entry.
+func (m *mockRepository) Transaction(ctx context.Context, fn func(repo Repository) error) error {
+	if m.transactionMock == nil {
+		return fn(m)
+	}
+
+	return m.transactionMock(ctx, fn)
 }
 
 func (m *mockRepository) Create(ctx context.Context, entity Entity) (Entity, error) {
 	return m.createMock(ctx, entity)
 }
 
-func (m *mockRepository) Update(ctx context.Context, entity Entity) (Entity, error) {
+func (m *mockRepository) CreateMany(ctx context.Context, entities []Entity) error {
+	return m.createManyMock(ctx, entities)
+}
+
+func (m *mockRepository) Update(ctx context.Context, entity Entity) (EntityChange, error) {
 	return m.updateMock(ctx, entity)
 }
 
-func (m *mockRepository) DeleteById(ctx context.Context, id string) error {
-	return m.deleteByIdMock(ctx, id)
+func (m *mockRepository) Patch(ctx context.Context, id string, version int, changes map[string]interface{}) (EntityChange, error) {
+	return m.patchMock(ctx, id, version, changes)
+}
+
+func (m *mockRepository) DeleteById(ctx context.Context, id string, version int) (EntityChange, error) {
+	return m.deleteByIdMock(ctx, id, version)
+}
+
+func (m *mockRepository) Restore(ctx context.Context, id string) (EntityChange, error) {
+	return m.restoreMock(ctx, id)
+}
+
+func (m *mockRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	return m.purgeMock(ctx, before)
 }
 
 func (m *mockRepository) GetMany(ctx context.Context, parameters GetManyParameters) ([]Entity, error) {
 	return m.getManyMock(ctx, parameters)
 }
 
+func (m *mockRepository) Count(ctx context.Context, parameters GetManyParameters) (int, error) {
+	return m.countMock(ctx, parameters)
+}
+
+func (m *mockRepository) Export(ctx context.Context, parameters GetManyParameters, fn func(entity Entity) error) error {
+	return m.exportMock(ctx, parameters, fn)
+}
+
+func (m *mockRepository) Search(ctx context.Context, parameters SearchParameters) ([]EntityMatch, error) {
+	return m.searchMock(ctx, parameters)
+}
+
+func (m *mockRepository) TakenNicknames(ctx context.Context, nicknames []string) ([]string, error) {
+	return m.takenMock(ctx, nicknames)
+}
+
+func (m *mockRepository) VerifyEmail(ctx context.Context, id string, email string) (EntityChange, error) {
+	return m.verifyEmailMock(ctx, id, email)
+}
+
+func (m *mockRepository) GetById(ctx context.Context, id string) (Entity, error) {
+	return m.getByIdMock(ctx, id)
+}
+
+func (m *mockRepository) GetByLogin(ctx context.Context, login string) (Entity, error) {
+	return m.getByLoginMock(ctx, login)
+}
+
+func (m *mockRepository) UpdatePassword(ctx context.Context, id string, password string) error {
+	return m.updatePwdMock(ctx, id, password)
+}
+
+func (m *mockRepository) TokenRevoked(ctx context.Context, id string, issuedAt time.Time) (bool, error) {
+	return m.revokedMock(ctx, id, issuedAt)
+}
+
+func (m *mockRepository) CreatePasswordResetToken(ctx context.Context, email string, tokenHash string, ttl time.Duration) (Entity, error) {
+	return m.createResetMock(ctx, email, tokenHash, ttl)
+}
+
+func (m *mockRepository) ResetPassword(ctx context.Context, tokenHash string, password string) (Entity, error) {
+	return m.resetPwdMock(ctx, tokenHash, password)
+}
+
+// AppendAudit accepts the entries unless the audit is mocked.
+func (m *mockRepository) AppendAudit(ctx context.Context, entities ...AuditEntity) error {
+	if m.appendAuditMock == nil {
+		return nil
+	}
+
+	return m.appendAuditMock(ctx, entities...)
+}
+
+func (m *mockRepository) GetHistory(ctx context.Context, userId string, limit int, offset int) ([]AuditEntity, error) {
+	return m.historyMock(ctx, userId, limit, offset)
+}
+
+func (m *mockRepository) GetSnapshot(ctx context.Context, userId string, at time.Time) (AuditEntity, error) {
+	return m.snapshotMock(ctx, userId, at)
+}
+
+func (m *mockRepository) Erase(ctx context.Context, id string) (EntityChange, error) {
+	return m.eraseMock(ctx, id)
+}
+
+func (m *mockRepository) GetPersonalData(ctx context.Context, id string) (PersonalDataEntity, error) {
+	return m.personalMock(ctx, id)
+}
+
 func TestService_Create(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		req := CreateUserRequest{
@@ -49,27 +173,34 @@ func TestService_Create(t *testing.T) {
 			assert.Equal(t, req.FirstName, entity.FirstName)
 			assert.Equal(t, req.LastName, entity.LastName)
 			assert.Equal(t, req.Nickname, entity.Nickname)
-			assert.Equal(t, req.Password, entity.Password)
+			assert.NotEqual(t, req.Password, entity.Password, "should not store the plain text password")
+			assert.NoError(t, hasher.Compare(entity.Password, req.Password))
 			assert.Equal(t, req.Email, entity.Email)
 			assert.Equal(t, req.Country, entity.Country)
 
 			return e, nil
 		}
 
-		service := NewService(WithRepository(mockRepo))
-
 		expected := CreateUserResponse{User{
 			Id:        e.Id,
 			FirstName: e.FirstName,
 			LastName:  e.LastName,
 			Nickname:  e.Nickname,
-			Password:  e.Password,
 			Email:     e.Email,
 			Country:   e.Country,
+			Version:   e.Version,
 			CreatedAt: e.CreatedAt,
 			UpdatedAt: e.UpdatedAt,
 		}}
 
+		mockBroker := &pubsub.MockBroker{}
+		mockBroker.PublishMock = func(s string, i interface{}) {
+			assert.Equal(t, UserCreatedTopic, s)
+			assert.EqualValues(t, expected.User, i)
+		}
+
+		service := NewService(WithRepository(mockRepo), WithBroker(mockBroker), WithPasswordHasher(hasher))
+
 		actual, err := service.Create(context.Background(), req)
 		assert.NoError(t, err)
 		assert.EqualValues(t, expected, actual)
@@ -83,7 +214,7 @@ func TestService_Create(t *testing.T) {
 			return Entity{}, expectedErr
 		}
 
-		service := NewService(WithRepository(mockRepo))
+		service := NewService(WithRepository(mockRepo), WithPasswordHasher(hasher))
 
 		req := CreateUserRequest{}
 		_, err := service.Create(context.Background(), req)
@@ -93,7 +224,36 @@ func TestService_Create(t *testing.T) {
 
 		apiErr := err.(apierr.ApiError)
 		assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
-		assert.Equal(t, expectedErr.Error(), apiErr.Message)
+		assert.ErrorIs(t, err, expectedErr)
+		assert.NotContains(t, apiErr.Message, expectedErr.Error(), "should not reveal the error of the database")
+	})
+
+	t.Run("should return conflict when the nickname is taken", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.createMock = func(ctx context.Context, entity Entity) (Entity, error) {
+			return Entity{}, &ConflictError{Constraint: "users_nickname_key", Field: "nickname"}
+		}
+
+		service := NewService(WithRepository(mockRepo), WithPasswordHasher(hasher))
+
+		_, err := service.Create(context.Background(), CreateUserRequest{Nickname: e.Nickname})
+		assert.Equal(t, userConflictError("nickname"), err)
+	})
+
+	t.Run("should hash the password with bcrypt by default", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.createMock = func(ctx context.Context, entity Entity) (Entity, error) {
+			assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(entity.Password), []byte(e.Password)))
+			return e, nil
+		}
+
+		mockBroker := &pubsub.MockBroker{}
+		mockBroker.PublishMock = func(s string, i interface{}) {}
+
+		service := NewService(WithRepository(mockRepo), WithBroker(mockBroker))
+
+		_, err := service.Create(context.Background(), CreateUserRequest{Password: e.Password})
+		assert.NoError(t, err)
 	})
 }
 
@@ -110,16 +270,17 @@ func TestService_Update(t *testing.T) {
 		}
 
 		mockRepo := &mockRepository{}
-		mockRepo.updateMock = func(ctx context.Context, entity Entity) (Entity, error) {
+		mockRepo.updateMock = func(ctx context.Context, entity Entity) (EntityChange, error) {
 			assert.Equal(t, req.Id, entity.Id)
 			assert.Equal(t, req.FirstName, entity.FirstName)
 			assert.Equal(t, req.LastName, entity.LastName)
 			assert.Equal(t, req.Nickname, entity.Nickname)
-			assert.Equal(t, req.Password, entity.Password)
+			assert.NotEqual(t, req.Password, entity.Password, "should not store the plain text password")
+			assert.NoError(t, hasher.Compare(entity.Password, req.Password))
 			assert.Equal(t, req.Email, entity.Email)
 			assert.Equal(t, req.Country, entity.Country)
 
-			return e, nil
+			return EntityChange{Entity: e, Previous: e}, nil
 		}
 
 		expected := UpdateUserResponse{User{
@@ -127,9 +288,9 @@ func TestService_Update(t *testing.T) {
 			FirstName: e.FirstName,
 			LastName:  e.LastName,
 			Nickname:  e.Nickname,
-			Password:  e.Password,
 			Email:     e.Email,
 			Country:   e.Country,
+			Version:   e.Version,
 			CreatedAt: e.CreatedAt,
 			UpdatedAt: e.UpdatedAt,
 		}}
@@ -137,13 +298,15 @@ func TestService_Update(t *testing.T) {
 		mockBroker := &pubsub.MockBroker{}
 		mockBroker.PublishMock = func(s string, i interface{}) {
 			assert.Equal(t, UserChangeTopic, s)
-			assert.IsType(t, User{}, i)
+			assert.IsType(t, UserChangeEvent{}, i)
 
-			val := i.(User)
-			assert.EqualValues(t, expected.User, val)
+			val := i.(UserChangeEvent)
+			assert.EqualValues(t, expected.User, val.User)
+			assert.EqualValues(t, expected.User, val.Previous)
+			assert.Empty(t, val.ChangedFields)
 		}
 
-		service := NewService(WithRepository(mockRepo), WithBroker(mockBroker))
+		service := NewService(WithRepository(mockRepo), WithBroker(mockBroker), WithPasswordHasher(hasher))
 		actual, err := service.Update(context.Background(), req)
 		assert.NoError(t, err)
 		assert.EqualValues(t, expected, actual)
@@ -153,11 +316,11 @@ func TestService_Update(t *testing.T) {
 		expectedErr := fmt.Errorf("mock error")
 
 		mockRepo := &mockRepository{}
-		mockRepo.updateMock = func(ctx context.Context, entity Entity) (Entity, error) {
-			return Entity{}, expectedErr
+		mockRepo.updateMock = func(ctx context.Context, entity Entity) (EntityChange, error) {
+			return EntityChange{}, expectedErr
 		}
 
-		service := NewService(WithRepository(mockRepo))
+		service := NewService(WithRepository(mockRepo), WithPasswordHasher(hasher))
 
 		req := UpdateUserRequest{}
 		_, err := service.Update(context.Background(), req)
@@ -167,7 +330,138 @@ func TestService_Update(t *testing.T) {
 
 		apiErr := err.(apierr.ApiError)
 		assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
-		assert.Equal(t, expectedErr.Error(), apiErr.Message)
+		assert.ErrorIs(t, err, expectedErr)
+	})
+}
+
+func TestService_Patch(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		country := "DE"
+		password := "newpassword"
+		req := PatchUserRequest{
+			Id:       e.Id,
+			Country:  &country,
+			Password: &password,
+		}
+
+		patched := e
+		patched.Country = country
+
+		mockRepo := &mockRepository{}
+		mockRepo.patchMock = func(ctx context.Context, id string, version int, changes map[string]interface{}) (EntityChange, error) {
+			assert.Equal(t, req.Id, id)
+			assert.Len(t, changes, 2, "should only change the fields provided in the request")
+			assert.Equal(t, country, changes["country"])
+			assert.NoError(t, hasher.Compare(changes["password"].(string), password))
+
+			return EntityChange{Entity: patched, Previous: e}, nil
+		}
+
+		expected := UpdateUserResponse{User{
+			Id:        patched.Id,
+			FirstName: patched.FirstName,
+			LastName:  patched.LastName,
+			Nickname:  patched.Nickname,
+			Email:     patched.Email,
+			Country:   patched.Country,
+			Version:   patched.Version,
+			CreatedAt: patched.CreatedAt,
+			UpdatedAt: patched.UpdatedAt,
+		}}
+
+		mockBroker := &pubsub.MockBroker{}
+		mockBroker.PublishMock = func(s string, i interface{}) {
+			assert.Equal(t, UserChangeTopic, s)
+			assert.IsType(t, UserChangeEvent{}, i)
+
+			val := i.(UserChangeEvent)
+			assert.EqualValues(t, expected.User, val.User)
+			assert.Equal(t, e.Country, val.Previous.Country)
+			assert.Equal(t, []string{"country"}, val.ChangedFields)
+		}
+
+		service := NewService(WithRepository(mockRepo), WithBroker(mockBroker), WithPasswordHasher(hasher))
+		actual, err := service.Patch(context.Background(), req)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, actual)
+	})
+
+	t.Run("should return the current user when the patch is empty", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.getByIdMock = func(ctx context.Context, id string) (Entity, error) {
+			assert.Equal(t, e.Id, id)
+
+			return e, nil
+		}
+
+		service := NewService(WithRepository(mockRepo))
+		actual, err := service.Patch(context.Background(), PatchUserRequest{Id: e.Id})
+		assert.NoError(t, err)
+		assert.Equal(t, e.Id, actual.Id)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		country := "DE"
+
+		mockRepo := &mockRepository{}
+		mockRepo.patchMock = func(ctx context.Context, id string, version int, changes map[string]interface{}) (EntityChange, error) {
+			return EntityChange{}, ErrNotFound
+		}
+		mockRepo.getByIdMock = func(ctx context.Context, id string) (Entity, error) {
+			return Entity{}, ErrNotFound
+		}
+
+		service := NewService(WithRepository(mockRepo))
+		_, err := service.Patch(context.Background(), PatchUserRequest{Id: e.Id, Country: &country})
+
+		assert.Error(t, err)
+		assert.EqualValues(t, userNotFoundError(e.Id), err)
+	})
+
+	t.Run("version mismatch", func(t *testing.T) {
+		country := "DE"
+
+		mockRepo := &mockRepository{}
+		mockRepo.patchMock = func(ctx context.Context, id string, version int, changes map[string]interface{}) (EntityChange, error) {
+			assert.Equal(t, e.Version, version)
+
+			return EntityChange{}, ErrNotFound
+		}
+		mockRepo.getByIdMock = func(ctx context.Context, id string) (Entity, error) {
+			updated := e
+			updated.Version = e.Version + 1
+
+			return updated, nil
+		}
+
+		service := NewService(WithRepository(mockRepo))
+		_, err := service.Patch(context.Background(), PatchUserRequest{Id: e.Id, Version: e.Version, Country: &country})
+
+		assert.Error(t, err)
+		assert.EqualValues(t, versionMismatchError(e.Id), err)
+
+		apiErr := err.(apierr.ApiError)
+		assert.Equal(t, http.StatusPreconditionFailed, apiErr.StatusCode)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		country := "DE"
+		expectedErr := fmt.Errorf("mock error")
+
+		mockRepo := &mockRepository{}
+		mockRepo.patchMock = func(ctx context.Context, id string, version int, changes map[string]interface{}) (EntityChange, error) {
+			return EntityChange{}, expectedErr
+		}
+
+		service := NewService(WithRepository(mockRepo))
+		_, err := service.Patch(context.Background(), PatchUserRequest{Id: e.Id, Country: &country})
+
+		assert.Error(t, err)
+		assert.IsType(t, apierr.ApiError{}, err)
+
+		apiErr := err.(apierr.ApiError)
+		assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+		assert.ErrorIs(t, err, expectedErr)
 	})
 }
 
@@ -177,25 +471,63 @@ func TestService_DeleteById(t *testing.T) {
 		expected := DeleteUserResponse{Id: e.Id}
 
 		mockRepo := &mockRepository{}
-		mockRepo.deleteByIdMock = func(ctx context.Context, s string) error {
+		mockRepo.deleteByIdMock = func(ctx context.Context, s string, version int) (EntityChange, error) {
 			assert.Equal(t, req.Id, s)
 
-			return nil
+			return EntityChange{Entity: e}, nil
 		}
 
-		service := NewService(WithRepository(mockRepo))
+		mockBroker := &pubsub.MockBroker{}
+		mockBroker.PublishMock = func(s string, i interface{}) {
+			assert.Equal(t, UserDeletedTopic, s)
+			assert.EqualValues(t, expected, i)
+		}
+
+		service := NewService(WithRepository(mockRepo), WithBroker(mockBroker))
 
 		actual, err := service.DeleteById(context.Background(), req)
 		assert.NoError(t, err)
 		assert.Equal(t, expected.Id, actual.Id)
 	})
 
+	t.Run("should return not found when the user does not exist", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.deleteByIdMock = func(ctx context.Context, id string, version int) (EntityChange, error) {
+			return EntityChange{}, ErrNotFound
+		}
+		mockRepo.getByIdMock = func(ctx context.Context, id string) (Entity, error) {
+			return Entity{}, ErrNotFound
+		}
+
+		service := NewService(WithRepository(mockRepo))
+
+		_, err := service.DeleteById(context.Background(), DeleteUserByIdRequest{Id: e.Id})
+		assert.Equal(t, userNotFoundError(e.Id), err)
+	})
+
+	t.Run("version mismatch", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.deleteByIdMock = func(ctx context.Context, id string, version int) (EntityChange, error) {
+			assert.Equal(t, e.Version, version)
+
+			return EntityChange{}, ErrNotFound
+		}
+		mockRepo.getByIdMock = func(ctx context.Context, id string) (Entity, error) {
+			return e, nil
+		}
+
+		service := NewService(WithRepository(mockRepo))
+
+		_, err := service.DeleteById(context.Background(), DeleteUserByIdRequest{Id: e.Id, Version: e.Version})
+		assert.EqualValues(t, versionMismatchError(e.Id), err)
+	})
+
 	t.Run("repository error", func(t *testing.T) {
 		expectedErr := fmt.Errorf("mock error")
 
 		mockRepo := &mockRepository{}
-		mockRepo.deleteByIdMock = func(ctx context.Context, id string) error {
-			return expectedErr
+		mockRepo.deleteByIdMock = func(ctx context.Context, id string, version int) (EntityChange, error) {
+			return EntityChange{}, expectedErr
 		}
 
 		service := NewService(WithRepository(mockRepo))
@@ -208,7 +540,79 @@ func TestService_DeleteById(t *testing.T) {
 
 		apiErr := err.(apierr.ApiError)
 		assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
-		assert.Equal(t, expectedErr.Error(), apiErr.Message)
+		assert.ErrorIs(t, err, expectedErr)
+	})
+}
+
+func TestService_Restore(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		expected := RestoreUserResponse{User{
+			Id:        e.Id,
+			FirstName: e.FirstName,
+			LastName:  e.LastName,
+			Nickname:  e.Nickname,
+			Email:     e.Email,
+			Country:   e.Country,
+			Version:   e.Version,
+			CreatedAt: e.CreatedAt,
+			UpdatedAt: e.UpdatedAt,
+		}}
+
+		mockRepo := &mockRepository{}
+		mockRepo.restoreMock = func(ctx context.Context, id string) (EntityChange, error) {
+			assert.Equal(t, e.Id, id)
+
+			deleted := e
+			deletedAt := time.Now()
+			deleted.DeletedAt = &deletedAt
+
+			return EntityChange{Entity: e, Previous: deleted}, nil
+		}
+
+		mockBroker := &pubsub.MockBroker{}
+		mockBroker.PublishMock = func(s string, i interface{}) {
+			assert.Equal(t, UserChangeTopic, s)
+			assert.IsType(t, UserChangeEvent{}, i)
+
+			val := i.(UserChangeEvent)
+			assert.EqualValues(t, expected.User, val.User)
+			assert.NotNil(t, val.Previous.DeletedAt)
+			assert.Equal(t, []string{"deleted_at"}, val.ChangedFields)
+		}
+
+		service := NewService(WithRepository(mockRepo), WithBroker(mockBroker))
+		actual, err := service.Restore(context.Background(), RestoreUserRequest{Id: e.Id})
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, actual)
+	})
+
+	t.Run("should return the user when it is not deleted", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.restoreMock = func(ctx context.Context, id string) (EntityChange, error) {
+			return EntityChange{}, ErrNotFound
+		}
+		mockRepo.getByIdMock = func(ctx context.Context, id string) (Entity, error) {
+			return e, nil
+		}
+
+		service := NewService(WithRepository(mockRepo))
+		actual, err := service.Restore(context.Background(), RestoreUserRequest{Id: e.Id})
+		assert.NoError(t, err)
+		assert.Equal(t, e.Id, actual.Id)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.restoreMock = func(ctx context.Context, id string) (EntityChange, error) {
+			return EntityChange{}, ErrNotFound
+		}
+		mockRepo.getByIdMock = func(ctx context.Context, id string) (Entity, error) {
+			return Entity{}, ErrNotFound
+		}
+
+		service := NewService(WithRepository(mockRepo))
+		_, err := service.Restore(context.Background(), RestoreUserRequest{Id: e.Id})
+		assert.EqualValues(t, userNotFoundError(e.Id), err)
 	})
 }
 
@@ -217,26 +621,28 @@ func TestService_GetMany(t *testing.T) {
 		req := GetUsersManyRequest{
 			Page:    1,
 			PerPage: 10,
-			Filter:  User{},
+			Filter: []FilterCondition{
+				{Column: "country", Operator: FilterEq, Values: []interface{}{"UK"}},
+			},
 		}
 
 		mockRepo := &mockRepository{}
 		mockRepo.getManyMock = func(ctx context.Context, parameters GetManyParameters) ([]Entity, error) {
-			assert.Equal(t, req.Page, parameters.Page)
-			assert.Equal(t, req.PerPage, parameters.PerPage)
-			assert.Equal(t, req.Filter.Id, parameters.Filter.Id)
-			assert.Equal(t, req.Filter.FirstName, parameters.Filter.FirstName)
-			assert.Equal(t, req.Filter.LastName, parameters.Filter.LastName)
-			assert.Equal(t, req.Filter.Nickname, parameters.Filter.Nickname)
-			assert.Equal(t, req.Filter.Password, parameters.Filter.Password)
-			assert.Equal(t, req.Filter.Country, parameters.Filter.Country)
-			assert.Equal(t, req.Filter.CreatedAt, parameters.Filter.CreatedAt)
-			assert.Equal(t, req.Filter.UpdatedAt, parameters.Filter.UpdatedAt)
+			assert.Equal(t, req.PerPage+1, parameters.Limit, "should fetch one more user to know whether there is a next page")
+			assert.Equal(t, 0, parameters.Offset)
+			assert.Nil(t, parameters.Cursor)
+			assert.Equal(t, req.Filter, parameters.Filter)
 
 			return entities, nil
 		}
+		mockRepo.countMock = func(ctx context.Context, parameters GetManyParameters) (int, error) {
+			assert.Equal(t, req.Filter, parameters.Filter)
+
+			return 25, nil
+		}
 
-		expected := GetUsersManyResponse{}
+		total, totalPages := 25, 3
+		expected := GetUsersManyResponse{Page: 1, PerPage: 10, Total: &total, TotalPages: &totalPages}
 		expected.Users = make([]User, len(entities))
 		for i, e := range entities {
 			expected.Users[i] = User{
@@ -244,7 +650,6 @@ func TestService_GetMany(t *testing.T) {
 				FirstName: e.FirstName,
 				LastName:  e.LastName,
 				Nickname:  e.Nickname,
-				Password:  e.Password,
 				Email:     e.Email,
 				Country:   e.Country,
 				CreatedAt: e.CreatedAt,
@@ -258,24 +663,871 @@ func TestService_GetMany(t *testing.T) {
 		assert.EqualValues(t, expected, actual)
 	})
 
-	t.Run("repository error", func(t *testing.T) {
-		expectedErr := fmt.Errorf("mock error")
+	t.Run("should return the next cursor when there are more users", func(t *testing.T) {
+		req := GetUsersManyRequest{
+			Page:      2,
+			PerPage:   3,
+			SkipTotal: true,
+		}
 
 		mockRepo := &mockRepository{}
 		mockRepo.getManyMock = func(ctx context.Context, parameters GetManyParameters) ([]Entity, error) {
-			return []Entity{}, expectedErr
+			assert.Equal(t, 4, parameters.Limit)
+			assert.Equal(t, 3, parameters.Offset)
+
+			return entities[3:7], nil
 		}
 
 		service := NewService(WithRepository(mockRepo))
+		actual, err := service.GetMany(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Len(t, actual.Users, 3)
+		assert.Equal(t, encodeCursor(after(entities[5], normalizeSort(nil))), actual.Next)
+		assert.Equal(t, encodeCursor(before(entities[3], normalizeSort(nil))), actual.Prev)
+	})
 
-		req := GetUsersManyRequest{}
-		_, err := service.GetMany(context.Background(), req)
+	t.Run("should page with the cursor", func(t *testing.T) {
+		cursor := after(entities[2], normalizeSort(nil))
+		req := GetUsersManyRequest{
+			Page:      5,
+			PerPage:   3,
+			Cursor:    encodeCursor(cursor),
+			SkipTotal: true,
+		}
 
-		assert.Error(t, err)
-		assert.IsType(t, apierr.ApiError{}, err)
+		mockRepo := &mockRepository{}
+		mockRepo.getManyMock = func(ctx context.Context, parameters GetManyParameters) ([]Entity, error) {
+			assert.Equal(t, 0, parameters.Offset, "should ignore the page when the cursor is given")
+			assert.NotNil(t, parameters.Cursor)
+			assert.Equal(t, []interface{}{entities[2].CreatedAt.Format(time.RFC3339Nano), entities[2].Id}, parameters.Cursor.Values)
 
-		apiErr := err.(apierr.ApiError)
-		assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
-		assert.Equal(t, expectedErr.Error(), apiErr.Message)
+			return entities[3:5], nil
+		}
+
+		service := NewService(WithRepository(mockRepo))
+		actual, err := service.GetMany(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Len(t, actual.Users, 2)
+		assert.Empty(t, actual.Next, "should not return the next cursor on the last page")
+		assert.Equal(t, encodeCursor(before(entities[3], normalizeSort(nil))), actual.Prev)
+	})
+
+	t.Run("should page backward with the cursor", func(t *testing.T) {
+		req := GetUsersManyRequest{
+			Page:      1,
+			PerPage:   3,
+			Cursor:    encodeCursor(before(entities[4], normalizeSort(nil))),
+			SkipTotal: true,
+		}
+
+		mockRepo := &mockRepository{}
+		mockRepo.getManyMock = func(ctx context.Context, parameters GetManyParameters) ([]Entity, error) {
+			assert.True(t, parameters.Cursor.Backward)
+
+			return entities[0:4], nil
+		}
+
+		service := NewService(WithRepository(mockRepo))
+		actual, err := service.GetMany(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Len(t, actual.Users, 3)
+		assert.Equal(t, entities[1].Id, actual.Users[0].Id, "should drop the extra user at the beginning")
+		assert.Equal(t, encodeCursor(after(entities[3], normalizeSort(nil))), actual.Next)
+		assert.Equal(t, encodeCursor(before(entities[1], normalizeSort(nil))), actual.Prev)
+	})
+
+	t.Run("should skip the total", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.getManyMock = func(ctx context.Context, parameters GetManyParameters) ([]Entity, error) {
+			return entities[0:2], nil
+		}
+
+		service := NewService(WithRepository(mockRepo))
+		actual, err := service.GetMany(context.Background(), GetUsersManyRequest{Page: 1, PerPage: 3, SkipTotal: true})
+		assert.NoError(t, err)
+		assert.Nil(t, actual.Total)
+		assert.Nil(t, actual.TotalPages)
+	})
+
+	t.Run("count error", func(t *testing.T) {
+		expectedErr := fmt.Errorf("mock error")
+
+		mockRepo := &mockRepository{}
+		mockRepo.getManyMock = func(ctx context.Context, parameters GetManyParameters) ([]Entity, error) {
+			return entities[0:2], nil
+		}
+		mockRepo.countMock = func(ctx context.Context, parameters GetManyParameters) (int, error) {
+			return 0, expectedErr
+		}
+
+		service := NewService(WithRepository(mockRepo))
+		_, err := service.GetMany(context.Background(), GetUsersManyRequest{Page: 1, PerPage: 3})
+		assert.EqualValues(t, repositoryError(expectedErr), err)
+	})
+
+	t.Run("should reject the cursor created for another sort", func(t *testing.T) {
+		req := GetUsersManyRequest{
+			Page:    1,
+			PerPage: 3,
+			Sort:    []SortField{{Column: "nickname"}},
+			Cursor:  encodeCursor(after(entities[0], normalizeSort(nil))),
+		}
+
+		service := NewService(WithRepository(&mockRepository{}))
+		_, err := service.GetMany(context.Background(), req)
+		assert.EqualValues(t, invalidCursorError(), err)
+	})
+
+	t.Run("should return the cursors of the sort", func(t *testing.T) {
+		sort := []SortField{{Column: "country", Descending: true}}
+		req := GetUsersManyRequest{
+			Page:      2,
+			PerPage:   3,
+			Sort:      sort,
+			SkipTotal: true,
+		}
+
+		mockRepo := &mockRepository{}
+		mockRepo.getManyMock = func(ctx context.Context, parameters GetManyParameters) ([]Entity, error) {
+			assert.Equal(t, normalizeSort(sort), parameters.Sort, "should break the ties by id")
+
+			return entities[3:7], nil
+		}
+
+		service := NewService(WithRepository(mockRepo))
+		actual, err := service.GetMany(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, encodeCursor(after(entities[5], normalizeSort(sort))), actual.Next)
+		assert.Equal(t, encodeCursor(before(entities[3], normalizeSort(sort))), actual.Prev)
+	})
+
+	t.Run("should return bad request when the cursor is invalid", func(t *testing.T) {
+		service := NewService(WithRepository(&mockRepository{}))
+		_, err := service.GetMany(context.Background(), GetUsersManyRequest{Page: 1, PerPage: 3, Cursor: "invalid"})
+		assert.EqualValues(t, invalidCursorError(), err)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		expectedErr := fmt.Errorf("mock error")
+
+		mockRepo := &mockRepository{}
+		mockRepo.getManyMock = func(ctx context.Context, parameters GetManyParameters) ([]Entity, error) {
+			return []Entity{}, expectedErr
+		}
+
+		service := NewService(WithRepository(mockRepo))
+
+		req := GetUsersManyRequest{}
+		_, err := service.GetMany(context.Background(), req)
+
+		assert.Error(t, err)
+		assert.IsType(t, apierr.ApiError{}, err)
+
+		apiErr := err.(apierr.ApiError)
+		assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+		assert.ErrorIs(t, err, expectedErr)
+	})
+}
+
+func TestService_Export(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		req := ExportUsersRequest{
+			Format: ExportFormatCSV,
+			Fields: []string{"id", "nickname"},
+			Sort:   []SortField{{Column: "nickname"}},
+			Filter: []FilterCondition{{Column: "country", Operator: FilterEq, Values: []interface{}{"UK"}}},
+		}
+
+		mockRepo := &mockRepository{}
+		mockRepo.exportMock = func(ctx context.Context, parameters GetManyParameters, fn func(Entity) error) error {
+			assert.EqualValues(t, GetManyParameters{Sort: req.Sort, Fields: req.Fields, Filter: req.Filter}, parameters)
+
+			for _, entity := range entities[:2] {
+				if err := fn(entity); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		service := NewService(WithRepository(mockRepo))
+
+		buf := &strings.Builder{}
+		err := service.Export(context.Background(), req, buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "id,nickname\n"+entities[0].Id+",bobby.firmino\n"+entities[1].Id+",bobby.firmino\n", buf.String())
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		expectedErr := fmt.Errorf("mock error")
+
+		mockRepo := &mockRepository{}
+		mockRepo.exportMock = func(ctx context.Context, parameters GetManyParameters, fn func(Entity) error) error {
+			return expectedErr
+		}
+
+		service := NewService(WithRepository(mockRepo))
+
+		err := service.Export(context.Background(), ExportUsersRequest{Format: ExportFormatNDJSON}, &strings.Builder{})
+		assert.Error(t, err)
+		assert.IsType(t, apierr.ApiError{}, err)
+
+		apiErr := err.(apierr.ApiError)
+		assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+		assert.ErrorIs(t, err, expectedErr)
+	})
+
+	t.Run("should not write the parquet header when the repository fails", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.exportMock = func(ctx context.Context, parameters GetManyParameters, fn func(Entity) error) error {
+			return fmt.Errorf("mock error")
+		}
+
+		service := NewService(WithRepository(mockRepo))
+
+		buf := &strings.Builder{}
+		err := service.Export(context.Background(), ExportUsersRequest{Format: ExportFormatParquet}, buf)
+		assert.Error(t, err)
+		assert.Empty(t, buf.String(), "should leave the response to the error")
+	})
+}
+
+func TestService_Search(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.searchMock = func(ctx context.Context, parameters SearchParameters) ([]EntityMatch, error) {
+			assert.Equal(t, "Firm bob", parameters.Query)
+			assert.Equal(t, []string{"firm", "bob"}, parameters.Terms)
+			assert.Equal(t, 5, parameters.Limit)
+			assert.Equal(t, 5, parameters.Offset)
+
+			return []EntityMatch{{Entity: e, Rank: 0.7}}, nil
+		}
+
+		service := NewService(WithRepository(mockRepo))
+		actual, err := service.Search(context.Background(), SearchUsersRequest{Query: "Firm bob", Page: 2, PerPage: 5})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, actual.Page)
+		assert.Equal(t, 5, actual.PerPage)
+		assert.Len(t, actual.Users, 1)
+		assert.Equal(t, e.Id, actual.Users[0].Id)
+		assert.Equal(t, 0.7, actual.Users[0].Rank)
+		assert.Equal(t, map[string]string{
+			"last_name": "<em>Firm</em>ino",
+			"nickname":  "<em>bob</em>by.<em>firm</em>ino",
+			"email":     "roberto<em>firm</em>ino@lfc.co.uk",
+		}, actual.Users[0].Highlights)
+	})
+
+	t.Run("should return bad request when the query has no words", func(t *testing.T) {
+		service := NewService(WithRepository(&mockRepository{}))
+		_, err := service.Search(context.Background(), SearchUsersRequest{Query: "@!", Page: 1, PerPage: 5})
+		assert.EqualValues(t, invalidSearchQueryError(), err)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		expectedErr := fmt.Errorf("mock error")
+
+		mockRepo := &mockRepository{}
+		mockRepo.searchMock = func(ctx context.Context, parameters SearchParameters) ([]EntityMatch, error) {
+			return nil, expectedErr
+		}
+
+		service := NewService(WithRepository(mockRepo))
+		_, err := service.Search(context.Background(), SearchUsersRequest{Query: "firm", Page: 1, PerPage: 5})
+		assert.EqualValues(t, repositoryError(expectedErr), err)
+	})
+}
+
+func TestService_Batch(t *testing.T) {
+	user := &CreateUserRequest{
+		FirstName: e.FirstName,
+		LastName:  e.LastName,
+		Nickname:  e.Nickname,
+		Password:  e.Password,
+		Email:     e.Email,
+		Country:   e.Country,
+	}
+
+	newMockRepo := func() *mockRepository {
+		mockRepo := &mockRepository{}
+		mockRepo.transactionMock = func(ctx context.Context, fn func(Repository) error) error {
+			return fn(mockRepo)
+		}
+		mockRepo.createMock = func(ctx context.Context, entity Entity) (Entity, error) {
+			assert.NoError(t, hasher.Compare(entity.Password, user.Password), "should hash the password")
+			return e, nil
+		}
+		mockRepo.deleteByIdMock = func(ctx context.Context, id string, version int) (EntityChange, error) {
+			return EntityChange{Entity: e}, nil
+		}
+		return mockRepo
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo := newMockRepo()
+
+		committed := false
+		transactionMock := mockRepo.transactionMock
+		mockRepo.transactionMock = func(ctx context.Context, fn func(Repository) error) error {
+			err := transactionMock(ctx, fn)
+			committed = true
+			return err
+		}
+
+		var topics []string
+		mockBroker := &pubsub.MockBroker{}
+		mockBroker.PublishMock = func(s string, i interface{}) {
+			assert.True(t, committed, "should publish the events after the commit")
+			topics = append(topics, s)
+		}
+
+		service := NewService(WithRepository(mockRepo), WithBroker(mockBroker), WithPasswordHasher(hasher))
+
+		req := BatchUsersRequest{Operations: []BatchOperation{
+			{Op: BatchOpCreate, User: user},
+			{Op: BatchOpDelete, Id: e.Id},
+		}}
+		resp, err := service.Batch(context.Background(), req)
+		assert.NoError(t, err)
+		assert.True(t, resp.Committed)
+		assert.Len(t, resp.Results, 2)
+		assert.Equal(t, http.StatusCreated, resp.Results[0].Status)
+		assert.Equal(t, e.Id, resp.Results[0].Id)
+		assert.Equal(t, http.StatusOK, resp.Results[1].Status)
+		assert.Equal(t, []string{UserCreatedTopic, UserDeletedTopic}, topics)
+	})
+
+	t.Run("atomic mode rolls back every operation when one fails", func(t *testing.T) {
+		mockRepo := newMockRepo()
+		mockRepo.updateMock = func(ctx context.Context, entity Entity) (EntityChange, error) {
+			return EntityChange{}, ErrNotFound
+		}
+		mockRepo.getByIdMock = func(ctx context.Context, id string) (Entity, error) {
+			return Entity{}, ErrNotFound
+		}
+
+		mockBroker := &pubsub.MockBroker{}
+		mockBroker.PublishMock = func(s string, i interface{}) {
+			assert.Fail(t, "should not publish the events of a rolled back batch")
+		}
+
+		service := NewService(WithRepository(mockRepo), WithBroker(mockBroker), WithPasswordHasher(hasher))
+
+		req := BatchUsersRequest{Mode: BatchModeAtomic, Operations: []BatchOperation{
+			{Op: BatchOpCreate, User: user},
+			{Op: BatchOpUpdate, Id: e.Id, User: user},
+			{Op: BatchOpDelete, Id: e.Id},
+		}}
+		resp, err := service.Batch(context.Background(), req)
+		assert.NoError(t, err)
+		assert.False(t, resp.Committed)
+		assert.Equal(t, http.StatusFailedDependency, resp.Results[0].Status)
+		assert.Equal(t, http.StatusNotFound, resp.Results[1].Status)
+		assert.Equal(t, "1003", resp.Results[1].Error.Code)
+		assert.Equal(t, http.StatusFailedDependency, resp.Results[2].Status)
+		assert.Equal(t, e.Id, resp.Results[2].Id)
+	})
+
+	t.Run("partial mode applies the operations that succeed", func(t *testing.T) {
+		mockRepo := newMockRepo()
+		mockRepo.deleteByIdMock = func(ctx context.Context, id string, version int) (EntityChange, error) {
+			return EntityChange{}, ErrNotFound
+		}
+		mockRepo.getByIdMock = func(ctx context.Context, id string) (Entity, error) {
+			return e, nil
+		}
+
+		transactions := 0
+		transactionMock := mockRepo.transactionMock
+		mockRepo.transactionMock = func(ctx context.Context, fn func(Repository) error) error {
+			transactions++
+			return transactionMock(ctx, fn)
+		}
+
+		var topics []string
+		mockBroker := &pubsub.MockBroker{}
+		mockBroker.PublishMock = func(s string, i interface{}) {
+			topics = append(topics, s)
+		}
+
+		service := NewService(WithRepository(mockRepo), WithBroker(mockBroker), WithPasswordHasher(hasher))
+
+		req := BatchUsersRequest{Mode: BatchModePartial, Operations: []BatchOperation{
+			{Op: BatchOpCreate, User: user},
+			{Op: BatchOpDelete, Id: e.Id, Version: e.Version + 1},
+		}}
+		resp, err := service.Batch(context.Background(), req)
+		assert.NoError(t, err)
+		assert.True(t, resp.Committed)
+		assert.Equal(t, 5, transactions, "should run every operation in a savepoint together with its audit entry")
+		assert.Equal(t, http.StatusCreated, resp.Results[0].Status)
+		assert.Equal(t, http.StatusPreconditionFailed, resp.Results[1].Status)
+		assert.Equal(t, []string{UserCreatedTopic}, topics, "should only publish the events of the applied operations")
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		expectedErr := fmt.Errorf("mock error")
+
+		mockRepo := &mockRepository{}
+		mockRepo.transactionMock = func(ctx context.Context, fn func(Repository) error) error {
+			return expectedErr
+		}
+
+		service := NewService(WithRepository(mockRepo), WithPasswordHasher(hasher))
+
+		_, err := service.Batch(context.Background(), BatchUsersRequest{Operations: []BatchOperation{{Op: BatchOpDelete, Id: e.Id}}})
+		assert.Error(t, err)
+		assert.IsType(t, apierr.ApiError{}, err)
+
+		apiErr := err.(apierr.ApiError)
+		assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+		assert.ErrorIs(t, err, expectedErr)
+	})
+
+	t.Run("should validate the operations before hashing the passwords", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.transactionMock = func(ctx context.Context, fn func(Repository) error) error {
+			assert.Fail(t, "should not start the transaction")
+			return nil
+		}
+
+		hashed := &hashingHasher{PasswordHasher: hasher}
+		service := NewService(WithRepository(mockRepo), WithPasswordHasher(hashed))
+
+		_, err := service.Batch(context.Background(), BatchUsersRequest{Operations: []BatchOperation{
+			{Op: BatchOpCreate, User: user},
+			{Op: BatchOpUpdate, User: user},
+		}})
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, err.(apierr.ApiError).StatusCode)
+		assert.Zero(t, hashed.count, "should not hash the passwords of an invalid batch")
+	})
+
+	t.Run("should hash every password of the batch", func(t *testing.T) {
+		mockRepo := newMockRepo()
+		created := 0
+		mockRepo.createMock = func(ctx context.Context, entity Entity) (Entity, error) {
+			created++
+			assert.NoError(t, hasher.Compare(entity.Password, user.Password), "should hash the password")
+			return e, nil
+		}
+
+		mockBroker := &pubsub.MockBroker{}
+		mockBroker.PublishMock = func(s string, i interface{}) {}
+
+		hashed := &hashingHasher{PasswordHasher: hasher}
+		service := NewService(WithRepository(mockRepo), WithBroker(mockBroker), WithPasswordHasher(hashed))
+
+		operations := make([]BatchOperation, 20)
+		for i := range operations {
+			operations[i] = BatchOperation{Op: BatchOpCreate, User: user}
+		}
+
+		resp, err := service.Batch(context.Background(), BatchUsersRequest{Operations: operations})
+		assert.NoError(t, err)
+		assert.True(t, resp.Committed)
+		assert.Equal(t, 20, created)
+		assert.EqualValues(t, 20, hashed.count)
+	})
+
+	t.Run("should keep the configuration of the service in the transaction", func(t *testing.T) {
+		txRepo := &mockRepository{}
+		service := NewService(WithRepository(&mockRepository{}), WithPasswordHasher(hasher), WithRequireVerifiedEmail(true))
+		txService := service.withTransaction(txRepo, &bufferedBroker{}, &bufferedMailer{})
+
+		assert.Same(t, txRepo, txService.repo)
+		expected := *service
+		expected.repo = txService.repo
+		expected.broker = txService.broker
+		expected.mailer = txService.mailer
+		assert.Equal(t, &expected, txService)
+	})
+}
+
+// hashingHasher counts the passwords hashed by the hasher, it is safe for concurrent use.
+type hashingHasher struct {
+	PasswordHasher
+	count int64
+}
+
+func (h *hashingHasher) Hash(password string) (string, error) {
+	atomic.AddInt64(&h.count, 1)
+	return h.PasswordHasher.Hash(password)
+}
+
+func TestService_Import(t *testing.T) {
+	upload := "first_name,last_name,nickname,password,email,country\n" +
+		"Roberto,Firmino,bobby.firmino,liverpool321,robertofirmino@lfc.co.uk,GB\n" +
+		"Mohamed,Salah,mo.salah,,mosalah@lfc.co.uk,EG\n" +
+		"Virgil,van Dijk,vvd,liverpool4,vvd@lfc.co.uk,NL\n" +
+		"Sadio,Mane,sadio,liverpool10,sadio@lfc.co.uk,SN\n"
+
+	waitImport := func(t *testing.T, service *service, id string) ImportJob {
+		var job ImportJobResponse
+		assert.Eventually(t, func() bool {
+			var err error
+			job, err = service.GetImport(context.Background(), GetImportRequest{Id: id})
+			assert.NoError(t, err)
+			return job.FinishedAt != nil
+		}, time.Second, 10*time.Millisecond)
+
+		return job.ImportJob
+	}
+
+	t.Run("success", func(t *testing.T) {
+		var batches [][]Entity
+		mockRepo := &mockRepository{}
+		mockRepo.createManyMock = func(ctx context.Context, entities []Entity) error {
+			batch := make([]Entity, len(entities))
+			copy(batch, entities)
+			batches = append(batches, batch)
+
+			if len(entities) > 1 {
+				return fmt.Errorf("mock batch error")
+			}
+			if entities[0].Nickname == "vvd" {
+				return fmt.Errorf("duplicate nickname")
+			}
+			return nil
+		}
+
+		var created []string
+		mockBroker := &pubsub.MockBroker{}
+		mockBroker.PublishMock = func(s string, i interface{}) {
+			assert.Equal(t, UserCreatedTopic, s)
+			created = append(created, i.(User).Nickname)
+		}
+
+		service := NewService(WithRepository(mockRepo), WithBroker(mockBroker), WithPasswordHasher(hasher), WithImportBatchSize(2))
+
+		resp, err := service.Import(context.Background(), ImportUsersRequest{Format: ImportFormatCSV, File: strings.NewReader(upload)})
+		assert.NoError(t, err)
+		assert.Equal(t, ImportStatusPending, resp.Status)
+
+		job := waitImport(t, service, resp.Id)
+		assert.Equal(t, ImportStatusCompleted, job.Status)
+		assert.Equal(t, 4, job.Processed)
+		assert.Equal(t, 2, job.Imported)
+		assert.Equal(t, 2, job.Failed)
+		assert.Equal(t, []string{"bobby.firmino", "sadio"}, created)
+
+		assert.Len(t, batches, 4, "should insert the rows of a failed batch one by one")
+		assert.Len(t, batches[0], 2)
+		assert.NotEmpty(t, batches[0][0].Id)
+		assert.NoError(t, hasher.Compare(batches[0][0].Password, "liverpool321"), "should hash the passwords")
+
+		errs, err := service.GetImportErrors(context.Background(), GetImportRequest{Id: resp.Id})
+		assert.NoError(t, err)
+		assert.Len(t, errs.Errors, 2)
+		assert.Equal(t, 2, errs.Errors[0].Row, "should validate the rows like the create requests")
+		assert.Equal(t, 3, errs.Errors[1].Row)
+		assert.Equal(t, "duplicate nickname", errs.Errors[1].Error)
+	})
+
+	t.Run("should report the rows whose passwords cannot be hashed", func(t *testing.T) {
+		var created []string
+		mockRepo := &mockRepository{}
+		mockRepo.createManyMock = func(ctx context.Context, entities []Entity) error {
+			for _, entity := range entities {
+				created = append(created, entity.Nickname)
+			}
+			return nil
+		}
+
+		service := NewService(WithRepository(mockRepo), WithBroker(&pubsub.MockBroker{PublishMock: func(string, interface{}) {}}),
+			WithPasswordHasher(&failingHasher{PasswordHasher: hasher, password: "liverpool4"}), WithImportBatchSize(2))
+
+		resp, err := service.Import(context.Background(), ImportUsersRequest{Format: ImportFormatCSV, File: strings.NewReader(upload)})
+		assert.NoError(t, err)
+
+		job := waitImport(t, service, resp.Id)
+		assert.Equal(t, 2, job.Imported)
+		assert.Equal(t, 2, job.Failed)
+		assert.Equal(t, []string{"bobby.firmino", "sadio"}, created)
+
+		errs, err := service.GetImportErrors(context.Background(), GetImportRequest{Id: resp.Id})
+		assert.NoError(t, err)
+		assert.Len(t, errs.Errors, 2)
+		assert.Equal(t, 3, errs.Errors[1].Row)
+		assert.Equal(t, passwordHashError().Message, errs.Errors[1].Error)
+	})
+
+	t.Run("should fail the job when the upload is invalid", func(t *testing.T) {
+		service := NewService(WithRepository(&mockRepository{}), WithPasswordHasher(hasher))
+
+		resp, err := service.Import(context.Background(), ImportUsersRequest{Format: ImportFormatCSV, File: strings.NewReader("first_name\n")})
+		assert.NoError(t, err)
+
+		job := waitImport(t, service, resp.Id)
+		assert.Equal(t, ImportStatusFailed, job.Status)
+		assert.NotEmpty(t, job.Error)
+	})
+
+	t.Run("should reject the unsupported formats", func(t *testing.T) {
+		service := NewService()
+
+		_, err := service.Import(context.Background(), ImportUsersRequest{Format: "application/xml", File: strings.NewReader("")})
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusUnsupportedMediaType, err.(apierr.ApiError).StatusCode)
+	})
+
+	t.Run("should return request entity too large when the upload exceeds the limit", func(t *testing.T) {
+		service := NewService()
+
+		file := http.MaxBytesReader(httptest.NewRecorder(), io.NopCloser(strings.NewReader(upload)), 10)
+		_, err := service.Import(context.Background(), ImportUsersRequest{Format: ImportFormatCSV, File: file})
+		assert.EqualValues(t, importTooLargeError(10), err)
+	})
+
+	t.Run("should return not found when the job does not exist", func(t *testing.T) {
+		service := NewService()
+
+		_, err := service.GetImport(context.Background(), GetImportRequest{Id: e.Id})
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusNotFound, err.(apierr.ApiError).StatusCode)
+
+		_, err = service.GetImportErrors(context.Background(), GetImportRequest{Id: e.Id})
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusNotFound, err.(apierr.ApiError).StatusCode)
+	})
+}
+
+func TestService_GetById(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		req := GetUserByIdRequest{Id: e.Id}
+
+		mockRepo := &mockRepository{}
+		mockRepo.getByIdMock = func(ctx context.Context, id string) (Entity, error) {
+			assert.Equal(t, req.Id, id)
+
+			return e, nil
+		}
+
+		expected := GetUserResponse{User{
+			Id:        e.Id,
+			FirstName: e.FirstName,
+			LastName:  e.LastName,
+			Nickname:  e.Nickname,
+			Email:     e.Email,
+			Country:   e.Country,
+			Version:   e.Version,
+			CreatedAt: e.CreatedAt,
+			UpdatedAt: e.UpdatedAt,
+		}}
+
+		service := NewService(WithRepository(mockRepo))
+		actual, err := service.GetById(context.Background(), req)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, actual)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.getByIdMock = func(ctx context.Context, id string) (Entity, error) {
+			return Entity{}, ErrNotFound
+		}
+
+		service := NewService(WithRepository(mockRepo))
+		_, err := service.GetById(context.Background(), GetUserByIdRequest{Id: e.Id})
+
+		assert.Error(t, err)
+		assert.IsType(t, apierr.ApiError{}, err)
+
+		apiErr := err.(apierr.ApiError)
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+		assert.EqualValues(t, userNotFoundError(e.Id), apiErr)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		expectedErr := fmt.Errorf("mock error")
+
+		mockRepo := &mockRepository{}
+		mockRepo.getByIdMock = func(ctx context.Context, id string) (Entity, error) {
+			return Entity{}, expectedErr
+		}
+
+		service := NewService(WithRepository(mockRepo))
+		_, err := service.GetById(context.Background(), GetUserByIdRequest{Id: e.Id})
+
+		assert.Error(t, err)
+		assert.IsType(t, apierr.ApiError{}, err)
+
+		apiErr := err.(apierr.ApiError)
+		assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+		assert.ErrorIs(t, err, expectedErr)
+	})
+}
+
+func TestService_Authenticate(t *testing.T) {
+	passwordHash, err := hasher.Hash(e.Password)
+	assert.NoError(t, err)
+
+	stored := e
+	stored.Password = passwordHash
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.getByLoginMock = func(ctx context.Context, login string) (Entity, error) {
+			assert.Equal(t, e.Nickname, login)
+
+			return stored, nil
+		}
+
+		service := NewService(WithRepository(mockRepo), WithPasswordHasher(hasher))
+		id, err := service.Authenticate(context.Background(), e.Nickname, e.Password)
+		assert.NoError(t, err)
+		assert.Equal(t, e.Id, id)
+	})
+
+	t.Run("should rehash the password when the hash parameters are changed", func(t *testing.T) {
+		newHasher := NewBcryptHasher(bcrypt.MinCost + 1)
+		rehashed := false
+
+		mockRepo := &mockRepository{}
+		mockRepo.getByLoginMock = func(ctx context.Context, login string) (Entity, error) {
+			return stored, nil
+		}
+		mockRepo.updatePwdMock = func(ctx context.Context, id string, password string) error {
+			rehashed = true
+			assert.Equal(t, e.Id, id)
+			assert.False(t, newHasher.NeedsRehash(password))
+			assert.NoError(t, newHasher.Compare(password, e.Password))
+
+			return nil
+		}
+
+		service := NewService(WithRepository(mockRepo), WithPasswordHasher(newHasher))
+		id, err := service.Authenticate(context.Background(), e.Email, e.Password)
+		assert.NoError(t, err)
+		assert.Equal(t, e.Id, id)
+		assert.True(t, rehashed)
+	})
+
+	t.Run("should return unauthorized when the password is wrong", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.getByLoginMock = func(ctx context.Context, login string) (Entity, error) {
+			return stored, nil
+		}
+
+		service := NewService(WithRepository(mockRepo), WithPasswordHasher(hasher))
+		_, err := service.Authenticate(context.Background(), e.Nickname, "wrong password")
+
+		assert.Error(t, err)
+		assert.IsType(t, apierr.ApiError{}, err)
+		assert.EqualValues(t, invalidCredentialsError(), err)
+	})
+
+	t.Run("should return forbidden when the email is not verified", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.getByLoginMock = func(ctx context.Context, login string) (Entity, error) {
+			return stored, nil
+		}
+
+		service := NewService(WithRepository(mockRepo), WithPasswordHasher(hasher), WithRequireVerifiedEmail(true))
+		_, err := service.Authenticate(context.Background(), e.Nickname, e.Password)
+
+		assert.EqualValues(t, emailNotVerifiedError(), err)
+	})
+
+	t.Run("should return unauthorized when the user does not exist", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.getByLoginMock = func(ctx context.Context, login string) (Entity, error) {
+			return Entity{}, ErrNotFound
+		}
+
+		compared := &comparingHasher{PasswordHasher: hasher}
+		service := NewService(WithRepository(mockRepo), WithPasswordHasher(compared))
+		_, err := service.Authenticate(context.Background(), e.Nickname, e.Password)
+
+		assert.Error(t, err)
+		assert.EqualValues(t, invalidCredentialsError(), err)
+		assert.Equal(t, 1, compared.count, "should compare the password with a dummy hash")
+	})
+}
+
+// comparingHasher counts the passwords compared by the hasher.
+type comparingHasher struct {
+	PasswordHasher
+	count int
+}
+
+func (h *comparingHasher) Compare(hash string, password string) error {
+	h.count++
+	return h.PasswordHasher.Compare(hash, password)
+}
+
+func TestService_Revoked(t *testing.T) {
+	issuedAt := time.Now()
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.revokedMock = func(ctx context.Context, id string, actual time.Time) (bool, error) {
+			assert.Equal(t, e.Id, id)
+			assert.Equal(t, issuedAt, actual)
+
+			return true, nil
+		}
+
+		service := NewService(WithRepository(mockRepo))
+		revoked, err := service.Revoked(context.Background(), e.Id, issuedAt)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+	})
+
+	t.Run("should return the error of the repository", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.revokedMock = func(ctx context.Context, id string, actual time.Time) (bool, error) {
+			return false, fmt.Errorf("mock error")
+		}
+
+		service := NewService(WithRepository(mockRepo))
+		_, err := service.Revoked(context.Background(), e.Id, issuedAt)
+		assert.Error(t, err)
+	})
+}
+
+func TestService_CheckNicknameAvailability(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.takenMock = func(ctx context.Context, nicknames []string) ([]string, error) {
+			assert.Equal(t, e.Nickname, nicknames[0])
+
+			return nil, nil
+		}
+
+		service := NewService(WithRepository(mockRepo))
+
+		actual, err := service.CheckNicknameAvailability(context.Background(), NicknameAvailabilityRequest{Nickname: e.Nickname})
+		assert.NoError(t, err)
+		assert.Equal(t, NicknameAvailabilityResponse{Nickname: e.Nickname, Available: true}, actual)
+	})
+
+	t.Run("should suggest the available nicknames when the nickname is taken", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.takenMock = func(ctx context.Context, nicknames []string) ([]string, error) {
+			return []string{e.Nickname, e.Nickname + "1", e.Nickname + "3"}, nil
+		}
+
+		service := NewService(WithRepository(mockRepo))
+
+		actual, err := service.CheckNicknameAvailability(context.Background(), NicknameAvailabilityRequest{Nickname: e.Nickname})
+		assert.NoError(t, err)
+		assert.False(t, actual.Available)
+		assert.Equal(t, []string{e.Nickname + "2", e.Nickname + "4", e.Nickname + "5"}, actual.Suggestions)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		expectedErr := fmt.Errorf("mock error")
+
+		mockRepo := &mockRepository{}
+		mockRepo.takenMock = func(ctx context.Context, nicknames []string) ([]string, error) {
+			return nil, expectedErr
+		}
+
+		service := NewService(WithRepository(mockRepo))
+
+		_, err := service.CheckNicknameAvailability(context.Background(), NicknameAvailabilityRequest{Nickname: e.Nickname})
+		assert.ErrorIs(t, err, expectedErr)
 	})
 }
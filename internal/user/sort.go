@@ -0,0 +1,108 @@
+package user
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SortField is a column the listed users are ordered by.
+type SortField struct {
+	Column     string
+	Descending bool
+}
+
+// unsortableColumns are the columns of the entity the users cannot be ordered by,
+// deleted_at and email_verified_at are nullable so they cannot be compared by the cursors.
+var unsortableColumns = map[string]bool{
+	"password":          true,
+	"deleted_at":        true,
+	"email_verified_at": true,
+}
+
+// sortableColumns maps the db tags of the entity fields the users can be ordered by to the field indexes.
+var sortableColumns = func() map[string]int {
+	columns := map[string]int{}
+
+	t := reflect.TypeOf(Entity{})
+	for i := 0; i < t.NumField(); i++ {
+		column := t.Field(i).Tag.Get("db")
+		if column != "" && !unsortableColumns[column] {
+			columns[column] = i
+		}
+	}
+
+	return columns
+}()
+
+var defaultSort = []SortField{{Column: "created_at"}}
+
+// parseSort parses the comma separated columns of the sort query parameter,
+// the columns prefixed with - are sorted in descending order.
+func parseSort(value string) ([]SortField, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var fields []SortField
+	seen := map[string]bool{}
+	for _, column := range strings.Split(value, ",") {
+		field := SortField{Column: strings.TrimPrefix(column, "-"), Descending: strings.HasPrefix(column, "-")}
+
+		if _, ok := sortableColumns[field.Column]; !ok {
+			return nil, invalidSortError(fmt.Sprintf("users cannot be sorted by %s", field.Column))
+		}
+		if seen[field.Column] {
+			return nil, invalidSortError(fmt.Sprintf("users are sorted by %s more than once", field.Column))
+		}
+
+		seen[field.Column] = true
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// normalizeSort falls back to the default order and appends id unless the users are already ordered by it,
+// so that the users having the same values are always in the same order.
+func normalizeSort(fields []SortField) []SortField {
+	if len(fields) == 0 {
+		fields = defaultSort
+	}
+
+	for _, field := range fields {
+		if field.Column == "id" {
+			return fields
+		}
+	}
+
+	normalized := make([]SortField, len(fields), len(fields)+1)
+	copy(normalized, fields)
+	return append(normalized, SortField{Column: "id"})
+}
+
+// sortSpec formats the fields back to the representation of the sort query parameter.
+func sortSpec(fields []SortField) string {
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		if field.Descending {
+			columns[i] = "-" + field.Column
+		} else {
+			columns[i] = field.Column
+		}
+	}
+
+	return strings.Join(columns, ",")
+}
+
+// sortValues returns the values of the sorted columns of the entity.
+func sortValues(entity Entity, fields []SortField) []interface{} {
+	v := reflect.ValueOf(entity)
+
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		values[i] = v.Field(sortableColumns[field.Column]).Interface()
+	}
+
+	return values
+}
@@ -0,0 +1,42 @@
+package user
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		actual, err := parseSort("-updated_at,nickname")
+		assert.NoError(t, err)
+		assert.Equal(t, []SortField{{Column: "updated_at", Descending: true}, {Column: "nickname"}}, actual)
+	})
+
+	t.Run("should reject the invalid columns", func(t *testing.T) {
+		for _, value := range []string{"password", "deleted_at", "unknown", "nickname,-nickname", ",nickname"} {
+			_, err := parseSort(value)
+			assert.Error(t, err, value)
+		}
+	})
+}
+
+func TestNormalizeSort(t *testing.T) {
+	t.Run("should sort by created_at by default", func(t *testing.T) {
+		assert.Equal(t, []SortField{{Column: "created_at"}, {Column: "id"}}, normalizeSort(nil))
+	})
+
+	t.Run("should break the ties by id", func(t *testing.T) {
+		actual := normalizeSort([]SortField{{Column: "country", Descending: true}})
+		assert.Equal(t, []SortField{{Column: "country", Descending: true}, {Column: "id"}}, actual)
+	})
+
+	t.Run("should keep the order by id", func(t *testing.T) {
+		sort := []SortField{{Column: "id", Descending: true}, {Column: "nickname"}}
+		assert.Equal(t, sort, normalizeSort(sort))
+	})
+}
+
+func TestSortValues(t *testing.T) {
+	sort := []SortField{{Column: "nickname"}, {Column: "version"}, {Column: "id"}}
+	assert.Equal(t, []interface{}{e.Nickname, e.Version, e.Id}, sortValues(e, sort))
+}
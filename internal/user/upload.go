@@ -0,0 +1,77 @@
+package user
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	mimeCSV    = "text/csv"
+	mimeNDJSON = "application/x-ndjson"
+)
+
+// importFormats are the import formats by their media types and file extensions.
+var importFormats = map[string]string{
+	mimeCSV:              ImportFormatCSV,
+	"application/csv":    ImportFormatCSV,
+	mimeNDJSON:           ImportFormatNDJSON,
+	"application/ndjson": ImportFormatNDJSON,
+	"application/jsonl":  ImportFormatNDJSON,
+	".csv":               ImportFormatCSV,
+	".ndjson":            ImportFormatNDJSON,
+	".jsonl":             ImportFormatNDJSON,
+}
+
+// importUpload returns the upload of the request without reading it, so that it is streamed.
+// The upload is either the body or the file part of a multipart form, in which case its format
+// is resolved by the content type of the part or the extension of the file.
+func importUpload(r *http.Request) (ImportUsersRequest, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ImportUsersRequest{}, unsupportedImportFormatError(r.Header.Get("Content-Type"))
+	}
+
+	if mediaType != "multipart/form-data" {
+		return ImportUsersRequest{Format: importFormat(mediaType, ""), File: r.Body}, nil
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return ImportUsersRequest{}, invalidImportError(err.Error())
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return ImportUsersRequest{}, invalidImportError("the file part is missing")
+		}
+		if err != nil {
+			return ImportUsersRequest{}, invalidImportError(err.Error())
+		}
+
+		if part.FormName() != "file" {
+			continue
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		return ImportUsersRequest{Format: importFormat(mediaType, part.FileName()), File: part}, nil
+	}
+}
+
+// importFormat resolves the format by the media type, or by the extension of the file
+// when the media type is not a known one.
+func importFormat(mediaType string, fileName string) string {
+	if format, ok := importFormats[mediaType]; ok {
+		return format
+	}
+
+	if format, ok := importFormats[strings.ToLower(filepath.Ext(fileName))]; ok {
+		return format
+	}
+
+	return mediaType
+}
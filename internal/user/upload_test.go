@@ -0,0 +1,60 @@
+package user
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestImportUpload(t *testing.T) {
+	t.Run("body", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodPost, "/users:import", strings.NewReader("{}"))
+		assert.NoError(t, err)
+		request.Header.Set("Content-Type", "application/x-ndjson; charset=utf-8")
+
+		actual, err := importUpload(request)
+		assert.NoError(t, err)
+		assert.Equal(t, ImportFormatNDJSON, actual.Format)
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		body := &bytes.Buffer{}
+		form := multipart.NewWriter(body)
+		assert.NoError(t, form.WriteField("description", "users"))
+		part, err := form.CreateFormFile("file", "users.CSV")
+		assert.NoError(t, err)
+		_, err = part.Write([]byte("first_name\n"))
+		assert.NoError(t, err)
+		assert.NoError(t, form.Close())
+
+		request, err := http.NewRequest(http.MethodPost, "/users:import", body)
+		assert.NoError(t, err)
+		request.Header.Set("Content-Type", form.FormDataContentType())
+
+		actual, err := importUpload(request)
+		assert.NoError(t, err)
+		assert.Equal(t, ImportFormatCSV, actual.Format, "should resolve the format by the extension of the file")
+
+		file, err := io.ReadAll(actual.File)
+		assert.NoError(t, err)
+		assert.Equal(t, "first_name\n", string(file))
+	})
+
+	t.Run("should reject the multipart without a file", func(t *testing.T) {
+		body := &bytes.Buffer{}
+		form := multipart.NewWriter(body)
+		assert.NoError(t, form.WriteField("description", "users"))
+		assert.NoError(t, form.Close())
+
+		request, err := http.NewRequest(http.MethodPost, "/users:import", body)
+		assert.NoError(t, err)
+		request.Header.Set("Content-Type", form.FormDataContentType())
+
+		_, err = importUpload(request)
+		assert.Error(t, err)
+	})
+}
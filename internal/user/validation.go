@@ -0,0 +1,159 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"faceit-backend-test/internal/apierr"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// nicknamePattern allows the letters, the digits, the dots, the underscores and the hyphens in the nicknames,
+// which start with a letter or a digit.
+var nicknamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// maxPasswordBytes is the longest password bcrypt hashes, the passwords are limited in bytes rather than in characters.
+const maxPasswordBytes = 72
+
+// validate validates the requests of the users. It is not the validator of gin, so that its rules and the names of
+// the fields in its violations, which are the names of the fields in the requests, do not leak into the other packages.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.SetTagName("binding")
+
+	_ = v.RegisterValidation("nickname", func(fl validator.FieldLevel) bool {
+		return nicknamePattern.MatchString(fl.Field().String())
+	})
+	_ = v.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+		return len(fl.Field().String()) <= maxPasswordBytes
+	})
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form", "uri"} {
+			name := strings.Split(field.Tag.Get(tag), ",")[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+
+		return field.Name
+	})
+
+	return v
+}
+
+// FieldViolation is a validation rule violated by a field of the request.
+// @Description validation rule violated by a field of the request
+type FieldViolation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// violationMessages are the messages of the rules, %s is replaced by the parameter of the rule.
+var violationMessages = map[string]string{
+	"required":         "is required",
+	"required_unless":  "is required",
+	"min":              "must be at least %s",
+	"max":              "must be at most %s",
+	"email":            "must be a valid email address",
+	"iso3166_1_alpha2": "must be an ISO 3166-1 alpha-2 country code",
+	"nickname":         "must contain only letters, digits, dots, underscores and hyphens, and start with a letter or a digit",
+	"password":         fmt.Sprintf("must be at most %d bytes long", maxPasswordBytes),
+	"oneof":            "must be one of %s",
+	"uuid":             "must be a uuid",
+}
+
+// lengthUnits are the units of the lengths checked by the min and the max rules, the numbers have no unit.
+var lengthUnits = map[reflect.Kind]string{
+	reflect.String: " characters long",
+	reflect.Slice:  " items long",
+	reflect.Map:    " items long",
+}
+
+// violations returns the violations of the validation errors, the fields are named by their paths in the request.
+func violations(errs validator.ValidationErrors) []FieldViolation {
+	result := make([]FieldViolation, len(errs))
+	for i, fe := range errs {
+		field := fe.Namespace()
+		if dot := strings.Index(field, "."); dot >= 0 {
+			field = field[dot+1:]
+		}
+
+		message, ok := violationMessages[fe.Tag()]
+		if !ok {
+			message = "must satisfy " + fe.Tag()
+		}
+		if strings.Contains(message, "%s") {
+			message = fmt.Sprintf(message, fe.Param())
+		}
+		if fe.Tag() == "min" || fe.Tag() == "max" {
+			message += lengthUnits[fe.Kind()]
+		}
+
+		result[i] = FieldViolation{Field: field, Rule: fe.Tag(), Message: message}
+	}
+
+	return result
+}
+
+// bindingError returns the error of a request body that cannot be bound, the violations of the validation rules
+// are reported together by validationError, the other errors are bad requests.
+func bindingError(err error) error {
+	var errs validator.ValidationErrors
+	if errors.As(err, &errs) {
+		return validationError(violations(errs))
+	}
+
+	return apierr.BadRequest(err.Error())
+}
+
+// violationsError returns the error listing the violations of the validation errors in a line.
+func violationsError(err error) error {
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return err
+	}
+
+	messages := make([]string, len(errs))
+	for i, violation := range violations(errs) {
+		messages[i] = violation.Field + " " + violation.Message
+	}
+
+	return errors.New(strings.Join(messages, "; "))
+}
+
+// bindJSON binds the JSON body like ShouldBindJSON does, except that the request is normalized before it is validated.
+func bindJSON(ctx *gin.Context, req normalizer) error {
+	if ctx.Request == nil || ctx.Request.Body == nil {
+		return errors.New("invalid request")
+	}
+
+	err := json.NewDecoder(ctx.Request.Body).Decode(req)
+	if err != nil {
+		return err
+	}
+
+	req.normalize()
+	return validate.Struct(req)
+}
+
+// bindQuery binds the query like ShouldBindQuery does, except that the request is normalized before it is validated.
+func bindQuery(ctx *gin.Context, req normalizer) error {
+	err := binding.MapFormWithTag(req, ctx.Request.URL.Query(), "form")
+	if err != nil {
+		return err
+	}
+
+	req.normalize()
+	return validate.Struct(req)
+}
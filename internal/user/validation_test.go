@@ -0,0 +1,152 @@
+package user
+
+import (
+	"errors"
+	"faceit-backend-test/internal/apierr"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestValidation_Nickname(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		for _, nickname := range []string{"bobby.firmino", "vvd", "mo_salah", "sadio-10", "10sadio"} {
+			request := CreateUserRequest{
+				FirstName: e.FirstName,
+				LastName:  e.LastName,
+				Nickname:  nickname,
+				Password:  e.Password,
+				Email:     e.Email,
+				Country:   e.Country,
+			}
+
+			assert.NoError(t, validate.Struct(&request), nickname)
+		}
+	})
+
+	t.Run("should reject the invalid nicknames", func(t *testing.T) {
+		for _, nickname := range []string{"bo", ".bobby", "bobby firmino", "bobby@firmino", "bóbby", strings.Repeat("b", 31)} {
+			request := CreateUserRequest{
+				FirstName: e.FirstName,
+				LastName:  e.LastName,
+				Nickname:  nickname,
+				Password:  e.Password,
+				Email:     e.Email,
+				Country:   e.Country,
+			}
+
+			assert.Error(t, validate.Struct(&request), nickname)
+		}
+	})
+}
+
+func TestValidation_Password(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		request := ConfirmPasswordResetRequest{Token: "token", Password: strings.Repeat("é", maxPasswordBytes/2)}
+
+		assert.NoError(t, validate.Struct(&request))
+	})
+
+	t.Run("should reject the passwords longer than 72 bytes", func(t *testing.T) {
+		password := strings.Repeat("é", maxPasswordBytes/2+1)
+		for _, request := range []interface{}{
+			&ConfirmPasswordResetRequest{Token: "token", Password: password},
+			&PatchUserRequest{Password: &password},
+			&CreateUserRequest{
+				FirstName: e.FirstName,
+				LastName:  e.LastName,
+				Nickname:  e.Nickname,
+				Password:  password,
+				Email:     e.Email,
+				Country:   e.Country,
+			},
+		} {
+			err := bindingError(validate.Struct(request))
+
+			var apiErr apierr.ApiError
+			assert.True(t, errors.As(err, &apiErr))
+			assert.Equal(t, []FieldViolation{
+				{Field: "password", Rule: "password", Message: "must be at most 72 bytes long"},
+			}, apiErr.Data)
+		}
+	})
+}
+
+func TestValidation_PatchUserRequest(t *testing.T) {
+	t.Run("should validate only the provided fields", func(t *testing.T) {
+		nickname := "firmino"
+		request := PatchUserRequest{Nickname: &nickname}
+
+		assert.NoError(t, validate.Struct(&request))
+	})
+
+	t.Run("should reject the empty fields", func(t *testing.T) {
+		empty := ""
+		request := PatchUserRequest{FirstName: &empty}
+
+		assert.Error(t, validate.Struct(&request))
+	})
+}
+
+func TestBindingError(t *testing.T) {
+	t.Run("should report the violations by the paths of the fields", func(t *testing.T) {
+		request := BatchUsersRequest{Operations: []BatchOperation{
+			{Op: BatchOpDelete, Id: e.Id},
+			{Op: BatchOpCreate, User: &CreateUserRequest{
+				FirstName: e.FirstName,
+				LastName:  e.LastName,
+				Nickname:  e.Nickname,
+				Password:  e.Password,
+				Email:     "robertofirmino",
+				Country:   "UK",
+			}},
+		}}
+
+		err := bindingError(validate.Struct(&request))
+
+		var apiErr apierr.ApiError
+		assert.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+		assert.Equal(t, []FieldViolation{
+			{Field: "operations[1].user.email", Rule: "email", Message: "must be a valid email address"},
+			{Field: "operations[1].user.country", Rule: "iso3166_1_alpha2", Message: "must be an ISO 3166-1 alpha-2 country code"},
+		}, apiErr.Data)
+	})
+
+	t.Run("should not rename the fields validated by gin", func(t *testing.T) {
+		request := struct {
+			Name string `json:"name" binding:"required"`
+		}{}
+
+		var errs validator.ValidationErrors
+		assert.True(t, errors.As(binding.Validator.ValidateStruct(&request), &errs))
+		assert.Equal(t, "Name", errs[0].Field())
+	})
+
+	t.Run("should return bad request when the body is not valid", func(t *testing.T) {
+		err := bindingError(errors.New("unexpected EOF"))
+
+		var apiErr apierr.ApiError
+		assert.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	})
+}
+
+func TestViolationsError(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		request := CreateUserRequest{
+			FirstName: e.FirstName,
+			Nickname:  e.Nickname,
+			Password:  e.Password,
+			Email:     e.Email,
+			Country:   "United Kingdom",
+		}
+
+		err := violationsError(validate.Struct(&request))
+
+		assert.EqualError(t, err, "last_name is required; country must be an ISO 3166-1 alpha-2 country code")
+	})
+}
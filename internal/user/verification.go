@@ -0,0 +1,188 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"faceit-backend-test/internal/mail"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"net/url"
+	"time"
+)
+
+const (
+	defaultVerificationTTL = 24 * time.Hour
+	// verificationAudience is the audience of the verification tokens,
+	// so that no other token signed by the same secret is accepted.
+	verificationAudience = "email_verification"
+)
+
+// verificationClaims are the claims of an email verification token, the subject is the id of the user.
+// The token is bound to the email, so it cannot verify the email the user has changed to.
+type verificationClaims struct {
+	jwt.RegisteredClaims
+	Email string `json:"email"`
+}
+
+// verificationToken is a signed email verification token. It is single use since the user is verified
+// only if the email is not verified yet.
+type verificationToken struct {
+	Value     string
+	ExpiresAt time.Time
+}
+
+func (s *service) issueVerificationToken(id string, email string) (verificationToken, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.verificationTTL)
+
+	claims := verificationClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   id,
+			Audience:  jwt.ClaimStrings{verificationAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		Email: email,
+	}
+
+	value, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.verificationSecret)
+	if err != nil {
+		return verificationToken{}, err
+	}
+
+	return verificationToken{Value: value, ExpiresAt: expiresAt}, nil
+}
+
+// parseVerificationToken checks the signature, the audience and the expiration of the token and returns its claims.
+func (s *service) parseVerificationToken(token string) (verificationClaims, error) {
+	var claims verificationClaims
+
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		// the algorithm is pinned, otherwise a token signed with another algorithm might be accepted.
+		if token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Method.Alg())
+		}
+
+		return s.verificationSecret, nil
+	})
+	if err != nil {
+		return verificationClaims{}, err
+	}
+
+	if !claims.VerifyAudience(verificationAudience, true) {
+		return verificationClaims{}, fmt.Errorf("invalid audience: %v", claims.Audience)
+	}
+
+	return claims, nil
+}
+
+// sendVerification mails the user a link to verify its email.
+func (s *service) sendVerification(ctx context.Context, user User) (verificationToken, error) {
+	if s.mailer == nil {
+		return verificationToken{}, errors.New("no mail sender")
+	}
+
+	token, err := s.issueVerificationToken(user.Id, user.Email)
+	if err != nil {
+		return verificationToken{}, err
+	}
+
+	link, err := tokenLink(s.verificationURL, token.Value)
+	if err != nil {
+		return verificationToken{}, err
+	}
+
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hi %s,\n\nplease verify your email by opening the link below before %s.\n\n%s\n",
+			user.FirstName, token.ExpiresAt.UTC().Format(time.RFC1123), link),
+	})
+	if err != nil {
+		return verificationToken{}, err
+	}
+
+	return token, nil
+}
+
+// tokenLink returns the link to the page at the url with the token in the token query parameter.
+func tokenLink(pageURL string, token string) (string, error) {
+	link, err := url.Parse(pageURL)
+	if err != nil {
+		return "", err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
+
+// SendVerification mails the user another link to verify its email, the links sent before are still valid until they expire.
+func (s *service) SendVerification(ctx context.Context, request SendVerificationRequest) (VerificationResponse, error) {
+	resp, err := s.GetById(ctx, GetUserByIdRequest{Id: request.Id})
+	if err != nil {
+		return VerificationResponse{}, err
+	}
+
+	if resp.EmailVerifiedAt != nil {
+		return VerificationResponse{}, emailAlreadyVerifiedError(request.Id)
+	}
+
+	token, err := s.sendVerification(ctx, resp.User)
+	if err != nil {
+		return VerificationResponse{}, verificationMailError(err)
+	}
+
+	return VerificationResponse{Id: resp.Id, Email: resp.Email, ExpiresAt: token.ExpiresAt}, nil
+}
+
+// VerifyEmail verifies the email of the user the token is issued for,
+// the token is rejected when the user has changed its email since.
+func (s *service) VerifyEmail(ctx context.Context, request VerifyEmailRequest) (VerifyEmailResponse, error) {
+	claims, err := s.parseVerificationToken(request.Token)
+	if err != nil {
+		return VerifyEmailResponse{}, invalidVerificationTokenError()
+	}
+
+	change, err := s.audited(ctx, AuditActionEmailVerified, func(repo Repository) (EntityChange, error) {
+		return repo.VerifyEmail(ctx, claims.Subject, claims.Email)
+	})
+	if errors.Is(err, ErrNotFound) {
+		return VerifyEmailResponse{}, s.verificationError(ctx, claims)
+	}
+	if err != nil {
+		return VerifyEmailResponse{}, repositoryError(err)
+	}
+
+	verifiedUser := User{
+		Id:              change.Id,
+		FirstName:       change.FirstName,
+		LastName:        change.LastName,
+		Nickname:        change.Nickname,
+		Email:           change.Email,
+		Country:         change.Country,
+		Version:         change.Version,
+		CreatedAt:       change.CreatedAt,
+		UpdatedAt:       change.UpdatedAt,
+		EmailVerifiedAt: change.EmailVerifiedAt,
+	}
+	s.broker.Publish(UserEmailVerifiedTopic, verifiedUser)
+
+	return VerifyEmailResponse{User: verifiedUser}, nil
+}
+
+// verificationError resolves why the email of the user is not verified,
+// either the user does not exist, its email is changed or it is already verified.
+func (s *service) verificationError(ctx context.Context, claims verificationClaims) error {
+	resp, err := s.GetById(ctx, GetUserByIdRequest{Id: claims.Subject})
+	if err != nil {
+		return err
+	}
+
+	if resp.Email != claims.Email {
+		return invalidVerificationTokenError()
+	}
+
+	return emailAlreadyVerifiedError(claims.Subject)
+}
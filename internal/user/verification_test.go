@@ -0,0 +1,202 @@
+package user
+
+import (
+	"context"
+	"faceit-backend-test/internal/mail"
+	"faceit-backend-test/internal/pubsub"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+var verificationSecret = []byte("secret")
+
+func TestService_VerificationToken(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		service := NewService(WithVerification(verificationSecret, time.Hour, "http://localhost/verify"))
+
+		token, err := service.issueVerificationToken(e.Id, e.Email)
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), token.ExpiresAt, time.Minute)
+
+		claims, err := service.parseVerificationToken(token.Value)
+		assert.NoError(t, err)
+		assert.Equal(t, e.Id, claims.Subject)
+		assert.Equal(t, e.Email, claims.Email)
+	})
+
+	t.Run("should reject the expired tokens", func(t *testing.T) {
+		service := NewService(WithVerification(verificationSecret, -time.Minute, "http://localhost/verify"))
+
+		token, err := service.issueVerificationToken(e.Id, e.Email)
+		assert.NoError(t, err)
+
+		_, err = service.parseVerificationToken(token.Value)
+		assert.Error(t, err)
+	})
+
+	t.Run("should reject the tokens signed by another secret", func(t *testing.T) {
+		issuer := NewService(WithVerification([]byte("another secret"), time.Hour, "http://localhost/verify"))
+		service := NewService(WithVerification(verificationSecret, time.Hour, "http://localhost/verify"))
+
+		token, err := issuer.issueVerificationToken(e.Id, e.Email)
+		assert.NoError(t, err)
+
+		_, err = service.parseVerificationToken(token.Value)
+		assert.Error(t, err)
+	})
+}
+
+func TestService_SendVerification(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.getByIdMock = func(ctx context.Context, id string) (Entity, error) {
+			return e, nil
+		}
+
+		var sent mail.Message
+		mockMailer := &mail.MockSender{}
+		mockMailer.SendMock = func(ctx context.Context, message mail.Message) error {
+			sent = message
+			return nil
+		}
+
+		service := NewService(WithRepository(mockRepo), WithMailSender(mockMailer),
+			WithVerification(verificationSecret, time.Hour, "http://localhost/verify"))
+
+		actual, err := service.SendVerification(context.Background(), SendVerificationRequest{Id: e.Id})
+		assert.NoError(t, err)
+		assert.Equal(t, e.Id, actual.Id)
+		assert.Equal(t, e.Email, actual.Email)
+		assert.Equal(t, e.Email, sent.To)
+
+		link := sent.Body[strings.Index(sent.Body, "http://localhost/verify?token="):]
+		u, err := url.Parse(strings.TrimSpace(link))
+		assert.NoError(t, err)
+
+		claims, err := service.parseVerificationToken(u.Query().Get("token"))
+		assert.NoError(t, err)
+		assert.Equal(t, e.Id, claims.Subject)
+	})
+
+	t.Run("should return conflict when the email is already verified", func(t *testing.T) {
+		verifiedAt := time.Now()
+		verified := e
+		verified.EmailVerifiedAt = &verifiedAt
+
+		mockRepo := &mockRepository{}
+		mockRepo.getByIdMock = func(ctx context.Context, id string) (Entity, error) {
+			return verified, nil
+		}
+
+		service := NewService(WithRepository(mockRepo))
+
+		_, err := service.SendVerification(context.Background(), SendVerificationRequest{Id: e.Id})
+		assert.Equal(t, emailAlreadyVerifiedError(e.Id), err)
+	})
+
+	t.Run("should return service unavailable when the mail cannot be sent", func(t *testing.T) {
+		expectedErr := fmt.Errorf("mock error")
+
+		mockRepo := &mockRepository{}
+		mockRepo.getByIdMock = func(ctx context.Context, id string) (Entity, error) {
+			return e, nil
+		}
+
+		mockMailer := &mail.MockSender{}
+		mockMailer.SendMock = func(ctx context.Context, message mail.Message) error {
+			return expectedErr
+		}
+
+		service := NewService(WithRepository(mockRepo), WithMailSender(mockMailer),
+			WithVerification(verificationSecret, time.Hour, "http://localhost/verify"))
+
+		_, err := service.SendVerification(context.Background(), SendVerificationRequest{Id: e.Id})
+		assert.Equal(t, verificationMailError(expectedErr), err)
+		assert.ErrorIs(t, err, expectedErr)
+	})
+}
+
+func TestService_VerifyEmail(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		verifiedAt := time.Now()
+
+		mockRepo := &mockRepository{}
+		mockRepo.verifyEmailMock = func(ctx context.Context, id string, email string) (EntityChange, error) {
+			assert.Equal(t, e.Id, id)
+			assert.Equal(t, e.Email, email)
+
+			change := EntityChange{Entity: e}
+			change.EmailVerifiedAt = &verifiedAt
+			return change, nil
+		}
+
+		mockBroker := &pubsub.MockBroker{}
+		mockBroker.PublishMock = func(s string, i interface{}) {
+			assert.Equal(t, UserEmailVerifiedTopic, s)
+			assert.Equal(t, &verifiedAt, i.(User).EmailVerifiedAt)
+		}
+
+		service := NewService(WithRepository(mockRepo), WithBroker(mockBroker),
+			WithVerification(verificationSecret, time.Hour, "http://localhost/verify"))
+
+		token, err := service.issueVerificationToken(e.Id, e.Email)
+		assert.NoError(t, err)
+
+		actual, err := service.VerifyEmail(context.Background(), VerifyEmailRequest{Token: token.Value})
+		assert.NoError(t, err)
+		assert.Equal(t, e.Id, actual.Id)
+		assert.Equal(t, &verifiedAt, actual.EmailVerifiedAt)
+	})
+
+	t.Run("should reject the invalid tokens", func(t *testing.T) {
+		service := NewService(WithVerification(verificationSecret, time.Hour, "http://localhost/verify"))
+
+		_, err := service.VerifyEmail(context.Background(), VerifyEmailRequest{Token: "token"})
+		assert.Equal(t, invalidVerificationTokenError(), err)
+	})
+
+	t.Run("should reject the token when the email is changed", func(t *testing.T) {
+		changed := e
+		changed.Email = "bobby@lfc.co.uk"
+
+		mockRepo := &mockRepository{}
+		mockRepo.verifyEmailMock = func(ctx context.Context, id string, email string) (EntityChange, error) {
+			return EntityChange{}, ErrNotFound
+		}
+		mockRepo.getByIdMock = func(ctx context.Context, id string) (Entity, error) {
+			return changed, nil
+		}
+
+		service := NewService(WithRepository(mockRepo), WithVerification(verificationSecret, time.Hour, "http://localhost/verify"))
+
+		token, err := service.issueVerificationToken(e.Id, e.Email)
+		assert.NoError(t, err)
+
+		_, err = service.VerifyEmail(context.Background(), VerifyEmailRequest{Token: token.Value})
+		assert.Equal(t, invalidVerificationTokenError(), err)
+	})
+
+	t.Run("should return conflict when the email is already verified", func(t *testing.T) {
+		mockRepo := &mockRepository{}
+		mockRepo.verifyEmailMock = func(ctx context.Context, id string, email string) (EntityChange, error) {
+			return EntityChange{}, ErrNotFound
+		}
+		mockRepo.getByIdMock = func(ctx context.Context, id string) (Entity, error) {
+			return e, nil
+		}
+
+		service := NewService(WithRepository(mockRepo), WithVerification(verificationSecret, time.Hour, "http://localhost/verify"))
+
+		token, err := service.issueVerificationToken(e.Id, e.Email)
+		assert.NoError(t, err)
+
+		_, err = service.VerifyEmail(context.Background(), VerifyEmailRequest{Token: token.Value})
+		assert.Equal(t, emailAlreadyVerifiedError(e.Id), err)
+		assert.Equal(t, http.StatusConflict, emailAlreadyVerifiedError(e.Id).StatusCode)
+	})
+}
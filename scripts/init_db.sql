@@ -1,3 +1,6 @@
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
 -- Table: public.users
 
 -- DROP TABLE public.users;
@@ -8,13 +11,17 @@ CREATE TABLE IF NOT EXISTS public.users
     first_name character varying(50) COLLATE pg_catalog."default" NOT NULL,
     last_name character varying(50) COLLATE pg_catalog."default" NOT NULL,
     nickname character varying(30) COLLATE pg_catalog."default" NOT NULL,
-    password character varying(50) COLLATE pg_catalog."default" NOT NULL,
+    password character varying(255) COLLATE pg_catalog."default" NOT NULL,
     email character varying(100) COLLATE pg_catalog."default" NOT NULL,
     country character varying(10) COLLATE pg_catalog."default" NOT NULL,
+    version integer NOT NULL DEFAULT 1,
     created_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    CONSTRAINT users_pkey PRIMARY KEY (id),
-    CONSTRAINT users_nickname_key UNIQUE (nickname)
+    deleted_at timestamp without time zone,
+    email_verified_at timestamp without time zone,
+    erased_at timestamp without time zone,
+    password_changed_at timestamp without time zone,
+    CONSTRAINT users_pkey PRIMARY KEY (id)
     )
 
     TABLESPACE pg_default;
@@ -24,6 +31,30 @@ ALTER TABLE public.users
 
 GRANT ALL ON TABLE public.users TO faceit;
 
+-- Index: users_nickname_key and users_email_key, the nicknames and the emails are unique regardless of their case.
+-- The service stores their canonical forms, the indexes keep them unique even if they are written otherwise.
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_nickname_key
+    ON public.users USING btree (lower(nickname));
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key
+    ON public.users USING btree (lower(email));
+
+-- Index: users_created_at_id_idx, used by the keyset pagination of the users
+
+CREATE INDEX IF NOT EXISTS users_created_at_id_idx
+    ON public.users USING btree (created_at ASC, id ASC);
+
+-- Index: users_search_idx, used by the full-text search of the users
+
+CREATE INDEX IF NOT EXISTS users_search_idx
+    ON public.users USING gin (to_tsvector('simple', (first_name || ' ' || last_name || ' ' || nickname || ' ' || email)));
+
+-- Index: users_search_trgm_idx, used by the similarity search of the users
+
+CREATE INDEX IF NOT EXISTS users_search_trgm_idx
+    ON public.users USING gin ((first_name || ' ' || last_name || ' ' || nickname || ' ' || email) gin_trgm_ops);
+
 -- FUNCTION: public.update_updated_at()
 
 -- DROP FUNCTION public.update_updated_at();
@@ -55,6 +86,124 @@ CREATE TRIGGER update_updated_at
     FOR EACH ROW
     EXECUTE FUNCTION public.update_updated_at();
 
+-- Table: public.password_reset_tokens, only the SHA-256 hashes of the tokens are stored
+
+-- DROP TABLE public.password_reset_tokens;
+
+CREATE TABLE IF NOT EXISTS public.password_reset_tokens
+(
+    id uuid NOT NULL DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    token_hash character(64) COLLATE pg_catalog."default" NOT NULL,
+    expires_at timestamp without time zone NOT NULL,
+    used_at timestamp without time zone,
+    created_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT password_reset_tokens_pkey PRIMARY KEY (id),
+    CONSTRAINT password_reset_tokens_token_hash_key UNIQUE (token_hash),
+    CONSTRAINT password_reset_tokens_user_id_fkey FOREIGN KEY (user_id)
+        REFERENCES public.users (id) ON DELETE CASCADE
+    )
+
+    TABLESPACE pg_default;
+
+ALTER TABLE public.password_reset_tokens
+    OWNER to faceit;
+
+GRANT ALL ON TABLE public.password_reset_tokens TO faceit;
+
+-- Index: password_reset_tokens_user_id_idx, used for invalidating the outstanding tokens of a user
+
+CREATE INDEX IF NOT EXISTS password_reset_tokens_user_id_idx
+    ON public.password_reset_tokens USING btree (user_id);
+
+-- Table: public.user_audit, the change history of the users. It has no foreign key, the history of a user
+-- is deleted together with it by the purge, and all but the erasure itself is deleted by the erasure.
+
+-- DROP TABLE public.user_audit;
+
+CREATE TABLE IF NOT EXISTS public.user_audit
+(
+    id bigserial NOT NULL,
+    user_id uuid NOT NULL,
+    action character varying(20) COLLATE pg_catalog."default" NOT NULL,
+    actor character varying(100) COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+    request_id character varying(100) COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+    changes jsonb NOT NULL,
+    snapshot jsonb NOT NULL,
+    created_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT user_audit_pkey PRIMARY KEY (id)
+    )
+
+    TABLESPACE pg_default;
+
+ALTER TABLE public.user_audit
+    OWNER to faceit;
+
+GRANT ALL ON TABLE public.user_audit TO faceit;
+
+-- Index: user_audit_user_id_id_idx, used for listing the history of a user and reconstructing it as of a time
+
+CREATE INDEX IF NOT EXISTS user_audit_user_id_id_idx
+    ON public.user_audit USING btree (user_id, id);
+
+-- FUNCTION: public.reject_user_audit_update(), the audit entries are only appended
+
+-- DROP FUNCTION public.reject_user_audit_update();
+
+CREATE FUNCTION public.reject_user_audit_update()
+    RETURNS trigger
+    LANGUAGE 'plpgsql'
+    COST 100
+    VOLATILE NOT LEAKPROOF
+AS $BODY$
+BEGIN
+    RAISE EXCEPTION 'the audit entries cannot be updated';
+END;
+$BODY$;
+
+ALTER FUNCTION public.reject_user_audit_update()
+    OWNER TO faceit;
+
+-- Trigger: reject_user_audit_update
+
+-- DROP TRIGGER reject_user_audit_update ON public.user_audit;
+
+CREATE TRIGGER reject_user_audit_update
+    BEFORE UPDATE
+    ON public.user_audit
+    FOR EACH ROW
+    EXECUTE FUNCTION public.reject_user_audit_update();
+
+-- Table: public.idempotency_keys, the first responses to the requests made with an idempotency key.
+-- The status code, the header and the body are null while the request is in progress.
+
+-- DROP TABLE public.idempotency_keys;
+
+CREATE TABLE IF NOT EXISTS public.idempotency_keys
+(
+    client character varying(255) COLLATE pg_catalog."default" NOT NULL,
+    key character varying(255) COLLATE pg_catalog."default" NOT NULL,
+    fingerprint character(64) COLLATE pg_catalog."default" NOT NULL,
+    status_code integer,
+    header jsonb,
+    body bytea,
+    created_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at timestamp without time zone NOT NULL,
+    CONSTRAINT idempotency_keys_pkey PRIMARY KEY (client, key)
+    )
+
+    TABLESPACE pg_default;
+
+ALTER TABLE public.idempotency_keys
+    OWNER to faceit;
+
+GRANT ALL ON TABLE public.idempotency_keys TO faceit;
+
+-- Index: idempotency_keys_expires_at_idx, used for purging the expired keys
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx
+    ON public.idempotency_keys USING btree (expires_at);
+
 --
 -- PostgreSQL database dump
 --
@@ -1079,6 +1228,10 @@ INSERT INTO public.users (id, first_name, last_name, nickname, password, email,
 INSERT INTO public.users (id, first_name, last_name, nickname, password, email, country, created_at, updated_at) VALUES ('f71c96c1-6616-482f-b41e-47f0ed0c099a', 'Rana', 'Wickling', 'rwicklingrr', 'k2gxYMxDpoZ', 'rwicklingrr@cdbaby.com', 'PE', '2022-09-05 16:35:04.222603', '2022-09-05 16:35:04.222603');
 
 
+-- the seed passwords are in plain text, they are hashed with bcrypt here.
+-- a low cost is used to keep the initialization fast, they are rehashed with the configured cost on login.
+UPDATE public.users SET password = public.crypt(password, public.gen_salt('bf', 8));
+
 --
 -- PostgreSQL database dump complete
 --
\ No newline at end of file